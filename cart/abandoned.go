@@ -0,0 +1,170 @@
+package cart
+
+import (
+	"audio_phile/database"
+	"audio_phile/database/dbHelper"
+	"audio_phile/inventory"
+	"audio_phile/jobs"
+	"audio_phile/model"
+	"audio_phile/notification"
+	"bytes"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"os"
+	"text/template"
+	"time"
+)
+
+const (
+	ReminderJob = "cart_reminder"
+	ExpiryJob   = "cart_expiry"
+
+	abandonedBatchSize = 100
+)
+
+// Abandonment configures when idle carts are reminded about and expired. Subject and Body are
+// text/template sources executed with the user's Name and the cart's Items.
+type Abandonment struct {
+	IdleExpiry    time.Duration
+	ReminderAfter time.Duration
+	Subject       string
+	Body          string
+}
+
+var DefaultAbandonment = Abandonment{
+	IdleExpiry:    7 * 24 * time.Hour,
+	ReminderAfter: 24 * time.Hour,
+	Subject:       "You left something in your cart",
+	Body: "Hi {{.Name}},\n\nYour cart is still waiting for you:\n{{range .Items}}- {{.Quantity}} x {{.Name}}\n{{end}}\n" +
+		"Carts are emptied after a few days without activity, so check out soon!",
+}
+
+// AbandonmentFromEnv reads CART_IDLE_EXPIRY and CART_REMINDER_AFTER as durations, e.g. 168h, and the
+// CART_REMINDER_SUBJECT and CART_REMINDER_BODY templates, falling back to DefaultAbandonment
+func AbandonmentFromEnv() Abandonment {
+	config := DefaultAbandonment
+	for name, target := range map[string]*time.Duration{
+		"CART_IDLE_EXPIRY":    &config.IdleExpiry,
+		"CART_REMINDER_AFTER": &config.ReminderAfter,
+	} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(value)
+		if err != nil || duration <= 0 {
+			logrus.Errorf("ignoring invalid %s %q", name, value)
+			continue
+		}
+		*target = duration
+	}
+	if value := os.Getenv("CART_REMINDER_SUBJECT"); value != "" {
+		config.Subject = value
+	}
+	if value := os.Getenv("CART_REMINDER_BODY"); value != "" {
+		config.Body = value
+	}
+	return config
+}
+
+// StartAbandonedCartJobs reminds users about idle carts and expires carts idle for longer than
+// IdleExpiry, releasing the stock they hold. A reminder is only sent when it comes before expiry.
+func StartAbandonedCartJobs(interval time.Duration, config Abandonment) error {
+	subject, err := template.New("subject").Parse(config.Subject)
+	if err != nil {
+		return err
+	}
+	body, err := template.New("body").Parse(config.Body)
+	if err != nil {
+		return err
+	}
+	remind := config.ReminderAfter < config.IdleExpiry
+	if !remind {
+		logrus.Warnf("cart reminders disabled: reminder after %s is not before expiry after %s", config.ReminderAfter, config.IdleExpiry)
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if remind {
+				sendReminders(time.Now().Add(-config.ReminderAfter), subject, body)
+			}
+			expireCarts(time.Now().Add(-config.IdleExpiry))
+		}
+	}()
+	return nil
+}
+
+func sendReminders(idleSince time.Time, subject, body *template.Template) {
+	carts, err := dbHelper.GetCartsToRemind(idleSince, abandonedBatchSize)
+	if err != nil {
+		logrus.Errorf("failed to get carts to remind with error: %+v", err)
+		return
+	}
+	if len(carts) == 0 {
+		return
+	}
+	run := jobs.Start(ReminderJob)
+	for _, abandoned := range carts {
+		if err := sendReminder(abandoned, idleSince, subject, body); err != nil {
+			logrus.Errorf("failed to send reminder for cart %s with error: %+v", abandoned.CartId, err)
+			run.Fail(err)
+			continue
+		}
+		run.Done()
+	}
+	run.Finish(nil)
+}
+
+func sendReminder(abandoned model.AbandonedCart, idleSince time.Time, subject, body *template.Template) error {
+	items, err := dbHelper.GetCartWithProduct(database.Audiophile, abandoned.CartId)
+	if err != nil {
+		return err
+	}
+	data := struct {
+		Name  string
+		Items []model.CartProduct
+	}{Name: abandoned.Name, Items: items}
+	var subjectText, bodyText bytes.Buffer
+	if err := subject.Execute(&subjectText, data); err != nil {
+		return err
+	}
+	if err := body.Execute(&bodyText, data); err != nil {
+		return err
+	}
+	if err := notification.Mail.Send([]string{abandoned.Email}, subjectText.String(), bodyText.String()); err != nil {
+		return err
+	}
+	return dbHelper.MarkCartReminded(abandoned.CartId, idleSince)
+}
+
+func expireCarts(idleSince time.Time) {
+	cartIds, err := dbHelper.GetIdleCartIds(idleSince, abandonedBatchSize)
+	if err != nil {
+		logrus.Errorf("failed to get idle carts with error: %+v", err)
+		return
+	}
+	if len(cartIds) == 0 {
+		return
+	}
+	run := jobs.Start(ExpiryJob)
+	for _, cartId := range cartIds {
+		txErr := database.Tx(func(tx *sqlx.Tx) error {
+			idle, err := dbHelper.LockIdleCart(tx, cartId, idleSince)
+			if err != nil || !idle {
+				return err
+			}
+			if err := inventory.ReleaseCart(tx, cartId); err != nil {
+				return err
+			}
+			return dbHelper.ExpireCart(tx, cartId)
+		})
+		if txErr != nil {
+			logrus.Errorf("failed to expire cart %s with error: %+v", cartId, txErr)
+			run.Fail(txErr)
+			continue
+		}
+		run.Done()
+	}
+	run.Finish(nil)
+}
@@ -0,0 +1,67 @@
+package cart
+
+import (
+	"audio_phile/database/dbHelper"
+	"audio_phile/inventory"
+	"audio_phile/model"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrProductNotFound = errors.New("product not found")
+
+// ActiveCartId returns the user's active cart, creating one when create is set. The user row is
+// locked for the rest of the transaction so concurrent requests cannot create two active carts.
+func ActiveCartId(tx *sqlx.Tx, userId string, create bool) (string, bool, error) {
+	if err := dbHelper.LockUser(tx, userId); err != nil {
+		return "", false, err
+	}
+	cartId, exist, err := dbHelper.GetActiveCartId(tx, userId)
+	if err != nil || exist || !create {
+		return cartId, exist, err
+	}
+	cartId, err = dbHelper.CreateCart(tx, userId, model.CartStatusActive)
+	if err != nil {
+		return "", false, err
+	}
+	return cartId, true, nil
+}
+
+// SetQuantity makes the cart hold exactly quantity units of the product, zero removes the line.
+// Repeating the same call leaves the cart unchanged. Any stock held by an earlier checkout of the
+// cart is released since it no longer matches the cart's contents.
+func SetQuantity(tx *sqlx.Tx, cartId, productId string, quantity int) error {
+	if quantity == 0 {
+		if _, err := dbHelper.DeleteProductFromCart(tx, cartId, productId); err != nil {
+			return err
+		}
+	} else {
+		available, err := dbHelper.GetAvailableQuantity(tx, productId, cartId)
+		if err == sql.ErrNoRows {
+			return ErrProductNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if quantity > available {
+			return &inventory.InsufficientStockError{ProductId: productId, Requested: quantity, Available: available}
+		}
+		if err := dbHelper.UpsertCartProduct(tx, cartId, productId, quantity); err != nil {
+			return err
+		}
+	}
+	if err := inventory.ReleaseCart(tx, cartId); err != nil {
+		return err
+	}
+	return dbHelper.TouchCart(tx, cartId)
+}
+
+// Get returns the cart with its live lines
+func Get(db sqlx.Queryer, cartId string) (model.Cart, error) {
+	items, err := dbHelper.GetCartWithProduct(db, cartId)
+	if err != nil {
+		return model.Cart{}, err
+	}
+	return model.Cart{Id: cartId, Items: items}, nil
+}
@@ -0,0 +1,97 @@
+package cart
+
+import (
+	"audio_phile/database/dbHelper"
+	"audio_phile/inventory"
+	"audio_phile/model"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/jmoiron/sqlx"
+)
+
+// GuestTokenHeader carries the opaque token of an anonymous cart
+const GuestTokenHeader = "X-Cart-Token"
+
+// NewGuestCart creates an anonymous cart and returns the token that identifies it. Only a hash of
+// the token is stored, the token itself is handed to the visitor once.
+func NewGuestCart(db sqlx.Ext) (string, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token := hex.EncodeToString(raw)
+	cartId, err := dbHelper.CreateGuestCart(db, HashGuestToken(token))
+	return cartId, token, err
+}
+
+func HashGuestToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// GuestCartId returns the active guest cart for a token
+func GuestCartId(db sqlx.Queryer, token string) (string, bool, error) {
+	if token == "" {
+		return "", false, nil
+	}
+	return dbHelper.GetGuestCartId(db, HashGuestToken(token))
+}
+
+// Merge folds a guest cart into the user's active cart, creating it if needed. Quantities of the same
+// product are added up and cut to what is available; products that no longer exist are dropped. The
+// guest cart is closed afterwards so its token stops working. Merging a cart twice is a no-op.
+func Merge(tx *sqlx.Tx, userId, guestCartId string) (model.CartMergeResult, error) {
+	result := model.CartMergeResult{Lines: make([]model.CartMergeLine, 0)}
+	open, err := dbHelper.LockGuestCart(tx, guestCartId)
+	if err != nil || !open {
+		return result, err
+	}
+	cartId, _, err := ActiveCartId(tx, userId, true)
+	if err != nil {
+		return result, err
+	}
+	result.CartId = cartId
+
+	existing, err := dbHelper.GetCartLines(tx, cartId)
+	if err != nil {
+		return result, err
+	}
+	current := make(map[string]int, len(existing))
+	for _, line := range existing {
+		current[line.ProductId] = line.Quantity
+	}
+
+	guestLines, err := dbHelper.GetCartLines(tx, guestCartId)
+	if err != nil {
+		return result, err
+	}
+	for _, line := range guestLines {
+		requested := current[line.ProductId] + line.Quantity
+		err := SetQuantity(tx, cartId, line.ProductId, requested)
+		var shortage *inventory.InsufficientStockError
+		switch {
+		case err == nil:
+			continue
+		case errors.Is(err, ErrProductNotFound):
+			result.Lines = append(result.Lines, model.CartMergeLine{ProductId: line.ProductId, Requested: requested, Quantity: current[line.ProductId]})
+			continue
+		case errors.As(err, &shortage):
+		default:
+			return result, err
+		}
+		quantity := shortage.Available
+		if quantity < current[line.ProductId] {
+			// never take away what the user already had in their cart
+			quantity = current[line.ProductId]
+		}
+		if quantity != current[line.ProductId] {
+			if err := SetQuantity(tx, cartId, line.ProductId, quantity); err != nil {
+				return result, err
+			}
+		}
+		result.Lines = append(result.Lines, model.CartMergeLine{ProductId: line.ProductId, Requested: requested, Quantity: quantity})
+	}
+	return result, dbHelper.CloseMergedCart(tx, guestCartId, cartId)
+}
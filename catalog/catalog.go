@@ -0,0 +1,295 @@
+package catalog
+
+import (
+	"audio_phile/database"
+	"audio_phile/database/dbHelper"
+	"audio_phile/inventory"
+	"audio_phile/model"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// csvHeader is the column order used for export and the set of columns understood on import
+var csvHeader = []string{"sku", "name", "price", "description", "is_available", "quantity", "category"}
+
+// errRollback aborts the import transaction after a dry run or when any row failed
+var errRollback = errors.New("catalog import rolled back")
+
+type row struct {
+	line    int
+	product model.ProductImportRow
+}
+
+func ParseFormat(format string) (model.ImportFormat, error) {
+	switch model.ImportFormat(strings.ToLower(strings.TrimSpace(format))) {
+	case "", model.ImportFormatCSV:
+		return model.ImportFormatCSV, nil
+	case model.ImportFormatNDJSON, "json":
+		return model.ImportFormatNDJSON, nil
+	}
+	return "", fmt.Errorf("unsupported format %q, expected csv or ndjson", format)
+}
+
+// Import reads the products from r and upserts them inside a single transaction.
+// Nothing is written when dryRun is set or when any row is invalid; the report lists every failing row.
+// Quantities are applied as stock adjustments by actorId, is_available is derived from them.
+func Import(r io.Reader, format model.ImportFormat, dryRun bool, actorId string) (model.ImportReport, error) {
+	var rows []row
+	var rowErrors []model.ImportRowError
+	var err error
+	switch format {
+	case model.ImportFormatCSV:
+		rows, rowErrors, err = decodeCSV(r)
+	case model.ImportFormatNDJSON:
+		rows, rowErrors, err = decodeNDJSON(r)
+	default:
+		return model.ImportReport{}, fmt.Errorf("unsupported format %q", format)
+	}
+	if err != nil {
+		return model.ImportReport{}, err
+	}
+	report := model.ImportReport{
+		DryRun: dryRun,
+		Total:  len(rows) + len(rowErrors),
+	}
+	rowErrors = append(rowErrors, validateRows(rows)...)
+	failed := make(map[int]bool)
+	for _, rowErr := range rowErrors {
+		failed[rowErr.Row] = true
+	}
+
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		for _, item := range rows {
+			if failed[item.line] {
+				continue
+			}
+			productId, exist, err := dbHelper.GetProductIdForImport(tx, item.product.Sku, item.product.Name)
+			if err != nil {
+				return err
+			}
+			taken, err := dbHelper.IsProductNameTaken(tx, item.product.Name, productId)
+			if err != nil {
+				return err
+			}
+			if taken {
+				rowErrors = append(rowErrors, rowError(item, "name already used by another product"))
+				failed[item.line] = true
+				continue
+			}
+			if exist {
+				if err := dbHelper.UpdateImportedProduct(tx, productId, item.product); err != nil {
+					return err
+				}
+				report.Updated++
+			} else {
+				if productId, err = dbHelper.CreateImportedProduct(tx, item.product); err != nil {
+					return err
+				}
+				report.Created++
+			}
+			if err := inventory.SetStockLevel(tx, productId, item.product.Quantity, actorId, "catalog import"); err != nil {
+				if inventory.IsInsufficientStock(err) {
+					// reported with the other failing rows, the import is rolled back once all rows were checked
+					rowErrors = append(rowErrors, rowError(item, err.Error()))
+					failed[item.line] = true
+					continue
+				}
+				return err
+			}
+		}
+		if dryRun || len(rowErrors) > 0 {
+			return errRollback
+		}
+		return nil
+	})
+	if txErr != nil && txErr != errRollback {
+		return model.ImportReport{}, txErr
+	}
+
+	report.Failed = len(failed)
+	report.Errors = rowErrors
+	if report.Errors == nil {
+		report.Errors = make([]model.ImportRowError, 0)
+	}
+	if !dryRun && report.Failed > 0 {
+		report.Created, report.Updated = 0, 0
+	}
+	return report, nil
+}
+
+// Export writes every live product to w in the given format, one record at a time
+func Export(w io.Writer, format model.ImportFormat) error {
+	switch format {
+	case model.ImportFormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(csvHeader); err != nil {
+			return err
+		}
+		err := dbHelper.StreamAllProduct(func(product model.Products) error {
+			return writer.Write([]string{
+				product.Sku,
+				product.Name,
+				strconv.Itoa(product.Price),
+				product.Description,
+				strconv.FormatBool(product.IsAvailable),
+				strconv.Itoa(product.Quantity),
+				string(product.Category),
+			})
+		})
+		if err != nil {
+			return err
+		}
+		writer.Flush()
+		return writer.Error()
+	case model.ImportFormatNDJSON:
+		encoder := json.NewEncoder(w)
+		return dbHelper.StreamAllProduct(func(product model.Products) error {
+			return encoder.Encode(model.ProductImportRow{
+				Sku:         product.Sku,
+				Name:        product.Name,
+				Price:       product.Price,
+				Description: product.Description,
+				IsAvailable: product.IsAvailable,
+				Quantity:    product.Quantity,
+				Category:    product.Category,
+			})
+		})
+	}
+	return fmt.Errorf("unsupported format %q", format)
+}
+
+func decodeCSV(r io.Reader) ([]row, []model.ImportRowError, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"name", "price", "category"} {
+		if _, ok := columns[name]; !ok {
+			return nil, nil, fmt.Errorf("csv header is missing column %q", name)
+		}
+	}
+
+	var rows []row
+	var rowErrors []model.ImportRowError
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, model.ImportRowError{Row: line, Error: err.Error()})
+			continue
+		}
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		product := model.ProductImportRow{
+			Sku:         field("sku"),
+			Name:        field("name"),
+			Description: field("description"),
+			Category:    model.Category(strings.ToLower(field("category"))),
+		}
+		var parseErr error
+		if product.Price, parseErr = parseInt(field("price")); parseErr != nil {
+			rowErrors = append(rowErrors, rowError(row{line, product}, "price: "+parseErr.Error()))
+			continue
+		}
+		if product.Quantity, parseErr = parseInt(field("quantity")); parseErr != nil {
+			rowErrors = append(rowErrors, rowError(row{line, product}, "quantity: "+parseErr.Error()))
+			continue
+		}
+		if value := field("is_available"); value != "" {
+			if product.IsAvailable, parseErr = strconv.ParseBool(value); parseErr != nil {
+				rowErrors = append(rowErrors, rowError(row{line, product}, "is_available: "+parseErr.Error()))
+				continue
+			}
+		}
+		rows = append(rows, row{line: line, product: product})
+	}
+	return rows, rowErrors, nil
+}
+
+func decodeNDJSON(r io.Reader) ([]row, []model.ImportRowError, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var rows []row
+	var rowErrors []model.ImportRowError
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var product model.ProductImportRow
+		if err := json.Unmarshal([]byte(text), &product); err != nil {
+			rowErrors = append(rowErrors, model.ImportRowError{Row: line, Error: err.Error()})
+			continue
+		}
+		product.Category = model.Category(strings.ToLower(string(product.Category)))
+		rows = append(rows, row{line: line, product: product})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read ndjson: %w", err)
+	}
+	return rows, rowErrors, nil
+}
+
+// validateRows checks each row against the struct rules and rejects duplicate sku or name inside the same file
+func validateRows(rows []row) []model.ImportRowError {
+	validate := validator.New()
+	seenSku := make(map[string]int)
+	seenName := make(map[string]int)
+	var rowErrors []model.ImportRowError
+	for _, r := range rows {
+		if err := validate.Struct(r.product); err != nil {
+			rowErrors = append(rowErrors, rowError(r, err.Error()))
+			continue
+		}
+		if r.product.Sku != "" {
+			if first, ok := seenSku[r.product.Sku]; ok {
+				rowErrors = append(rowErrors, rowError(r, fmt.Sprintf("duplicate sku, first seen on row %d", first)))
+				continue
+			}
+			seenSku[r.product.Sku] = r.line
+		}
+		if first, ok := seenName[r.product.Name]; ok {
+			rowErrors = append(rowErrors, rowError(r, fmt.Sprintf("duplicate name, first seen on row %d", first)))
+			continue
+		}
+		seenName[r.product.Name] = r.line
+	}
+	return rowErrors
+}
+
+func rowError(r row, message string) model.ImportRowError {
+	return model.ImportRowError{
+		Row:   r.line,
+		Sku:   r.product.Sku,
+		Name:  r.product.Name,
+		Error: message,
+	}
+}
+
+func parseInt(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"audio_phile/catalog"
+	"audio_phile/database"
+	"encoding/json"
+	"flag"
+	"github.com/sirupsen/logrus"
+	"os"
+)
+
+// catalog imports or exports the product catalog from the command line, e.g.
+//
+//	go run ./cmd/catalog -import products.csv -dry-run
+//	go run ./cmd/catalog -export products.ndjson -format ndjson
+func main() {
+	importFile := flag.String("import", "", "path of the csv/ndjson file to import")
+	exportFile := flag.String("export", "", "path of the file to export the catalog to")
+	formatFlag := flag.String("format", "csv", "file format: csv or ndjson")
+	dryRun := flag.Bool("dry-run", false, "validate the import without writing anything")
+	flag.Parse()
+
+	if (*importFile == "") == (*exportFile == "") {
+		logrus.Fatal("exactly one of -import or -export is required")
+	}
+
+	format, err := catalog.ParseFormat(*formatFlag)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	if err := database.ConnectAndMigrate(
+		"localhost",
+		"5434",
+		"postgres",
+		"local",
+		"local",
+		database.SSLModeDisable); err != nil {
+		logrus.Panicf("Failed to initialize and migrate database with error: %+v", err)
+	}
+
+	if *exportFile != "" {
+		file, err := os.Create(*exportFile)
+		if err != nil {
+			logrus.Fatalf("Failed to create export file with error: %+v", err)
+		}
+		defer file.Close()
+		if err := catalog.Export(file, format); err != nil {
+			logrus.Fatalf("Failed to export products with error: %+v", err)
+		}
+		logrus.Infof("catalog exported to %s", *exportFile)
+		return
+	}
+
+	file, err := os.Open(*importFile)
+	if err != nil {
+		logrus.Fatalf("Failed to open import file with error: %+v", err)
+	}
+	defer file.Close()
+
+	report, err := catalog.Import(file, format, *dryRun, "")
+	if err != nil {
+		logrus.Fatalf("Failed to import products with error: %+v", err)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		logrus.Errorf("failed to print import report with error: %+v", err)
+	}
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
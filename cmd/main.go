@@ -1,12 +1,26 @@
 package main
 
 import (
+	"audio_phile/cart"
+	"audio_phile/credit"
 	"audio_phile/database"
+	"audio_phile/inventory"
+	"audio_phile/invoice"
+	"audio_phile/notification"
+	"audio_phile/payment"
 	"audio_phile/server"
+	"audio_phile/shipping"
+	"audio_phile/utils"
 	"github.com/sirupsen/logrus"
+	"os"
+	"time"
 )
 
 func main() {
+	// settings like the SMTP server and alert recipients come from app.env unless set in the environment
+	if err := utils.LoadEnvFile("app.env"); err != nil {
+		logrus.Panicf("Failed to load app.env with error: %+v", err)
+	}
 	srv := server.SetupRoutes()
 	if err := database.ConnectAndMigrate(
 		"localhost",
@@ -19,6 +33,33 @@ func main() {
 	}
 	logrus.Info("migration successfully!!")
 
+	notification.Mail = notification.MailerFromEnv()
+	inventory.AlertNotifier = notification.NotifierFromEnv("STOCK_ALERT", notification.Mail)
+	gateway, err := payment.FromEnv()
+	if err != nil {
+		logrus.Panicf("Failed to set up payments with error: %+v", err)
+	}
+	payment.Gateway = gateway
+	if os.Getenv("PAYMENT_WEBHOOK_SECRET") == "" {
+		logrus.Warn("PAYMENT_WEBHOOK_SECRET is not set, payment webhooks will be rejected")
+	}
+	payment.StoreCredit = credit.Wallet{}
+	if err := invoice.FromEnv(); err != nil {
+		logrus.Panicf("Failed to set up invoices with error: %+v", err)
+	}
+	invoice.Currency = payment.Currency
+	if err := shipping.FromEnv(); err != nil {
+		logrus.Panicf("Failed to set up shipping with error: %+v", err)
+	}
+
+	inventory.StartReleaser(time.Minute)
+	inventory.StartAlertDispatcher(30 * time.Second)
+	inventory.StartRestockNotifier(30 * time.Second)
+	payment.StartRefundReconciler(time.Minute, 5*time.Minute)
+	if err := cart.StartAbandonedCartJobs(10*time.Minute, cart.AbandonmentFromEnv()); err != nil {
+		logrus.Panicf("Failed to start abandoned cart jobs with error: %+v", err)
+	}
+
 	if err := srv.Run(":8000"); err != nil {
 		logrus.Fatalf("Failed to run server with error %+v", err)
 	}
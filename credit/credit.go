@@ -0,0 +1,79 @@
+package credit
+
+import (
+	"audio_phile/database"
+	"audio_phile/database/dbHelper"
+	"audio_phile/model"
+	"audio_phile/payment"
+	"errors"
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrNegativeBalance = errors.New("store credit balance cannot go below zero")
+
+// Wallet keeps store credit in the ledger and is what store credit payments are drawn from
+type Wallet struct{}
+
+// Debit takes amount off the user's store credit for a payment
+func (Wallet) Debit(tx *sqlx.Tx, userId string, amount model.Money, reference string) error {
+	if _, err := add(tx, userId, -amount, model.CreditPayment, reference, "", ""); err != nil {
+		if errors.Is(err, ErrNegativeBalance) {
+			return payment.ErrInsufficientCredit
+		}
+		return err
+	}
+	return nil
+}
+
+// Credit gives the amount of a refund back to the user's store credit
+func (Wallet) Credit(tx *sqlx.Tx, userId string, amount model.Money, reference string) error {
+	_, err := add(tx, userId, amount, model.CreditRefund, reference, "", "")
+	return err
+}
+
+// Redeem moves a gift card into the user's store credit for a payment
+func (Wallet) Redeem(tx *sqlx.Tx, userId, code string) (model.Money, error) {
+	return RedeemGiftCard(tx, userId, code)
+}
+
+// Statement returns a user's store credit balance with the ledger entries behind it
+func Statement(db sqlx.Queryer, userId string) (model.CreditStatement, error) {
+	balance, err := dbHelper.GetCreditBalance(db, userId)
+	if err != nil {
+		return model.CreditStatement{}, err
+	}
+	entries, err := dbHelper.GetCreditEntries(db, userId)
+	if err != nil {
+		return model.CreditStatement{}, err
+	}
+	return model.CreditStatement{Balance: balance, Entries: entries}, nil
+}
+
+// Adjust records store credit an admin gives, e.g. as goodwill after a return, or takes back
+func Adjust(userId string, req model.CreditEntryRequest, actorId string) (model.CreditEntry, error) {
+	var entry model.CreditEntry
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		var err error
+		entry, err = add(tx, userId, req.Amount, req.Kind, req.Reference, req.Note, actorId)
+		return err
+	})
+	return entry, txErr
+}
+
+// add appends an entry to the user's ledger. The user is locked first so concurrent entries cannot together
+// take the balance below zero.
+func add(tx *sqlx.Tx, userId string, amount model.Money, kind model.CreditEntryKind, reference, note, actorId string) (model.CreditEntry, error) {
+	if err := dbHelper.LockUser(tx, userId); err != nil {
+		return model.CreditEntry{}, err
+	}
+	if amount < 0 {
+		balance, err := dbHelper.GetCreditBalance(tx, userId)
+		if err != nil {
+			return model.CreditEntry{}, err
+		}
+		if balance+amount < 0 {
+			return model.CreditEntry{}, ErrNegativeBalance
+		}
+	}
+	return dbHelper.CreateCreditEntry(tx, userId, amount, kind, reference, note, actorId)
+}
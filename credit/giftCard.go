@@ -0,0 +1,151 @@
+package credit
+
+import (
+	"audio_phile/database"
+	"audio_phile/database/dbHelper"
+	"audio_phile/model"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// codeAlphabet leaves out characters that are easily mistaken for each other, like 0 and O or 1 and I
+const codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+var (
+	ErrGiftCardNotFound = errors.New("gift card not found")
+	ErrGiftCardExpired  = errors.New("gift card has expired")
+	ErrGiftCardDisabled = errors.New("gift card was disabled")
+	ErrGiftCardEmpty    = errors.New("gift card has no balance left")
+)
+
+// IssueGiftCard creates a gift card worth req.Amount and returns it with its code. Only a hash of the code
+// is stored, so the code is only ever shown here.
+func IssueGiftCard(req model.GiftCardRequest, actorId string) (model.GiftCard, string, error) {
+	code, err := newCode()
+	if err != nil {
+		return model.GiftCard{}, "", err
+	}
+	var card model.GiftCard
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		var err error
+		card, err = dbHelper.CreateGiftCard(tx, hashCode(code), code[len(code)-4:], req.Amount, req.ExpiresAt, req.Note, actorId)
+		if err != nil {
+			return err
+		}
+		return dbHelper.CreateGiftCardTransaction(tx, card.Id, req.Amount, model.GiftCardIssue, "", actorId, req.Note)
+	})
+	return card, code, txErr
+}
+
+// GiftCardDetail returns a gift card with its transactions
+func GiftCardDetail(db sqlx.Queryer, giftCardId string) (model.GiftCardDetail, error) {
+	card, err := dbHelper.GetGiftCard(db, giftCardId)
+	if err != nil {
+		return model.GiftCardDetail{}, err
+	}
+	transactions, err := dbHelper.GetGiftCardTransactions(db, card.Id)
+	if err != nil {
+		return model.GiftCardDetail{}, err
+	}
+	return model.GiftCardDetail{GiftCard: card, Transactions: transactions}, nil
+}
+
+// AdjustGiftCard changes the balance of a gift card by req.Amount, which may be negative but may not take
+// the balance below zero
+func AdjustGiftCard(giftCardId string, req model.GiftCardAdjustmentRequest, actorId string) (model.GiftCard, error) {
+	var card model.GiftCard
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		var err error
+		card, err = dbHelper.LockGiftCard(tx, giftCardId)
+		if err != nil {
+			return err
+		}
+		if card.Balance+req.Amount < 0 {
+			return ErrNegativeBalance
+		}
+		if err := dbHelper.AddGiftCardBalance(tx, card.Id, req.Amount); err != nil {
+			return err
+		}
+		card.Balance += req.Amount
+		return dbHelper.CreateGiftCardTransaction(tx, card.Id, req.Amount, model.GiftCardAdjustment, "", actorId, req.Note)
+	})
+	return card, txErr
+}
+
+// DisableGiftCard stops a gift card from being redeemed, e.g. after it was reported stolen
+func DisableGiftCard(giftCardId, actorId string) error {
+	return database.Tx(func(tx *sqlx.Tx) error {
+		card, err := dbHelper.LockGiftCard(tx, giftCardId)
+		if err != nil {
+			return err
+		}
+		if card.DisabledAt != nil {
+			return nil
+		}
+		if err := dbHelper.DisableGiftCard(tx, card.Id); err != nil {
+			return err
+		}
+		return dbHelper.CreateGiftCardTransaction(tx, card.Id, 0, model.GiftCardDisable, "", actorId, "")
+	})
+}
+
+// RedeemGiftCard moves the whole balance of a gift card into the user's store credit and returns the
+// amount moved. Store credit does not expire, so a card is best redeemed before it does.
+func RedeemGiftCard(tx *sqlx.Tx, userId, code string) (model.Money, error) {
+	card, err := dbHelper.LockGiftCardByCode(tx, hashCode(code))
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrGiftCardNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case card.DisabledAt != nil:
+		return 0, ErrGiftCardDisabled
+	case card.ExpiresAt != nil && card.ExpiresAt.Before(time.Now()):
+		return 0, ErrGiftCardExpired
+	case card.Balance == 0:
+		return 0, ErrGiftCardEmpty
+	}
+	if err := dbHelper.AddGiftCardBalance(tx, card.Id, -card.Balance); err != nil {
+		return 0, err
+	}
+	if err := dbHelper.CreateGiftCardTransaction(tx, card.Id, -card.Balance, model.GiftCardRedeem, userId, userId, ""); err != nil {
+		return 0, err
+	}
+	if _, err := add(tx, userId, card.Balance, model.CreditGiftCard, card.Id, "gift card ending in "+card.LastFour, ""); err != nil {
+		return 0, err
+	}
+	return card.Balance, nil
+}
+
+// newCode returns a random code of four groups of four characters, like ABCD-EF23-GH45-JK67
+func newCode() (string, error) {
+	var code strings.Builder
+	max := big.NewInt(int64(len(codeAlphabet)))
+	for i := 0; i < 16; i++ {
+		if i > 0 && i%4 == 0 {
+			code.WriteByte('-')
+		}
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code.WriteByte(codeAlphabet[n.Int64()])
+	}
+	return code.String(), nil
+}
+
+// hashCode hashes a code the way it was typed, ignoring case, spaces and dashes
+func hashCode(code string) string {
+	normalized := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,58 @@
+package dbHelper
+
+import (
+	"audio_phile/database"
+	"audio_phile/model"
+	"database/sql"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+// GetCartsToRemind returns user carts with items that have been idle since before idleSince and were not
+// reminded about since their last activity
+func GetCartsToRemind(idleSince time.Time, limit int) ([]model.AbandonedCart, error) {
+	SQL := `SELECT c.id, u.name, u.email
+			FROM carts c INNER JOIN users u ON c.user_id = u.id
+			WHERE c.status = 'active'
+			  AND c.update_at < $1
+			  AND c.reminded_at IS NULL
+			  AND u.archived_at IS NULL
+			  AND EXISTS(SELECT 1 FROM cart_products cp WHERE cp.cart_id = c.id AND cp.archived_at IS NULL)
+			ORDER BY c.update_at
+			LIMIT $2`
+	list := make([]model.AbandonedCart, 0)
+	err := database.Audiophile.Select(&list, SQL, idleSince, limit)
+	return list, err
+}
+
+// MarkCartReminded records the reminder unless the cart saw activity after it was picked up
+func MarkCartReminded(cartId string, idleSince time.Time) error {
+	SQL := `UPDATE carts SET reminded_at = Now() WHERE id = $1 AND update_at < $2`
+	_, err := database.Audiophile.Exec(SQL, cartId, idleSince)
+	return err
+}
+
+// GetIdleCartIds returns active carts, of users and guests, that have been idle since before idleSince
+func GetIdleCartIds(idleSince time.Time, limit int) ([]string, error) {
+	SQL := `SELECT id FROM carts WHERE status = 'active' AND update_at < $1 ORDER BY update_at LIMIT $2`
+	list := make([]string, 0)
+	err := database.Audiophile.Select(&list, SQL, idleSince, limit)
+	return list, err
+}
+
+// LockIdleCart locks a cart that is still active and idle, it returns false when the cart moved on meanwhile
+func LockIdleCart(tx *sqlx.Tx, cartId string, idleSince time.Time) (bool, error) {
+	SQL := `SELECT id FROM carts WHERE id = $1 AND status = 'active' AND update_at < $2 FOR UPDATE`
+	var id string
+	err := tx.Get(&id, SQL, cartId, idleSince)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func ExpireCart(tx *sqlx.Tx, cartId string) error {
+	SQL := `UPDATE carts SET status = $2, expired_at = Now(), update_at = Now() WHERE id = $1`
+	_, err := tx.Exec(SQL, cartId, model.CartStatusInActive)
+	return err
+}
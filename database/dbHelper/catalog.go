@@ -0,0 +1,85 @@
+package dbHelper
+
+import (
+	"audio_phile/database"
+	"audio_phile/model"
+	"database/sql"
+	"github.com/jmoiron/sqlx"
+)
+
+// GetProductIdForImport finds the product a row should update. A sku match wins; otherwise the name is matched
+// against products that do not have a sku yet so that existing catalog entries can be given one
+func GetProductIdForImport(db sqlx.Ext, sku, name string) (string, bool, error) {
+	SQL := `SELECT id
+			FROM products
+			WHERE archived_at IS NULL
+			  AND (($1 <> '' AND sku = $1) OR (name = $2 AND ($1 = '' OR sku IS NULL)))
+			ORDER BY sku = $1 DESC NULLS LAST
+			LIMIT 1`
+	var id string
+	err := sqlx.Get(db, &id, SQL, sku, name)
+	if err != nil && err != sql.ErrNoRows {
+		return "", false, err
+	}
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	return id, true, nil
+}
+
+// IsProductNameTaken reports whether another live product already uses the name
+func IsProductNameTaken(db sqlx.Ext, name, exceptProductId string) (bool, error) {
+	SQL := `SELECT count(*) > 0 FROM products WHERE name = $1 AND archived_at IS NULL AND id::text <> $2`
+	var taken bool
+	err := sqlx.Get(db, &taken, SQL, name, exceptProductId)
+	return taken, err
+}
+
+// CreateImportedProduct inserts the product without stock; quantities are set through the stock ledger
+func CreateImportedProduct(db sqlx.Ext, row model.ProductImportRow) (string, error) {
+	SQL := `INSERT INTO products(sku, name, price, description, is_available, quantity, category) VALUES (NULLIF($1, ''), $2, $3, $4, FALSE, 0, $5) RETURNING id`
+	var productId string
+	err := db.QueryRowx(SQL, row.Sku, row.Name, row.Price, row.Description, row.Category).Scan(&productId)
+	return productId, err
+}
+
+func UpdateImportedProduct(db sqlx.Ext, productId string, row model.ProductImportRow) error {
+	SQL := `UPDATE products
+			SET sku = COALESCE(NULLIF($2, ''), sku),
+			    name = $3,
+			    price = $4,
+			    description = $5,
+			    category = $6,
+			    update_at = Now()
+			WHERE id = $1`
+	_, err := db.Exec(SQL, productId, row.Sku, row.Name, row.Price, row.Description, row.Category)
+	return err
+}
+
+// StreamAllProduct calls fn for every live product without loading the whole catalog in memory
+func StreamAllProduct(fn func(product model.Products) error) error {
+	SQL := `SELECT id,
+       			   COALESCE(sku, '') AS sku,
+       			   name,
+       			   price,
+       			   COALESCE(description, '') AS description,
+       			   is_available,
+       			   quantity,
+       			   category
+			FROM products WHERE archived_at IS NULL ORDER BY name`
+	rows, err := database.Audiophile.Queryx(SQL)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var product model.Products
+		if err := rows.StructScan(&product); err != nil {
+			return err
+		}
+		if err := fn(product); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,105 @@
+package dbHelper
+
+import (
+	"audio_phile/model"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+const giftCardColumns = `id, last_four, initial_balance, balance, expires_at, note, issued_by, disabled_at, created_at`
+
+func GetCreditBalance(db sqlx.Queryer, userId string) (model.Money, error) {
+	SQL := `SELECT COALESCE(SUM(amount), 0) FROM store_credit_entries WHERE user_id = $1`
+	var balance model.Money
+	err := sqlx.Get(db, &balance, SQL, userId)
+	return balance, err
+}
+
+func CreateCreditEntry(db sqlx.Queryer, userId string, amount model.Money, kind model.CreditEntryKind, reference, note, actorId string) (model.CreditEntry, error) {
+	SQL := `INSERT INTO store_credit_entries(user_id, amount, kind, reference, note, actor_id)
+			VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, '')::uuid)
+			RETURNING id, user_id, amount, kind, reference, note, actor_id, created_at`
+	var entry model.CreditEntry
+	err := sqlx.Get(db, &entry, SQL, userId, amount, kind, reference, note, actorId)
+	return entry, err
+}
+
+// GetCreditEntries returns a user's store credit ledger newest first
+func GetCreditEntries(db sqlx.Queryer, userId string) ([]model.CreditEntry, error) {
+	SQL := `SELECT id, user_id, amount, kind, reference, note, actor_id, created_at
+			FROM store_credit_entries
+			WHERE user_id::text = $1
+			ORDER BY created_at DESC, id`
+	list := make([]model.CreditEntry, 0)
+	err := sqlx.Select(db, &list, SQL, userId)
+	return list, err
+}
+
+func CreateGiftCard(db sqlx.Queryer, codeHash, lastFour string, amount model.Money, expiresAt *time.Time, note, actorId string) (model.GiftCard, error) {
+	SQL := `INSERT INTO gift_cards(code_hash, last_four, initial_balance, balance, expires_at, note, issued_by)
+			VALUES ($1, $2, $3, $3, $4, NULLIF($5, ''), $6)
+			RETURNING ` + giftCardColumns
+	var card model.GiftCard
+	err := sqlx.Get(db, &card, SQL, codeHash, lastFour, amount, expiresAt, note, actorId)
+	return card, err
+}
+
+func GetGiftCards(db sqlx.Queryer) ([]model.GiftCard, error) {
+	SQL := `SELECT ` + giftCardColumns + ` FROM gift_cards ORDER BY created_at DESC`
+	list := make([]model.GiftCard, 0)
+	err := sqlx.Select(db, &list, SQL)
+	return list, err
+}
+
+func GetGiftCard(db sqlx.Queryer, giftCardId string) (model.GiftCard, error) {
+	SQL := `SELECT ` + giftCardColumns + ` FROM gift_cards WHERE id::text = $1`
+	var card model.GiftCard
+	err := sqlx.Get(db, &card, SQL, giftCardId)
+	return card, err
+}
+
+// LockGiftCard reads a gift card by id and locks it until the transaction ends
+func LockGiftCard(tx *sqlx.Tx, giftCardId string) (model.GiftCard, error) {
+	SQL := `SELECT ` + giftCardColumns + ` FROM gift_cards WHERE id::text = $1 FOR UPDATE`
+	var card model.GiftCard
+	err := tx.Get(&card, SQL, giftCardId)
+	return card, err
+}
+
+// LockGiftCardByCode reads a gift card by the hash of its code and locks it until the transaction ends
+func LockGiftCardByCode(tx *sqlx.Tx, codeHash string) (model.GiftCard, error) {
+	SQL := `SELECT ` + giftCardColumns + ` FROM gift_cards WHERE code_hash = $1 FOR UPDATE`
+	var card model.GiftCard
+	err := tx.Get(&card, SQL, codeHash)
+	return card, err
+}
+
+// AddGiftCardBalance changes the balance of a gift card by amount, which may be negative
+func AddGiftCardBalance(db sqlx.Ext, giftCardId string, amount model.Money) error {
+	SQL := `UPDATE gift_cards SET balance = balance + $2 WHERE id = $1`
+	_, err := db.Exec(SQL, giftCardId, amount)
+	return err
+}
+
+func DisableGiftCard(db sqlx.Ext, giftCardId string) error {
+	SQL := `UPDATE gift_cards SET disabled_at = Now() WHERE id = $1`
+	_, err := db.Exec(SQL, giftCardId)
+	return err
+}
+
+func CreateGiftCardTransaction(db sqlx.Ext, giftCardId string, amount model.Money, kind model.GiftCardTransactionKind, userId, actorId, note string) error {
+	SQL := `INSERT INTO gift_card_transactions(gift_card_id, amount, kind, user_id, actor_id, note)
+			VALUES ($1, $2, $3, NULLIF($4, '')::uuid, NULLIF($5, '')::uuid, NULLIF($6, ''))`
+	_, err := db.Exec(SQL, giftCardId, amount, kind, userId, actorId, note)
+	return err
+}
+
+func GetGiftCardTransactions(db sqlx.Queryer, giftCardId string) ([]model.GiftCardTransaction, error) {
+	SQL := `SELECT id, amount, kind, user_id, actor_id, note, created_at
+			FROM gift_card_transactions
+			WHERE gift_card_id = $1
+			ORDER BY created_at, id`
+	list := make([]model.GiftCardTransaction, 0)
+	err := sqlx.Select(db, &list, SQL, giftCardId)
+	return list, err
+}
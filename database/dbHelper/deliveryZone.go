@@ -0,0 +1,94 @@
+package dbHelper
+
+import (
+	"audio_phile/database"
+	"audio_phile/model"
+	"github.com/jmoiron/sqlx"
+)
+
+func CreateDeliveryZone(tx *sqlx.Tx, name string) (string, error) {
+	SQL := `INSERT INTO delivery_zones(name) VALUES (TRIM($1)) RETURNING id`
+	var zoneId string
+	err := tx.Get(&zoneId, SQL, name)
+	return zoneId, err
+}
+
+func CreateDeliveryZonePoint(tx *sqlx.Tx, zoneId string, position int, point model.GeoPoint) error {
+	SQL := `INSERT INTO delivery_zone_points(zone_id, position, lat, long) VALUES ($1, $2, $3, $4)`
+	_, err := tx.Exec(SQL, zoneId, position, point.Lat, point.Long)
+	return err
+}
+
+// CreateDeliveryZonePostalCode adds a postal code to a zone, listing it twice is not an error
+func CreateDeliveryZonePostalCode(tx *sqlx.Tx, zoneId, postalCode string) error {
+	SQL := `INSERT INTO delivery_zone_postal_codes(zone_id, postal_code) VALUES ($1, UPPER(REPLACE($2, ' ', '')))
+			ON CONFLICT DO NOTHING`
+	_, err := tx.Exec(SQL, zoneId, postalCode)
+	return err
+}
+
+func GetDeliveryZones(db sqlx.Queryer) ([]model.DeliveryZone, error) {
+	SQL := `SELECT id, name, created_at FROM delivery_zones WHERE archived_at IS NULL ORDER BY name, created_at`
+	list := make([]model.DeliveryZone, 0)
+	err := sqlx.Select(db, &list, SQL)
+	return list, err
+}
+
+func GetDeliveryZone(db sqlx.Queryer, zoneId string) (model.DeliveryZone, error) {
+	SQL := `SELECT id, name, created_at FROM delivery_zones WHERE id::text = $1 AND archived_at IS NULL`
+	var zone model.DeliveryZone
+	err := sqlx.Get(db, &zone, SQL, zoneId)
+	return zone, err
+}
+
+// GetDeliveryZonePoints returns the polygon corners of the live zones, or of one zone when zoneId is set,
+// in drawing order
+func GetDeliveryZonePoints(db sqlx.Queryer, zoneId string) ([]model.DeliveryZonePoint, error) {
+	SQL := `SELECT p.zone_id, p.lat, p.long
+			FROM delivery_zone_points p INNER JOIN delivery_zones z ON p.zone_id = z.id
+			WHERE z.archived_at IS NULL AND ($1 = '' OR z.id::text = $1)
+			ORDER BY p.zone_id, p.position`
+	list := make([]model.DeliveryZonePoint, 0)
+	err := sqlx.Select(db, &list, SQL, zoneId)
+	return list, err
+}
+
+// GetDeliveryZonePostalCodes returns the postal codes of the live zones, or of one zone when zoneId is set
+func GetDeliveryZonePostalCodes(db sqlx.Queryer, zoneId string) ([]model.DeliveryZonePostalCode, error) {
+	SQL := `SELECT c.zone_id, c.postal_code
+			FROM delivery_zone_postal_codes c INNER JOIN delivery_zones z ON c.zone_id = z.id
+			WHERE z.archived_at IS NULL AND ($1 = '' OR z.id::text = $1)
+			ORDER BY c.zone_id, c.postal_code`
+	list := make([]model.DeliveryZonePostalCode, 0)
+	err := sqlx.Select(db, &list, SQL, zoneId)
+	return list, err
+}
+
+// FindPostalCodeZone returns the live zone listing a postal code, sql.ErrNoRows when none does
+func FindPostalCodeZone(db sqlx.Queryer, postalCode string) (model.DeliveryZone, error) {
+	SQL := `SELECT z.id, z.name, z.created_at
+			FROM delivery_zone_postal_codes c INNER JOIN delivery_zones z ON c.zone_id = z.id
+			WHERE c.postal_code = UPPER(REPLACE($1, ' ', '')) AND z.archived_at IS NULL
+			ORDER BY z.created_at
+			LIMIT 1`
+	var zone model.DeliveryZone
+	err := sqlx.Get(db, &zone, SQL, postalCode)
+	return zone, err
+}
+
+func HasDeliveryZones(db sqlx.Queryer) (bool, error) {
+	SQL := `SELECT count(*) > 0 FROM delivery_zones WHERE archived_at IS NULL`
+	var exist bool
+	err := sqlx.Get(db, &exist, SQL)
+	return exist, err
+}
+
+func ArchiveDeliveryZone(zoneId string) (bool, error) {
+	SQL := `UPDATE delivery_zones SET archived_at = Now() WHERE id::text = $1 AND archived_at IS NULL`
+	result, err := database.Audiophile.Exec(SQL, zoneId)
+	if err != nil {
+		return false, err
+	}
+	count, err := result.RowsAffected()
+	return count > 0, err
+}
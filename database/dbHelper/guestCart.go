@@ -0,0 +1,47 @@
+package dbHelper
+
+import (
+	"audio_phile/model"
+	"database/sql"
+	"github.com/jmoiron/sqlx"
+)
+
+func CreateGuestCart(db sqlx.Ext, tokenHash string) (string, error) {
+	SQL := `INSERT INTO carts(guest_token_hash, status) VALUES ($1, $2) RETURNING id`
+	var cartId string
+	err := db.QueryRowx(SQL, tokenHash, model.CartStatusActive).Scan(&cartId)
+	return cartId, err
+}
+
+// GetGuestCartId returns the active guest cart behind a token hash
+func GetGuestCartId(db sqlx.Queryer, tokenHash string) (string, bool, error) {
+	SQL := `SELECT id FROM carts WHERE guest_token_hash = $1 AND user_id IS NULL AND status = 'active'`
+	var cartId string
+	err := sqlx.Get(db, &cartId, SQL, tokenHash)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	return cartId, err == nil, err
+}
+
+// LockGuestCart locks an active guest cart so that it is merged at most once
+func LockGuestCart(tx *sqlx.Tx, cartId string) (bool, error) {
+	SQL := `SELECT id FROM carts WHERE id = $1 AND user_id IS NULL AND status = 'active' FOR UPDATE`
+	var id string
+	err := tx.Get(&id, SQL, cartId)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// CloseMergedCart deactivates a guest cart whose lines were merged into mergedInto and archives its lines
+func CloseMergedCart(tx *sqlx.Tx, cartId, mergedInto string) error {
+	SQL := `UPDATE carts SET status = $3, merged_into = $2, update_at = Now() WHERE id = $1`
+	if _, err := tx.Exec(SQL, cartId, mergedInto, model.CartStatusInActive); err != nil {
+		return err
+	}
+	SQL = `UPDATE cart_products SET archived_at = Now(), updated_at = Now() WHERE cart_id = $1 AND archived_at IS NULL`
+	_, err := tx.Exec(SQL, cartId)
+	return err
+}
@@ -0,0 +1,93 @@
+package dbHelper
+
+import (
+	"audio_phile/model"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+// GetCartLines returns the live lines of a cart merged per product, ordered by product id so that
+// rows are always locked in the same order
+func GetCartLines(db sqlx.Queryer, cartId string) ([]model.CartLine, error) {
+	SQL := `SELECT product_id, SUM(quantity) AS quantity
+			FROM cart_products
+			WHERE cart_id = $1 AND archived_at IS NULL AND quantity > 0
+			GROUP BY product_id
+			ORDER BY product_id`
+	list := make([]model.CartLine, 0)
+	err := sqlx.Select(db, &list, SQL, cartId)
+	return list, err
+}
+
+// LockProductQuantity reads the on-hand quantity of a product and locks its row until the transaction ends
+func LockProductQuantity(tx *sqlx.Tx, productId string) (int, error) {
+	SQL := `SELECT quantity FROM products WHERE id = $1 AND archived_at IS NULL FOR UPDATE`
+	var quantity int
+	err := tx.Get(&quantity, SQL, productId)
+	return quantity, err
+}
+
+// GetReservedQuantity sums the unexpired reservations held on a product by carts other than excludeCartId
+func GetReservedQuantity(db sqlx.Queryer, productId, excludeCartId string) (int, error) {
+	SQL := `SELECT COALESCE(SUM(quantity), 0)
+			FROM stock_reservations
+			WHERE product_id = $1
+			  AND status = 'active'
+			  AND expires_at > Now()
+			  AND cart_id::text <> $2`
+	var reserved int
+	err := sqlx.Get(db, &reserved, SQL, productId, excludeCartId)
+	return reserved, err
+}
+
+func CreateReservation(db sqlx.Ext, cartId, productId string, quantity int, expiresAt time.Time) (model.StockReservation, error) {
+	SQL := `INSERT INTO stock_reservations(cart_id, product_id, quantity, expires_at)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, cart_id, product_id, quantity, status, expires_at`
+	var reservation model.StockReservation
+	err := sqlx.Get(db, &reservation, SQL, cartId, productId, quantity, expiresAt)
+	return reservation, err
+}
+
+func GetActiveReservations(db sqlx.Queryer, cartId string) ([]model.StockReservation, error) {
+	SQL := `SELECT id, cart_id, product_id, quantity, status, expires_at
+			FROM stock_reservations
+			WHERE cart_id = $1 AND status = 'active' AND expires_at > Now()
+			ORDER BY product_id`
+	list := make([]model.StockReservation, 0)
+	err := sqlx.Select(db, &list, SQL, cartId)
+	return list, err
+}
+
+func UpdateCartReservationStatus(db sqlx.Ext, cartId string, status model.ReservationStatus) error {
+	SQL := `UPDATE stock_reservations SET status = $2, updated_at = Now() WHERE cart_id = $1 AND status = 'active'`
+	_, err := db.Exec(SQL, cartId, status)
+	return err
+}
+
+// ReleaseExpiredReservations frees every active reservation whose hold has run out and returns the affected products
+func ReleaseExpiredReservations(db sqlx.Ext) ([]string, error) {
+	SQL := `WITH released AS (
+				UPDATE stock_reservations SET status = 'released', updated_at = Now()
+				WHERE status = 'active' AND expires_at <= Now()
+				RETURNING product_id)
+			SELECT DISTINCT product_id FROM released`
+	productIds := make([]string, 0)
+	err := sqlx.Select(db, &productIds, SQL)
+	return productIds, err
+}
+
+// GetAvailableQuantity is the on-hand quantity of a product minus what other carts currently hold
+func GetAvailableQuantity(db sqlx.Queryer, productId, excludeCartId string) (int, error) {
+	SQL := `SELECT p.quantity - COALESCE((SELECT SUM(sr.quantity)
+                                      FROM stock_reservations sr
+                                      WHERE sr.product_id = p.id
+                                        AND sr.status = 'active'
+                                        AND sr.expires_at > Now()
+                                        AND sr.cart_id::text <> $2), 0)
+			FROM products p
+			WHERE p.id = $1 AND p.archived_at IS NULL`
+	var available int
+	err := sqlx.Get(db, &available, SQL, productId, excludeCartId)
+	return available, err
+}
@@ -0,0 +1,59 @@
+package dbHelper
+
+import (
+	"audio_phile/model"
+	"github.com/jmoiron/sqlx"
+)
+
+const invoiceColumns = `id, order_id, number, financial_year, sequence, seller_name, seller_address, seller_tax_id,
+       buyer_name, buyer_email, buyer_address, subtotal, discount_total, tax_total, shipping_total, grand_total, credit_applied,
+       currency, issued_at`
+
+// NextInvoiceNumber hands out the next invoice number of a financial year. The sequence row stays locked until
+// the transaction ends, so numbers are handed out one after the other and a rolled back invoice leaves no gap.
+func NextInvoiceNumber(tx *sqlx.Tx, financialYear string) (int, error) {
+	SQL := `INSERT INTO invoice_sequences(financial_year, last_number) VALUES ($1, 1)
+			ON CONFLICT (financial_year) DO UPDATE SET last_number = invoice_sequences.last_number + 1
+			RETURNING last_number`
+	var number int
+	err := tx.Get(&number, SQL, financialYear)
+	return number, err
+}
+
+func CreateInvoice(db sqlx.Queryer, invoice model.Invoice, pdf []byte) (model.Invoice, error) {
+	SQL := `INSERT INTO invoices(order_id, number, financial_year, sequence, seller_name, seller_address, seller_tax_id,
+								 buyer_name, buyer_email, buyer_address, subtotal, discount_total, tax_total, shipping_total,
+								 grand_total, credit_applied, currency, pdf, issued_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+			RETURNING ` + invoiceColumns
+	var created model.Invoice
+	err := sqlx.Get(db, &created, SQL, invoice.OrderId, invoice.Number, invoice.FinancialYear, invoice.Sequence,
+		invoice.SellerName, invoice.SellerAddress, invoice.SellerTaxId, invoice.BuyerName, invoice.BuyerEmail,
+		invoice.BuyerAddress, invoice.Subtotal, invoice.DiscountTotal, invoice.TaxTotal, invoice.ShippingTotal,
+		invoice.GrandTotal, invoice.CreditApplied, invoice.Currency, pdf, invoice.IssuedAt)
+	return created, err
+}
+
+func GetOrderInvoice(db sqlx.Queryer, orderId string) (model.Invoice, error) {
+	SQL := `SELECT ` + invoiceColumns + ` FROM invoices WHERE order_id::text = $1`
+	var invoice model.Invoice
+	err := sqlx.Get(db, &invoice, SQL, orderId)
+	return invoice, err
+}
+
+func GetInvoicePDF(db sqlx.Queryer, invoiceId string) ([]byte, error) {
+	SQL := `SELECT pdf FROM invoices WHERE id = $1`
+	var pdf []byte
+	err := sqlx.Get(db, &pdf, SQL, invoiceId)
+	return pdf, err
+}
+
+// GetInvoiceBuyer returns the name and email of the user who placed an order
+func GetInvoiceBuyer(db sqlx.Queryer, orderId string) (model.InvoiceBuyer, error) {
+	SQL := `SELECT COALESCE(u.name, '') AS name, COALESCE(u.email, '') AS email
+			FROM orders o LEFT JOIN users u ON o.user_id = u.id
+			WHERE o.id = $1`
+	var buyer model.InvoiceBuyer
+	err := sqlx.Get(db, &buyer, SQL, orderId)
+	return buyer, err
+}
@@ -0,0 +1,36 @@
+package dbHelper
+
+import (
+	"audio_phile/database"
+	"audio_phile/model"
+)
+
+func CreateJobRun(job string) (string, error) {
+	SQL := `INSERT INTO job_runs(job) VALUES ($1) RETURNING id`
+	var runId string
+	err := database.Audiophile.QueryRowx(SQL, job).Scan(&runId)
+	return runId, err
+}
+
+func UpdateJobRunProgress(runId string, processed, failed int, lastError *string) error {
+	SQL := `UPDATE job_runs SET processed = $2, failed = $3, last_error = COALESCE($4, last_error) WHERE id = $1`
+	_, err := database.Audiophile.Exec(SQL, runId, processed, failed, lastError)
+	return err
+}
+
+func FinishJobRun(runId string, status model.JobStatus, lastError *string) error {
+	SQL := `UPDATE job_runs SET status = $2, last_error = COALESCE($3, last_error), finished_at = Now() WHERE id = $1`
+	_, err := database.Audiophile.Exec(SQL, runId, status, lastError)
+	return err
+}
+
+func GetJobRuns(job string, limit int) ([]model.JobRun, error) {
+	SQL := `SELECT id, job, status, processed, failed, last_error, started_at, finished_at
+			FROM job_runs
+			WHERE $1 = '' OR job = $1
+			ORDER BY started_at DESC
+			LIMIT $2`
+	list := make([]model.JobRun, 0)
+	err := database.Audiophile.Select(&list, SQL, job, limit)
+	return list, err
+}
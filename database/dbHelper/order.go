@@ -0,0 +1,222 @@
+package dbHelper
+
+import (
+	"audio_phile/database"
+	"audio_phile/model"
+	"github.com/jmoiron/sqlx"
+)
+
+// LockOrderStatus reads the status of an order and locks the order until the transaction ends
+func LockOrderStatus(tx *sqlx.Tx, orderId string) (model.OrderStatus, error) {
+	SQL := `SELECT status FROM orders WHERE id::text = $1 FOR UPDATE`
+	var status model.OrderStatus
+	err := tx.Get(&status, SQL, orderId)
+	return status, err
+}
+
+// LockPaymentOrderStatus locks the order a payment belongs to, for callers that only know the payment, so
+// the order is locked before its payments like everywhere else
+func LockPaymentOrderStatus(tx *sqlx.Tx, paymentId string) (model.OrderStatus, error) {
+	SQL := `SELECT o.status FROM orders o JOIN payments p ON p.order_id = o.id WHERE p.id = $1 FOR UPDATE OF o`
+	var status model.OrderStatus
+	err := tx.Get(&status, SQL, paymentId)
+	return status, err
+}
+
+func UpdateOrderStatus(db sqlx.Ext, orderId string, status model.OrderStatus) error {
+	SQL := `UPDATE orders SET status = $2, updated_at = Now() WHERE id = $1`
+	_, err := db.Exec(SQL, orderId, status)
+	return err
+}
+
+func CreateOrderStatusChange(db sqlx.Ext, orderId string, from *model.OrderStatus, to model.OrderStatus, actorId, note string) error {
+	SQL := `INSERT INTO order_status_history(order_id, from_status, to_status, actor_id, note)
+			VALUES ($1, $2, $3, NULLIF($4, '')::uuid, NULLIF($5, ''))`
+	_, err := db.Exec(SQL, orderId, from, to, actorId, note)
+	return err
+}
+
+func GetOrderStatusHistory(orderId string) ([]model.OrderStatusChange, error) {
+	SQL := `SELECT id, order_id, from_status, to_status, actor_id, note, created_at
+			FROM order_status_history
+			WHERE order_id::text = $1
+			ORDER BY created_at, id`
+	list := make([]model.OrderStatusChange, 0)
+	err := database.Audiophile.Select(&list, SQL, orderId)
+	return list, err
+}
+
+// GetOrderSales returns, per product and warehouse, the units an order took out of stock that were not
+// put back yet, ordered by product so rows are locked in the usual order
+func GetOrderSales(db sqlx.Queryer, orderId string) ([]model.OrderSale, error) {
+	SQL := `SELECT product_id, warehouse_id, -SUM(quantity) AS quantity
+			FROM stock_movements
+			WHERE reference_id = $1 AND movement_type IN ('sale', 'return')
+			GROUP BY product_id, warehouse_id
+			HAVING SUM(quantity) < 0
+			ORDER BY product_id, warehouse_id`
+	list := make([]model.OrderSale, 0)
+	err := sqlx.Select(db, &list, SQL, orderId)
+	return list, err
+}
+
+func CreateOrderItem(db sqlx.Ext, orderId string, line model.PricedLine) error {
+	SQL := `INSERT INTO order_items(order_id, product_id, name, category, unit_price, quantity, line_total, discount, tax_rate, tax, total)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+	_, err := db.Exec(SQL, orderId, line.ProductId, line.Name, line.Category, line.UnitPrice, line.Quantity,
+		line.LineTotal, line.Discount, line.TaxRate, line.Tax, line.Total)
+	return err
+}
+
+func GetOrderItems(db sqlx.Queryer, orderId string) ([]model.OrderItem, error) {
+	SQL := `SELECT product_id, name, category, unit_price, quantity, line_total, discount, tax_rate, tax, total
+			FROM order_items
+			WHERE order_id = $1
+			ORDER BY created_at, id`
+	list := make([]model.OrderItem, 0)
+	err := sqlx.Select(db, &list, SQL, orderId)
+	return list, err
+}
+
+// DeactivateCart closes a cart once it was ordered so it cannot be ordered again
+func DeactivateCart(db sqlx.Ext, cartId string) error {
+	SQL := `UPDATE carts SET status = $2, update_at = Now() WHERE id = $1`
+	_, err := db.Exec(SQL, cartId, model.CartStatusInActive)
+	return err
+}
+
+const orderColumns = `o.id,
+       o.user_id,
+       o.status,
+       o.subtotal,
+       o.discount_total,
+       o.tax_total,
+       o.shipping_total,
+       o.grand_total,
+       o.credit_applied,
+       o.shipping_address,
+       o.shipping_region,
+       o.shipping_postal_code,
+       o.shipping_lat,
+       o.shipping_long,
+       o.carrier,
+       o.tracking_number,
+       o.tracking_url,
+       o.shipped_at,
+       o.delivered_at,
+       o.created_at,
+       o.updated_at`
+
+// orderFilterSQL expects user, status, from, to, customer and minimum total as $1 to $6, empty or NULL
+// to not filter. The customer matches a user id or part of an email.
+const orderFilterSQL = `($1 = '' OR o.user_id::text = $1)
+			  AND ($2 = '' OR o.status::text = $2)
+			  AND ($3::timestamptz IS NULL OR o.created_at >= $3)
+			  AND ($4::timestamptz IS NULL OR o.created_at < $4)
+			  AND ($5 = '' OR o.user_id::text = $5 OR u.email ILIKE '%' || $5 || '%')
+			  AND o.grand_total >= $6`
+
+const orderListColumns = `o.id,
+       u.email,
+       o.status,
+       (SELECT COALESCE(SUM(oi.quantity), 0) FROM order_items oi WHERE oi.order_id = o.id) AS item_count,
+       o.grand_total,
+       o.created_at`
+
+// GetOrders returns a page of orders matching the filter, newest first, and the number of matching orders
+func GetOrders(filter model.OrderFilter) ([]model.OrderSummary, int, error) {
+	SQL := `SELECT ` + orderListColumns + `
+			FROM orders o LEFT JOIN users u ON o.user_id = u.id
+			WHERE ` + orderFilterSQL + `
+			ORDER BY o.created_at DESC, o.id
+			LIMIT $7 OFFSET $8`
+	list := make([]model.OrderSummary, 0)
+	err := database.Audiophile.Select(&list, SQL, filter.UserId, filter.Status, filter.From, filter.To, filter.Customer, filter.MinTotal,
+		filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	SQL = `SELECT count(*) FROM orders o LEFT JOIN users u ON o.user_id = u.id WHERE ` + orderFilterSQL
+	var total int
+	err = database.Audiophile.Get(&total, SQL, filter.UserId, filter.Status, filter.From, filter.To, filter.Customer, filter.MinTotal)
+	return list, total, err
+}
+
+// StreamOrders calls fn for every order matching the filter, newest first, ignoring the page
+func StreamOrders(filter model.OrderFilter, fn func(row model.OrderExportRow) error) error {
+	SQL := `SELECT o.id,
+       			   o.created_at,
+       			   o.status,
+       			   u.email,
+       			   (SELECT COALESCE(SUM(oi.quantity), 0) FROM order_items oi WHERE oi.order_id = o.id) AS item_count,
+       			   o.subtotal,
+       			   o.discount_total,
+       			   o.tax_total,
+       			   o.shipping_total,
+       			   o.grand_total,
+       			   o.shipping_region,
+       			   o.carrier,
+       			   o.tracking_number
+			FROM orders o LEFT JOIN users u ON o.user_id = u.id
+			WHERE ` + orderFilterSQL + `
+			ORDER BY o.created_at DESC, o.id`
+	rows, err := database.Audiophile.Queryx(SQL, filter.UserId, filter.Status, filter.From, filter.To, filter.Customer, filter.MinTotal)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var row model.OrderExportRow
+		if err := rows.StructScan(&row); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func CreateOrderNote(db sqlx.Ext, orderId, authorId, body string) (string, error) {
+	SQL := `INSERT INTO order_notes(order_id, author_id, body) SELECT id, $2::uuid, $3::text FROM orders WHERE id::text = $1 RETURNING id`
+	var noteId string
+	err := db.QueryRowx(SQL, orderId, authorId, body).Scan(&noteId)
+	return noteId, err
+}
+
+func GetOrderNotes(db sqlx.Queryer, orderId string) ([]model.OrderNote, error) {
+	SQL := `SELECT n.id, n.author_id, u.name AS author, n.body, n.created_at
+			FROM order_notes n INNER JOIN users u ON n.author_id = u.id
+			WHERE n.order_id = $1
+			ORDER BY n.created_at`
+	list := make([]model.OrderNote, 0)
+	err := sqlx.Select(db, &list, SQL, orderId)
+	return list, err
+}
+
+// GetOrder returns an order, only when it belongs to userId unless userId is empty
+func GetOrder(db sqlx.Queryer, orderId, userId string) (model.Order, error) {
+	SQL := `SELECT ` + orderColumns + ` FROM orders o WHERE o.id::text = $1 AND ($2 = '' OR o.user_id::text = $2)`
+	var order model.Order
+	err := sqlx.Get(db, &order, SQL, orderId, userId)
+	return order, err
+}
+
+// AddOrderCreditApplied adds store credit paid for an order to its totals
+func AddOrderCreditApplied(db sqlx.Ext, orderId string, amount model.Money) error {
+	SQL := `UPDATE orders SET credit_applied = credit_applied + $2, updated_at = Now() WHERE id = $1`
+	_, err := db.Exec(SQL, orderId, amount)
+	return err
+}
+
+func UpdateOrderTracking(db sqlx.Ext, orderId string, tracking model.Tracking) error {
+	SQL := `UPDATE orders SET carrier = $2, tracking_number = $3, tracking_url = NULLIF($4, ''), shipped_at = Now() WHERE id = $1`
+	_, err := db.Exec(SQL, orderId, tracking.Carrier, tracking.TrackingNumber, tracking.TrackingUrl)
+	return err
+}
+
+func MarkOrderDelivered(db sqlx.Ext, orderId string) error {
+	SQL := `UPDATE orders SET delivered_at = Now() WHERE id = $1`
+	_, err := db.Exec(SQL, orderId)
+	return err
+}
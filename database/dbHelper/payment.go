@@ -0,0 +1,135 @@
+package dbHelper
+
+import (
+	"audio_phile/model"
+	"github.com/jmoiron/sqlx"
+)
+
+const paymentColumns = `id, order_id, method, provider, intent_id, amount, currency, status, refunded_amount, failure_reason,
+       captured_at, created_at, collected_amount, collection_reference, collected_at, collected_by`
+
+func CreatePayment(db sqlx.Queryer, orderId string, method model.PaymentMethod, provider string, amount model.Money, currency string) (model.Payment, error) {
+	SQL := `INSERT INTO payments(order_id, method, provider, amount, currency) VALUES ($1, $2, $3, $4, $5) RETURNING ` + paymentColumns
+	var payment model.Payment
+	err := sqlx.Get(db, &payment, SQL, orderId, method, provider, amount, currency)
+	return payment, err
+}
+
+// GetOpenPayment returns the order's latest payment of the method that has neither failed nor been settled
+func GetOpenPayment(db sqlx.Queryer, orderId string, method model.PaymentMethod) (model.Payment, error) {
+	SQL := `SELECT ` + paymentColumns + `
+			FROM payments
+			WHERE order_id = $1 AND method = $2 AND status IN ('created', 'pending')
+			ORDER BY created_at DESC
+			LIMIT 1`
+	var payment model.Payment
+	err := sqlx.Get(db, &payment, SQL, orderId, method)
+	return payment, err
+}
+
+// GetOrderCaptured returns how much was captured for an order so far, whatever the method
+func GetOrderCaptured(db sqlx.Queryer, orderId string) (model.Money, error) {
+	SQL := `SELECT COALESCE(SUM(amount), 0) FROM payments WHERE order_id = $1 AND status IN ('captured', 'refunded')`
+	var captured model.Money
+	err := sqlx.Get(db, &captured, SQL, orderId)
+	return captured, err
+}
+
+// HasOpenCodPayment tells whether the order is to be paid in cash on delivery
+func HasOpenCodPayment(db sqlx.Queryer, orderId string) (bool, error) {
+	SQL := `SELECT count(*) > 0 FROM payments WHERE order_id = $1 AND method = 'cod' AND status = 'pending'`
+	var cod bool
+	err := sqlx.Get(db, &cod, SQL, orderId)
+	return cod, err
+}
+
+// GetCodPayments lists cash on delivery payments oldest first, only those still to be collected when
+// outstandingOnly is set. Payments of cancelled orders that were never collected are left out.
+func GetCodPayments(db sqlx.Queryer, outstandingOnly bool) ([]model.CodPayment, error) {
+	SQL := `SELECT p.id, p.order_id, p.method, p.provider, p.intent_id, p.amount, p.currency, p.status,
+				   p.refunded_amount, p.failure_reason, p.captured_at, p.created_at, p.collected_amount,
+				   p.collection_reference, p.collected_at, p.collected_by,
+				   o.status AS order_status, o.delivered_at
+			FROM payments p
+					 JOIN orders o ON o.id = p.order_id
+			WHERE p.method = 'cod'
+			  AND p.status IN ('pending', 'captured', 'refunded')
+			  AND NOT (o.status = 'cancelled' AND p.status = 'pending')
+			  AND (NOT $1 OR p.status = 'pending')
+			ORDER BY p.created_at`
+	list := make([]model.CodPayment, 0)
+	err := sqlx.Select(db, &list, SQL, outstandingOnly)
+	return list, err
+}
+
+// RecordCodCollection stores what the courier collected for a cash on delivery payment
+func RecordCodCollection(db sqlx.Ext, paymentId string, amount model.Money, reference, actorId string) error {
+	SQL := `UPDATE payments
+			SET collected_amount = $2,
+			    collection_reference = NULLIF($3, ''),
+			    collected_at = Now(),
+			    collected_by = $4,
+			    updated_at = Now()
+			WHERE id = $1`
+	_, err := db.Exec(SQL, paymentId, amount, reference, actorId)
+	return err
+}
+
+// LockPayment reads a payment and locks it until the transaction ends
+func LockPayment(tx *sqlx.Tx, paymentId string) (model.Payment, error) {
+	SQL := `SELECT ` + paymentColumns + ` FROM payments WHERE id = $1 FOR UPDATE`
+	var payment model.Payment
+	err := tx.Get(&payment, SQL, paymentId)
+	return payment, err
+}
+
+func GetPaymentIdByIntent(db sqlx.Queryer, provider, intentId string) (string, error) {
+	SQL := `SELECT id FROM payments WHERE provider = $1 AND intent_id = $2`
+	var paymentId string
+	err := sqlx.Get(db, &paymentId, SQL, provider, intentId)
+	return paymentId, err
+}
+
+func GetOrderPayments(db sqlx.Queryer, orderId string) ([]model.Payment, error) {
+	SQL := `SELECT ` + paymentColumns + ` FROM payments WHERE order_id = $1 ORDER BY created_at`
+	list := make([]model.Payment, 0)
+	err := sqlx.Select(db, &list, SQL, orderId)
+	return list, err
+}
+
+func SetPaymentIntent(db sqlx.Ext, paymentId, intentId string) error {
+	SQL := `UPDATE payments SET intent_id = $2, updated_at = Now() WHERE id = $1`
+	_, err := db.Exec(SQL, paymentId, intentId)
+	return err
+}
+
+func UpdatePaymentStatus(db sqlx.Ext, paymentId string, status model.PaymentStatus, failureReason string) error {
+	SQL := `UPDATE payments
+			SET status = $2,
+			    failure_reason = NULLIF($3, ''),
+			    captured_at = CASE WHEN $2 = 'captured' THEN Now() ELSE captured_at END,
+			    updated_at = Now()
+			WHERE id = $1`
+	_, err := db.Exec(SQL, paymentId, status, failureReason)
+	return err
+}
+
+// AddPaymentRefund adds to the refunded amount and marks the payment refunded once all of it was given back
+func AddPaymentRefund(db sqlx.Ext, paymentId string, amount model.Money) error {
+	SQL := `UPDATE payments
+			SET refunded_amount = refunded_amount + $2,
+			    status = CASE WHEN refunded_amount + $2 >= amount THEN 'refunded'::payment_status ELSE status END,
+			    updated_at = Now()
+			WHERE id = $1`
+	_, err := db.Exec(SQL, paymentId, amount)
+	return err
+}
+
+// FailOpenCodPayment drops the cash on delivery payment of an order that will not be delivered
+func FailOpenCodPayment(db sqlx.Ext, orderId, reason string) error {
+	SQL := `UPDATE payments
+			SET status = 'failed', failure_reason = $2, updated_at = Now()
+			WHERE order_id = $1 AND method = 'cod' AND status IN ('created', 'pending')`
+	_, err := db.Exec(SQL, orderId, reason)
+	return err
+}
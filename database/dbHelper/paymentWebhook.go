@@ -0,0 +1,57 @@
+package dbHelper
+
+import (
+	"audio_phile/database"
+	"audio_phile/model"
+	"database/sql"
+	"github.com/jmoiron/sqlx"
+)
+
+const webhookEventColumns = `id, provider, event_id, event_type, payload, attempts, last_error, received_at, processed_at`
+
+// CreateWebhookEvent stores a received event and returns it. When the provider already delivered an event
+// with the same id, the stored one is returned with created false.
+func CreateWebhookEvent(provider, eventId, eventType, payload string) (model.PaymentWebhookEvent, bool, error) {
+	SQL := `INSERT INTO payment_webhook_events(provider, event_id, event_type, payload) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (provider, event_id) DO NOTHING
+			RETURNING ` + webhookEventColumns
+	var event model.PaymentWebhookEvent
+	err := database.Audiophile.Get(&event, SQL, provider, eventId, eventType, payload)
+	if err != sql.ErrNoRows {
+		return event, err == nil, err
+	}
+	SQL = `SELECT ` + webhookEventColumns + ` FROM payment_webhook_events WHERE provider = $1 AND event_id = $2`
+	err = database.Audiophile.Get(&event, SQL, provider, eventId)
+	return event, false, err
+}
+
+// LockWebhookEvent reads a stored event and locks it so that it is processed by one request at a time
+func LockWebhookEvent(tx *sqlx.Tx, id string) (model.PaymentWebhookEvent, error) {
+	SQL := `SELECT ` + webhookEventColumns + ` FROM payment_webhook_events WHERE id::text = $1 FOR UPDATE`
+	var event model.PaymentWebhookEvent
+	err := tx.Get(&event, SQL, id)
+	return event, err
+}
+
+func MarkWebhookEventProcessed(db sqlx.Ext, id string) error {
+	SQL := `UPDATE payment_webhook_events SET processed_at = Now(), attempts = attempts + 1, last_error = NULL WHERE id = $1`
+	_, err := db.Exec(SQL, id)
+	return err
+}
+
+func MarkWebhookEventFailed(id, message string) error {
+	SQL := `UPDATE payment_webhook_events SET attempts = attempts + 1, last_error = $2 WHERE id = $1`
+	_, err := database.Audiophile.Exec(SQL, id, message)
+	return err
+}
+
+func GetWebhookEvents(unprocessedOnly bool, limit int) ([]model.PaymentWebhookEvent, error) {
+	SQL := `SELECT ` + webhookEventColumns + `
+			FROM payment_webhook_events
+			WHERE NOT $1 OR processed_at IS NULL
+			ORDER BY received_at DESC
+			LIMIT $2`
+	list := make([]model.PaymentWebhookEvent, 0)
+	err := database.Audiophile.Select(&list, SQL, unprocessedOnly, limit)
+	return list, err
+}
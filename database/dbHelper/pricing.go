@@ -0,0 +1,81 @@
+package dbHelper
+
+import (
+	"audio_phile/database"
+	"audio_phile/model"
+	"github.com/jmoiron/sqlx"
+)
+
+// GetPricingLines returns the live lines of a cart with the current catalog price of each product
+func GetPricingLines(db sqlx.Queryer, cartId string) ([]model.PricingLine, error) {
+	SQL := `SELECT p.id AS product_id,
+       			   p.name,
+       			   p.category,
+       			   p.price,
+       			   p.weight_grams,
+       			   SUM(cp.quantity) AS quantity
+			FROM cart_products cp INNER JOIN products p ON cp.product_id = p.id
+			WHERE cp.cart_id = $1 AND cp.archived_at IS NULL
+			GROUP BY p.id, p.name, p.category, p.price, p.weight_grams
+			ORDER BY p.id`
+	list := make([]model.PricingLine, 0)
+	err := sqlx.Select(db, &list, SQL, cartId)
+	return list, err
+}
+
+func GetTaxRates(db sqlx.Queryer) ([]model.TaxRate, error) {
+	SQL := `SELECT id, name, category, region, rate_bps FROM tax_rates WHERE archived_at IS NULL ORDER BY category NULLS LAST, region NULLS LAST`
+	list := make([]model.TaxRate, 0)
+	err := sqlx.Select(db, &list, SQL)
+	return list, err
+}
+
+func IsTaxRateExist(category model.Category, region string) (bool, error) {
+	SQL := `SELECT count(*) > 0
+			FROM tax_rates
+			WHERE COALESCE(category::text, '') = $1 AND COALESCE(UPPER(region), '') = UPPER(TRIM($2)) AND archived_at IS NULL`
+	var exist bool
+	err := database.Audiophile.Get(&exist, SQL, string(category), region)
+	return exist, err
+}
+
+func CreateTaxRate(body model.TaxRateRequest) (string, error) {
+	SQL := `INSERT INTO tax_rates(name, category, region, rate_bps) VALUES ($1, NULLIF($2, '')::category, NULLIF(UPPER(TRIM($3)), ''), $4) RETURNING id`
+	var taxRateId string
+	err := database.Audiophile.QueryRowx(SQL, body.Name, string(body.Category), body.Region, body.RateBps).Scan(&taxRateId)
+	return taxRateId, err
+}
+
+func ArchiveTaxRate(taxRateId string) (bool, error) {
+	SQL := `UPDATE tax_rates SET archived_at = Now() WHERE id::text = $1 AND archived_at IS NULL`
+	result, err := database.Audiophile.Exec(SQL, taxRateId)
+	if err != nil {
+		return false, err
+	}
+	count, err := result.RowsAffected()
+	return count > 0, err
+}
+
+// GetUserAddress returns one of the user's addresses, or the most recently added one when addressId is empty
+func GetUserAddress(db sqlx.Queryer, userId, addressId string) (model.AddressModel, error) {
+	SQL := `SELECT id, address, address_type, region, COALESCE(postal_code, '') AS postal_code, lat, long
+			FROM user_addresses
+			WHERE user_id = $1 AND ($2 = '' OR id::text = $2) AND archived_at IS NULL
+			ORDER BY created_at DESC
+			LIMIT 1`
+	var address model.AddressModel
+	err := sqlx.Get(db, &address, SQL, userId, addressId)
+	return address, err
+}
+
+// UpdateOrderCheckout records the user, the copied shipping address and the totals of a placed order
+func UpdateOrderCheckout(db sqlx.Ext, orderId, userId string, address model.AddressModel, pricing model.PriceBreakdown) error {
+	SQL := `UPDATE orders
+			SET user_id = $2, address_id = $3, shipping_address = $4, shipping_region = $5, shipping_lat = $6, shipping_long = $7,
+			    subtotal = $8, discount_total = $9, tax_total = $10, shipping_total = $11, grand_total = $12,
+			    shipping_postal_code = NULLIF($13, ''), updated_at = Now()
+			WHERE id = $1`
+	_, err := db.Exec(SQL, orderId, userId, address.Id, address.Address, address.Region, address.Lat, address.Long,
+		pricing.Subtotal, pricing.DiscountTotal, pricing.TaxTotal, pricing.ShippingTotal, pricing.GrandTotal, address.PostalCode)
+	return err
+}
@@ -0,0 +1,139 @@
+package dbHelper
+
+import (
+	"audio_phile/database"
+	"audio_phile/model"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// promotionColumns expects the user the usage is counted for as $1
+const promotionColumns = `p.id,
+       p.name,
+       p.code,
+       p.kind,
+       p.value,
+       p.category,
+       p.product_id,
+       p.buy_quantity,
+       p.get_quantity,
+       p.min_cart_value,
+       p.usage_limit,
+       p.per_user_limit,
+       p.starts_at,
+       p.ends_at,
+       (SELECT count(*) FROM promotion_redemptions r WHERE r.promotion_id = p.id) AS uses,
+       (SELECT count(*) FROM promotion_redemptions r WHERE r.promotion_id = p.id AND r.user_id::text = $1) AS user_uses,
+       p.created_at`
+
+func CreatePromotion(body model.PromotionRequest) (string, error) {
+	SQL := `INSERT INTO promotions(name, code, kind, value, category, product_id, buy_quantity, get_quantity, min_cart_value,
+                       usage_limit, per_user_limit, starts_at, ends_at)
+			VALUES ($1, NULLIF(UPPER($2), ''), $3, $4, NULLIF($5, '')::category, NULLIF($6, '')::uuid, $7, $8, $9, $10, $11, $12, $13)
+			RETURNING id`
+	var promotionId string
+	err := database.Audiophile.QueryRowx(SQL, body.Name, body.Code, body.Kind, body.Value, string(body.Category), body.ProductId,
+		body.BuyQuantity, body.GetQuantity, body.MinCartValue, body.UsageLimit, body.PerUserLimit, body.StartsAt, body.EndsAt).Scan(&promotionId)
+	return promotionId, err
+}
+
+func UpdatePromotion(promotionId string, body model.PromotionRequest) (bool, error) {
+	SQL := `UPDATE promotions
+			SET name = $2, code = NULLIF(UPPER($3), ''), kind = $4, value = $5, category = NULLIF($6, '')::category,
+			    product_id = NULLIF($7, '')::uuid, buy_quantity = $8, get_quantity = $9, min_cart_value = $10,
+			    usage_limit = $11, per_user_limit = $12, starts_at = $13, ends_at = $14, updated_at = Now()
+			WHERE id::text = $1 AND archived_at IS NULL`
+	result, err := database.Audiophile.Exec(SQL, promotionId, body.Name, body.Code, body.Kind, body.Value, string(body.Category), body.ProductId,
+		body.BuyQuantity, body.GetQuantity, body.MinCartValue, body.UsageLimit, body.PerUserLimit, body.StartsAt, body.EndsAt)
+	if err != nil {
+		return false, err
+	}
+	count, err := result.RowsAffected()
+	return count > 0, err
+}
+
+// ArchivePromotion ends a promotion and takes its coupon off every cart it was applied to
+func ArchivePromotion(tx *sqlx.Tx, promotionId string) (bool, error) {
+	SQL := `UPDATE promotions SET archived_at = Now() WHERE id::text = $1 AND archived_at IS NULL`
+	result, err := tx.Exec(SQL, promotionId)
+	if err != nil {
+		return false, err
+	}
+	count, err := result.RowsAffected()
+	if err != nil || count == 0 {
+		return false, err
+	}
+	SQL = `UPDATE carts SET coupon_id = NULL WHERE coupon_id::text = $1`
+	_, err = tx.Exec(SQL, promotionId)
+	return true, err
+}
+
+func IsPromotionCodeTaken(code, excludeId string) (bool, error) {
+	SQL := `SELECT count(*) > 0 FROM promotions WHERE UPPER(code) = UPPER($1) AND id::text <> $2 AND archived_at IS NULL`
+	var taken bool
+	err := database.Audiophile.Get(&taken, SQL, code, excludeId)
+	return taken, err
+}
+
+func GetPromotions() ([]model.Promotion, error) {
+	SQL := `SELECT ` + promotionColumns + ` FROM promotions p WHERE p.archived_at IS NULL ORDER BY p.created_at DESC`
+	list := make([]model.Promotion, 0)
+	err := database.Audiophile.Select(&list, SQL, "")
+	return list, err
+}
+
+func GetPromotionById(promotionId string) (model.Promotion, error) {
+	SQL := `SELECT ` + promotionColumns + ` FROM promotions p WHERE p.id::text = $2 AND p.archived_at IS NULL`
+	var promotion model.Promotion
+	err := database.Audiophile.Get(&promotion, SQL, "", promotionId)
+	return promotion, err
+}
+
+func GetCouponIdByCode(db sqlx.Queryer, code string) (string, error) {
+	SQL := `SELECT id FROM promotions WHERE UPPER(code) = UPPER(TRIM($1)) AND archived_at IS NULL`
+	var couponId string
+	err := sqlx.Get(db, &couponId, SQL, code)
+	return couponId, err
+}
+
+// GetCartPromotionContext returns the owner of a cart and the coupon applied to it, if any
+func GetCartPromotionContext(db sqlx.Queryer, cartId string) (string, *string, error) {
+	SQL := `SELECT COALESCE(user_id::text, '') AS user_id, coupon_id FROM carts WHERE id = $1`
+	var context struct {
+		UserId   string  `db:"user_id"`
+		CouponId *string `db:"coupon_id"`
+	}
+	err := sqlx.Get(db, &context, SQL, cartId)
+	return context.UserId, context.CouponId, err
+}
+
+func SetCartCoupon(db sqlx.Ext, cartId string, couponId *string) error {
+	SQL := `UPDATE carts SET coupon_id = $2, update_at = Now() WHERE id = $1`
+	_, err := db.Exec(SQL, cartId, couponId)
+	return err
+}
+
+// GetCandidatePromotions returns every live automatic promotion and the given coupon, with their usage
+// by userId. Validity windows, limits and minimum cart values are left to the pricing engine.
+func GetCandidatePromotions(db sqlx.Queryer, userId, couponId string) ([]model.Promotion, error) {
+	SQL := `SELECT ` + promotionColumns + `
+			FROM promotions p
+			WHERE p.archived_at IS NULL AND (p.code IS NULL OR p.id::text = $2)
+			ORDER BY p.code NULLS FIRST, p.created_at`
+	list := make([]model.Promotion, 0)
+	err := sqlx.Select(db, &list, SQL, userId, couponId)
+	return list, err
+}
+
+// LockPromotions serialises orders redeeming the same promotions so usage limits cannot be overrun
+func LockPromotions(tx *sqlx.Tx, promotionIds []string) error {
+	SQL := `SELECT id FROM promotions WHERE id::text = ANY($1) ORDER BY id FOR UPDATE`
+	var locked []string
+	return tx.Select(&locked, SQL, pq.Array(promotionIds))
+}
+
+func CreatePromotionRedemption(db sqlx.Ext, promotionId, userId, orderId string, amount model.Money) error {
+	SQL := `INSERT INTO promotion_redemptions(promotion_id, user_id, order_id, amount) VALUES ($1, $2, $3, $4)`
+	_, err := db.Exec(SQL, promotionId, userId, orderId, amount)
+	return err
+}
@@ -0,0 +1,121 @@
+package dbHelper
+
+import (
+	"audio_phile/model"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+const refundColumns = `id, order_id, payment_id, return_id, amount, status, provider_refund_id, reason, failure_reason, actor_id, created_at`
+
+// LockRefundablePayments returns the captured payments of an order that can be refunded, oldest first, with
+// what is left to refund of each once pending refunds are set aside, and locks them until the transaction ends.
+// Gateway payments are left out until the provider gave them an intent to refund against.
+func LockRefundablePayments(tx *sqlx.Tx, orderId string) ([]model.RefundablePayment, error) {
+	SQL := `SELECT p.id,
+				   p.method,
+				   COALESCE(p.intent_id, '') AS intent_id,
+				   p.amount - p.refunded_amount - (SELECT COALESCE(SUM(r.amount), 0)
+												   FROM refunds r
+												   WHERE r.payment_id = p.id
+													 AND r.status = 'pending') AS refundable
+			FROM payments p
+			WHERE p.order_id = $1
+			  AND p.status = 'captured'
+			  AND (p.method <> 'gateway' OR p.intent_id IS NOT NULL)
+			ORDER BY p.created_at, p.id
+			FOR UPDATE`
+	list := make([]model.RefundablePayment, 0)
+	err := tx.Select(&list, SQL, orderId)
+	return list, err
+}
+
+// GetOrderUnrefunded returns how much of what was captured for an order has not been refunded yet
+func GetOrderUnrefunded(db sqlx.Queryer, orderId string) (model.Money, error) {
+	SQL := `SELECT COALESCE(SUM(amount - refunded_amount), 0)
+			FROM payments
+			WHERE order_id = $1 AND status IN ('captured', 'refunded')`
+	var unrefunded model.Money
+	err := sqlx.Get(db, &unrefunded, SQL, orderId)
+	return unrefunded, err
+}
+
+func CreateRefund(db sqlx.Queryer, orderId, paymentId, returnId string, amount model.Money, reason, actorId string) (model.Refund, error) {
+	SQL := `INSERT INTO refunds(order_id, payment_id, return_id, amount, reason, actor_id)
+			VALUES ($1, $2, NULLIF($3, '')::uuid, $4, NULLIF($5, ''), NULLIF($6, '')::uuid)
+			RETURNING ` + refundColumns
+	var refund model.Refund
+	err := sqlx.Get(db, &refund, SQL, orderId, paymentId, returnId, amount, reason, actorId)
+	return refund, err
+}
+
+// UpdateRefund settles a pending refund, one that is settled already is left alone
+func UpdateRefund(db sqlx.Ext, refundId string, status model.RefundStatus, providerRefundId, failureReason string) error {
+	SQL := `UPDATE refunds
+			SET status = $2,
+			    provider_refund_id = NULLIF($3, ''),
+			    failure_reason = NULLIF($4, ''),
+			    updated_at = Now()
+			WHERE id = $1 AND status = 'pending'`
+	_, err := db.Exec(SQL, refundId, status, providerRefundId, failureReason)
+	return err
+}
+
+func GetOrderRefunds(db sqlx.Queryer, orderId string) ([]model.Refund, error) {
+	SQL := `SELECT ` + refundColumns + ` FROM refunds WHERE order_id = $1 ORDER BY created_at, id`
+	list := make([]model.Refund, 0)
+	err := sqlx.Select(db, &list, SQL, orderId)
+	return list, err
+}
+
+func GetReturnRefunds(db sqlx.Queryer, returnId string) ([]model.Refund, error) {
+	SQL := `SELECT ` + refundColumns + ` FROM refunds WHERE return_id = $1 ORDER BY created_at, id`
+	list := make([]model.Refund, 0)
+	err := sqlx.Select(db, &list, SQL, returnId)
+	return list, err
+}
+
+// LockRefundStatus returns the status of a refund and locks it until the transaction ends
+func LockRefundStatus(tx *sqlx.Tx, refundId string) (model.RefundStatus, error) {
+	SQL := `SELECT status FROM refunds WHERE id = $1 FOR UPDATE`
+	var status model.RefundStatus
+	err := tx.Get(&status, SQL, refundId)
+	return status, err
+}
+
+// GetReturnRefunded returns how much of a return was refunded or is still being refunded, failed refunds left out
+func GetReturnRefunded(db sqlx.Queryer, returnId string) (model.Money, error) {
+	SQL := `SELECT COALESCE(SUM(amount), 0) FROM refunds WHERE return_id = $1 AND status IN ('pending', 'succeeded')`
+	var refunded model.Money
+	err := sqlx.Get(db, &refunded, SQL, returnId)
+	return refunded, err
+}
+
+// GetStalePendingRefunds returns refunds created before since that are still pending, left behind when the
+// process stopped between writing a refund and recording the provider's answer, with what is needed to retry them
+func GetStalePendingRefunds(db sqlx.Queryer, since time.Time, limit int) ([]model.PendingRefund, error) {
+	SQL := `SELECT r.id,
+				   r.order_id,
+				   r.payment_id,
+				   r.return_id,
+				   r.amount,
+				   r.status,
+				   r.provider_refund_id,
+				   r.reason,
+				   r.failure_reason,
+				   r.actor_id,
+				   r.created_at,
+				   p.method,
+				   COALESCE(p.intent_id, '')     AS intent_id,
+				   COALESCE(o.user_id::text, '') AS user_id
+			FROM refunds r
+					 JOIN payments p ON p.id = r.payment_id
+					 JOIN orders o ON o.id = r.order_id
+			WHERE r.status = 'pending'
+			  AND r.created_at < $1
+			ORDER BY r.created_at
+			LIMIT $2`
+	list := make([]model.PendingRefund, 0)
+	err := sqlx.Select(db, &list, SQL, since, limit)
+	return list, err
+}
@@ -0,0 +1,79 @@
+package dbHelper
+
+import (
+	"audio_phile/database"
+	"audio_phile/model"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+func CreateRestockSubscription(db sqlx.Ext, userId, productId string) (string, error) {
+	SQL := `INSERT INTO restock_subscriptions(user_id, product_id) VALUES ($1, $2) RETURNING id`
+	var subscriptionId string
+	err := db.QueryRowx(SQL, userId, productId).Scan(&subscriptionId)
+	return subscriptionId, err
+}
+
+func DeleteRestockSubscription(db sqlx.Ext, userId, productId string) (bool, error) {
+	SQL := `UPDATE restock_subscriptions SET archived_at = Now() WHERE user_id = $1 AND product_id = $2 AND notified_at IS NULL AND archived_at IS NULL`
+	result, err := db.Exec(SQL, userId, productId)
+	if err != nil {
+		return false, err
+	}
+	count, err := result.RowsAffected()
+	return count > 0, err
+}
+
+func GetUserRestockSubscriptions(userId string) ([]model.RestockSubscription, error) {
+	SQL := `SELECT s.id, s.product_id, p.name AS product_name, s.user_id, '' AS email, s.created_at, s.queued_at
+			FROM restock_subscriptions s INNER JOIN products p ON s.product_id = p.id
+			WHERE s.user_id = $1 AND s.notified_at IS NULL AND s.archived_at IS NULL
+			ORDER BY s.created_at`
+	list := make([]model.RestockSubscription, 0)
+	err := database.Audiophile.Select(&list, SQL, userId)
+	return list, err
+}
+
+// QueueRestockNotifications puts every waiting subscriber of the product in the delivery queue
+func QueueRestockNotifications(db sqlx.Ext, productId string) error {
+	SQL := `UPDATE restock_subscriptions SET queued_at = Now() WHERE product_id = $1 AND queued_at IS NULL AND notified_at IS NULL AND archived_at IS NULL`
+	_, err := db.Exec(SQL, productId)
+	return err
+}
+
+// GetQueuedRestockNotifications returns the next queued subscriptions in subscription order, skipping
+// products that went out of stock again before their subscribers were reached, deliveries that failed
+// maxAttempts times and those whose retry is not due yet
+func GetQueuedRestockNotifications(limit, maxAttempts int) ([]model.RestockSubscription, error) {
+	SQL := `SELECT s.id, s.product_id, p.name AS product_name, s.user_id, u.email, s.created_at, s.queued_at, s.attempts
+			FROM restock_subscriptions s
+				INNER JOIN products p ON s.product_id = p.id
+				INNER JOIN users u ON s.user_id = u.id
+			WHERE s.queued_at IS NOT NULL
+			  AND s.notified_at IS NULL
+			  AND s.archived_at IS NULL
+			  AND p.is_available
+			  AND p.archived_at IS NULL
+			  AND u.archived_at IS NULL
+			  AND s.attempts < $2
+			  AND (s.next_attempt_at IS NULL OR s.next_attempt_at <= Now())
+			ORDER BY s.created_at
+			LIMIT $1`
+	list := make([]model.RestockSubscription, 0)
+	err := database.Audiophile.Select(&list, SQL, limit, maxAttempts)
+	return list, err
+}
+
+// MarkRestockNotified records the delivery, which also ends the subscription
+func MarkRestockNotified(subscriptionId string) error {
+	SQL := `UPDATE restock_subscriptions SET notified_at = Now() WHERE id = $1`
+	_, err := database.Audiophile.Exec(SQL, subscriptionId)
+	return err
+}
+
+// MarkRestockFailed records a failed delivery and when it may be tried again
+func MarkRestockFailed(subscriptionId, lastError string, retryAt time.Time) error {
+	SQL := `UPDATE restock_subscriptions SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3 WHERE id = $1`
+	_, err := database.Audiophile.Exec(SQL, subscriptionId, lastError, retryAt)
+	return err
+}
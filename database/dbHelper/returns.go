@@ -0,0 +1,140 @@
+package dbHelper
+
+import (
+	"audio_phile/model"
+	"github.com/jmoiron/sqlx"
+)
+
+const returnColumns = `id, order_id, user_id, status, comment, resolution_note, resolved_by, carrier, tracking_number,
+       tracking_url, shipped_at, received_at, refund_amount, created_at, updated_at`
+
+// GetReturnableItems returns the lines of an order with the units already claimed by returns that were not rejected
+func GetReturnableItems(db sqlx.Queryer, orderId string) ([]model.ReturnableItem, error) {
+	SQL := `SELECT oi.product_id,
+				   oi.quantity,
+				   oi.total,
+				   COALESCE((SELECT SUM(ri.quantity)
+							 FROM return_items ri
+									  JOIN returns r ON r.id = ri.return_id
+							 WHERE r.order_id = oi.order_id
+							   AND r.status != 'rejected'
+							   AND ri.product_id = oi.product_id), 0) AS returned
+			FROM order_items oi
+			WHERE oi.order_id = $1
+			ORDER BY oi.product_id`
+	list := make([]model.ReturnableItem, 0)
+	err := sqlx.Select(db, &list, SQL, orderId)
+	return list, err
+}
+
+func CreateReturn(db sqlx.Queryer, orderId, userId, comment string) (string, error) {
+	SQL := `INSERT INTO returns(order_id, user_id, comment) VALUES ($1, $2, NULLIF($3, '')) RETURNING id`
+	var returnId string
+	err := sqlx.Get(db, &returnId, SQL, orderId, userId, comment)
+	return returnId, err
+}
+
+func CreateReturnItem(db sqlx.Ext, returnId string, item model.ReturnItemRequest, amount model.Money) error {
+	SQL := `INSERT INTO return_items(return_id, product_id, quantity, reason, amount) VALUES ($1, $2, $3, $4, $5)`
+	_, err := db.Exec(SQL, returnId, item.ProductId, item.Quantity, item.Reason, amount)
+	return err
+}
+
+// GetReturn returns a return. With a userId only that user's return is returned, anybody else's is reported
+// as sql.ErrNoRows.
+func GetReturn(db sqlx.Queryer, returnId, userId string) (model.Return, error) {
+	SQL := `SELECT ` + returnColumns + `
+			FROM returns
+			WHERE id::text = $1 AND ($2 = '' OR user_id::text = $2)`
+	var rma model.Return
+	err := sqlx.Get(db, &rma, SQL, returnId, userId)
+	return rma, err
+}
+
+// LockReturn is GetReturn that also locks the return until the transaction ends
+func LockReturn(tx *sqlx.Tx, returnId, userId string) (model.Return, error) {
+	SQL := `SELECT ` + returnColumns + `
+			FROM returns
+			WHERE id::text = $1 AND ($2 = '' OR user_id::text = $2)
+			FOR UPDATE`
+	var rma model.Return
+	err := tx.Get(&rma, SQL, returnId, userId)
+	return rma, err
+}
+
+// GetReturns lists returns newest first, of one user when userId is given and in one status when status is given
+func GetReturns(db sqlx.Queryer, userId string, status model.ReturnStatus) ([]model.Return, error) {
+	SQL := `SELECT ` + returnColumns + `
+			FROM returns
+			WHERE ($1 = '' OR user_id::text = $1)
+			  AND ($2 = '' OR status::text = $2)
+			ORDER BY created_at DESC`
+	list := make([]model.Return, 0)
+	err := sqlx.Select(db, &list, SQL, userId, status)
+	return list, err
+}
+
+func GetReturnItems(db sqlx.Queryer, returnId string) ([]model.ReturnItem, error) {
+	SQL := `SELECT ri.product_id, oi.name, ri.quantity, ri.reason, ri.amount, ri.restocked
+			FROM return_items ri
+					 JOIN returns r ON r.id = ri.return_id
+					 JOIN order_items oi ON oi.order_id = r.order_id AND oi.product_id = ri.product_id
+			WHERE ri.return_id = $1
+			ORDER BY ri.product_id`
+	list := make([]model.ReturnItem, 0)
+	err := sqlx.Select(db, &list, SQL, returnId)
+	return list, err
+}
+
+// ResolveReturn records an admin's approval or rejection of a return
+func ResolveReturn(db sqlx.Ext, returnId string, status model.ReturnStatus, actorId, note string) error {
+	SQL := `UPDATE returns
+			SET status = $2, resolved_by = $3, resolution_note = NULLIF($4, ''), updated_at = Now()
+			WHERE id = $1`
+	_, err := db.Exec(SQL, returnId, status, actorId, note)
+	return err
+}
+
+func UpdateReturnShipment(db sqlx.Ext, returnId string, tracking model.Tracking) error {
+	SQL := `UPDATE returns
+			SET status = 'in_transit',
+			    carrier = $2,
+			    tracking_number = $3,
+			    tracking_url = NULLIF($4, ''),
+			    shipped_at = Now(),
+			    updated_at = Now()
+			WHERE id = $1`
+	_, err := db.Exec(SQL, returnId, tracking.Carrier, tracking.TrackingNumber, tracking.TrackingUrl)
+	return err
+}
+
+func MarkReturnReceived(db sqlx.Ext, returnId string, restocked bool) error {
+	SQL := `UPDATE returns SET status = 'received', received_at = Now(), updated_at = Now() WHERE id = $1`
+	if _, err := db.Exec(SQL, returnId); err != nil {
+		return err
+	}
+	SQL = `UPDATE return_items SET restocked = $2 WHERE return_id = $1`
+	_, err := db.Exec(SQL, returnId, restocked)
+	return err
+}
+
+// SetReturnRefundAmount records how much the return is being refunded in total
+func SetReturnRefundAmount(db sqlx.Ext, returnId string, amount model.Money) error {
+	SQL := `UPDATE returns SET refund_amount = $2, updated_at = Now() WHERE id = $1`
+	_, err := db.Exec(SQL, returnId, amount)
+	return err
+}
+
+// CompleteReturnRefund moves a received return to refunded once its succeeded refunds cover its refund amount
+func CompleteReturnRefund(db sqlx.Ext, returnId string) error {
+	SQL := `UPDATE returns
+			SET status = 'refunded', updated_at = Now()
+			WHERE id = $1
+			  AND status = 'received'
+			  AND refund_amount <= (SELECT COALESCE(SUM(amount), 0)
+									FROM refunds
+									WHERE return_id = $1
+									  AND status = 'succeeded')`
+	_, err := db.Exec(SQL, returnId)
+	return err
+}
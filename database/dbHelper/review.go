@@ -0,0 +1,83 @@
+package dbHelper
+
+import (
+	"audio_phile/database"
+	"audio_phile/model"
+	"database/sql"
+	"github.com/jmoiron/sqlx"
+)
+
+// HasUserOrderedProduct reports whether the user paid for an order containing the product. Orders still
+// awaiting payment or cancelled do not count, refunded ones neither.
+func HasUserOrderedProduct(userId, productId string) (bool, error) {
+	SQL := `SELECT EXISTS(SELECT 1
+              FROM order_items oi
+                       INNER JOIN orders o ON oi.order_id = o.id
+              WHERE o.user_id = $1
+                AND oi.product_id = $2
+                AND o.status IN ('paid', 'packed', 'shipped', 'delivered'))`
+	var ordered bool
+	err := database.Audiophile.Get(&ordered, SQL, userId, productId)
+	return ordered, err
+}
+
+func IsReviewExist(userId, productId string) (bool, error) {
+	SQL := `SELECT id FROM product_reviews WHERE user_id = $1 AND product_id = $2 AND archived_at IS NULL`
+	var id string
+	err := database.Audiophile.Get(&id, SQL, userId, productId)
+	if err != nil && err != sql.ErrNoRows {
+		return false, err
+	}
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return true, nil
+}
+
+func CreateReview(db sqlx.Ext, userId, productId string, review model.ReviewRequest) (string, error) {
+	SQL := `INSERT INTO product_reviews(product_id, user_id, rating, title, body, status) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	var reviewId string
+	err := db.QueryRowx(SQL, productId, userId, review.Rating, review.Title, review.Body, model.ReviewStatusPending).Scan(&reviewId)
+	return reviewId, err
+}
+
+const reviewColumns = `r.id,
+       r.product_id,
+       r.user_id,
+       u.name AS user_name,
+       r.rating,
+       r.title,
+       r.body,
+       r.status,
+       r.created_at`
+
+func GetProductReviews(productId string, status model.ReviewStatus) ([]model.Review, error) {
+	SQL := `SELECT ` + reviewColumns + `
+FROM product_reviews r INNER JOIN users u ON r.user_id = u.id
+WHERE r.product_id = $1 AND r.status = $2 AND r.archived_at IS NULL
+ORDER BY r.created_at DESC`
+	list := make([]model.Review, 0)
+	err := database.Audiophile.Select(&list, SQL, productId, status)
+	return list, err
+}
+
+// GetReviewsByStatus lists reviews for moderation, all of them when status is empty
+func GetReviewsByStatus(status model.ReviewStatus) ([]model.Review, error) {
+	SQL := `SELECT ` + reviewColumns + `
+FROM product_reviews r INNER JOIN users u ON r.user_id = u.id
+WHERE ($1 = '' OR r.status::text = $1) AND r.archived_at IS NULL
+ORDER BY r.created_at`
+	list := make([]model.Review, 0)
+	err := database.Audiophile.Select(&list, SQL, status)
+	return list, err
+}
+
+func UpdateReviewStatus(db sqlx.Ext, reviewId string, status model.ReviewStatus) (bool, error) {
+	SQL := `UPDATE product_reviews SET status = $2, updated_at = Now() WHERE id = $1 AND archived_at IS NULL`
+	result, err := db.Exec(SQL, reviewId, status)
+	if err != nil {
+		return false, err
+	}
+	count, err := result.RowsAffected()
+	return count > 0, err
+}
@@ -0,0 +1,61 @@
+package dbHelper
+
+import (
+	"audio_phile/database"
+	"audio_phile/model"
+	"github.com/jmoiron/sqlx"
+)
+
+const shippingRateColumns = `id, name, min_distance_km, max_distance_km, min_weight_grams, max_weight_grams, base_fee, per_kg_fee`
+
+// GetShippingRates returns the live rates, the most specific band first: the farthest distance band start,
+// then the heaviest weight band start
+func GetShippingRates(db sqlx.Queryer) ([]model.ShippingRate, error) {
+	SQL := `SELECT ` + shippingRateColumns + `
+			FROM shipping_rates
+			WHERE archived_at IS NULL
+			ORDER BY min_distance_km DESC, min_weight_grams DESC, created_at`
+	list := make([]model.ShippingRate, 0)
+	err := sqlx.Select(db, &list, SQL)
+	return list, err
+}
+
+func CreateShippingRate(body model.ShippingRateRequest) (string, error) {
+	SQL := `INSERT INTO shipping_rates(name, min_distance_km, max_distance_km, min_weight_grams, max_weight_grams, base_fee, per_kg_fee)
+			VALUES (TRIM($1), $2, $3, $4, $5, $6, $7) RETURNING id`
+	var rateId string
+	err := database.Audiophile.QueryRowx(SQL, body.Name, body.MinDistanceKm, body.MaxDistanceKm, body.MinWeightGrams, body.MaxWeightGrams,
+		body.BaseFee, body.PerKgFee).Scan(&rateId)
+	return rateId, err
+}
+
+func ArchiveShippingRate(rateId string) (bool, error) {
+	SQL := `UPDATE shipping_rates SET archived_at = Now() WHERE id::text = $1 AND archived_at IS NULL`
+	result, err := database.Audiophile.Exec(SQL, rateId)
+	if err != nil {
+		return false, err
+	}
+	count, err := result.RowsAffected()
+	return count > 0, err
+}
+
+// GetShippingOrigins returns the live warehouses that have a location, a warehouse at 0, 0 was never placed
+func GetShippingOrigins(db sqlx.Queryer) ([]model.Warehouse, error) {
+	SQL := `SELECT id, code, name, address, lat, long, is_default
+			FROM warehouses
+			WHERE archived_at IS NULL AND (lat <> 0 OR long <> 0)
+			ORDER BY code`
+	list := make([]model.Warehouse, 0)
+	err := sqlx.Select(db, &list, SQL)
+	return list, err
+}
+
+func UpdateProductWeight(productId string, weightGrams int) (bool, error) {
+	SQL := `UPDATE products SET weight_grams = $2 WHERE id::text = $1 AND archived_at IS NULL`
+	result, err := database.Audiophile.Exec(SQL, productId, weightGrams)
+	if err != nil {
+		return false, err
+	}
+	count, err := result.RowsAffected()
+	return count > 0, err
+}
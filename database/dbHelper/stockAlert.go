@@ -0,0 +1,105 @@
+package dbHelper
+
+import (
+	"audio_phile/database"
+	"audio_phile/model"
+	"github.com/jmoiron/sqlx"
+)
+
+// GetStockState reads what is needed to decide availability and low-stock alerts for a product
+func GetStockState(db sqlx.Queryer, productId string) (model.StockState, error) {
+	SQL := `SELECT p.id,
+       			   p.name,
+       			   p.is_available,
+       			   p.quantity AS on_hand,
+       			   ` + availableQuantitySQL + ` AS sellable,
+       			   p.reorder_threshold,
+       			   p.low_stock_alerted
+			FROM products p
+			WHERE p.id = $1`
+	var state model.StockState
+	err := sqlx.Get(db, &state, SQL, productId)
+	return state, err
+}
+
+func UpdateStockFlags(db sqlx.Ext, productId string, isAvailable, lowStockAlerted bool) error {
+	SQL := `UPDATE products SET is_available = $2, low_stock_alerted = $3, update_at = Now() WHERE id = $1`
+	_, err := db.Exec(SQL, productId, isAvailable, lowStockAlerted)
+	return err
+}
+
+func UpdateReorderThreshold(db sqlx.Ext, productId string, threshold int) (bool, error) {
+	SQL := `UPDATE products SET reorder_threshold = $2, update_at = Now() WHERE id = $1 AND archived_at IS NULL`
+	result, err := db.Exec(SQL, productId, threshold)
+	if err != nil {
+		return false, err
+	}
+	count, err := result.RowsAffected()
+	return count > 0, err
+}
+
+func CreateStockAlert(db sqlx.Ext, productId string, kind model.StockAlertKind, sellable, threshold int) error {
+	SQL := `INSERT INTO stock_alerts(product_id, kind, sellable_quantity, threshold) VALUES ($1, $2, $3, $4)`
+	_, err := db.Exec(SQL, productId, kind, sellable, threshold)
+	return err
+}
+
+const stockAlertColumns = `a.id,
+       a.product_id,
+       p.name AS product_name,
+       a.kind,
+       a.sellable_quantity,
+       a.threshold,
+       a.attempts,
+       a.last_error,
+       a.created_at,
+       a.delivered_at`
+
+// GetPendingStockAlerts returns undelivered alerts that have not used up their delivery attempts, oldest first
+func GetPendingStockAlerts(limit, maxAttempts int) ([]model.StockAlert, error) {
+	SQL := `SELECT ` + stockAlertColumns + `
+			FROM stock_alerts a INNER JOIN products p ON a.product_id = p.id
+			WHERE a.delivered_at IS NULL AND a.attempts < $2
+			ORDER BY a.created_at
+			LIMIT $1`
+	list := make([]model.StockAlert, 0)
+	err := database.Audiophile.Select(&list, SQL, limit, maxAttempts)
+	return list, err
+}
+
+func GetStockAlerts(pendingOnly bool, limit int) ([]model.StockAlert, error) {
+	SQL := `SELECT ` + stockAlertColumns + `
+			FROM stock_alerts a INNER JOIN products p ON a.product_id = p.id
+			WHERE NOT $1 OR a.delivered_at IS NULL
+			ORDER BY a.created_at DESC
+			LIMIT $2`
+	list := make([]model.StockAlert, 0)
+	err := database.Audiophile.Select(&list, SQL, pendingOnly, limit)
+	return list, err
+}
+
+func MarkStockAlertDelivered(alertId string) error {
+	SQL := `UPDATE stock_alerts SET delivered_at = Now(), attempts = attempts + 1, last_error = NULL WHERE id = $1`
+	_, err := database.Audiophile.Exec(SQL, alertId)
+	return err
+}
+
+func MarkStockAlertFailed(alertId, message string) error {
+	SQL := `UPDATE stock_alerts SET attempts = attempts + 1, last_error = $2 WHERE id = $1`
+	_, err := database.Audiophile.Exec(SQL, alertId, message)
+	return err
+}
+
+// GetStockAlertChannels returns the channels an alert was already delivered to
+func GetStockAlertChannels(alertId string) ([]string, error) {
+	SQL := `SELECT channel FROM stock_alert_deliveries WHERE alert_id = $1`
+	list := make([]string, 0)
+	err := database.Audiophile.Select(&list, SQL, alertId)
+	return list, err
+}
+
+func CreateStockAlertDelivery(alertId, channel string) error {
+	SQL := `INSERT INTO stock_alert_deliveries(alert_id, channel) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	_, err := database.Audiophile.Exec(SQL, alertId, channel)
+	return err
+}
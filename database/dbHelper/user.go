@@ -109,24 +109,61 @@ func IsProductExist(name string) (bool, error) {
 	return true, nil
 }
 
-func GetAllProduct() ([]model.Products, error) {
-	SQL := `SELECT id, 
-       			   name, 
-       			   price, 
-       			   description, 
-       			   is_available,
-       			   quantity,
-       			   category
+func GetAllProduct(filter model.ProductFilter) ([]model.Products, error) {
+	SQL := `SELECT p.id, 
+       			   COALESCE(p.sku, '') AS sku,
+       			   p.name, 
+       			   p.price, 
+       			   p.description, 
+       			   p.is_available,
+       			   p.quantity,
+       			   p.category,
+       			   ` + availableQuantitySQL + ` AS available_quantity,
+       			   COALESCE(r.average_rating, 0) AS average_rating,
+       			   COALESCE(r.review_count, 0) AS review_count
 FROM 
-       			            products WHERE archived_at is null `
+       			            products p LEFT JOIN (` + approvedRatingSQL + `) r ON r.product_id = p.id
+WHERE p.archived_at is null AND COALESCE(r.average_rating, 0) >= $1
+ORDER BY ` + productOrderBy(filter)
 
 	list := make([]model.Products, 0)
-	err := database.Audiophile.Select(&list, SQL)
+	err := database.Audiophile.Select(&list, SQL, filter.MinRating)
 	return list, err
 }
 
+// availableQuantitySQL derives the sellable quantity of product p: stock on hand minus unexpired reservations
+const availableQuantitySQL = `p.quantity - COALESCE((SELECT SUM(sr.quantity)
+                      FROM stock_reservations sr
+                      WHERE sr.product_id = p.id AND sr.status = 'active' AND sr.expires_at > Now()), 0)`
+
+// approvedRatingSQL aggregates the approved reviews of every product
+const approvedRatingSQL = `SELECT product_id,
+       ROUND(AVG(rating), 2)::float AS average_rating,
+       COUNT(*)                     AS review_count
+FROM product_reviews
+WHERE status = 'approved' AND archived_at IS NULL
+GROUP BY product_id`
+
+func productOrderBy(filter model.ProductFilter) string {
+	direction := "ASC"
+	if filter.Desc {
+		direction = "DESC"
+	}
+	switch filter.SortBy {
+	case model.ProductSortPrice:
+		return "p.price " + direction + ", p.name"
+	case model.ProductSortRating:
+		return "average_rating " + direction + ", review_count DESC, p.name"
+	case model.ProductSortReviews:
+		return "review_count " + direction + ", p.name"
+	}
+	return "p.name " + direction
+}
+
 func GetProductById(productId string) (model.Products, error) {
-	SQL := `SELECT id,name,price, description,is_available, quantity, category FROM products WHERE id = $1 AND archived_at is null`
+	SQL := `SELECT p.id, COALESCE(p.sku, '') AS sku, p.name, p.price, p.description, p.is_available, p.quantity, p.category,
+       ` + availableQuantitySQL + ` AS available_quantity, COALESCE(r.average_rating, 0) AS average_rating, COALESCE(r.review_count, 0) AS review_count
+FROM products p LEFT JOIN (` + approvedRatingSQL + `) r ON r.product_id = p.id WHERE p.id = $1 AND p.archived_at is null`
 	var productModel model.Products
 	err := database.Audiophile.Get(&productModel, SQL, productId)
 	return productModel, err
@@ -140,15 +177,16 @@ func GetUserByUserId(userId string) (model.User, error) {
 }
 
 func GetAddress(db *sqlx.DB, userId string) ([]model.AddressModel, error) {
-	SQL := `SELECT id, address, address_type, lat, long FROM user_addresses WHERE user_id = $1 AND archived_at is null `
+	SQL := `SELECT id, address, address_type, region, COALESCE(postal_code, '') AS postal_code, lat, long FROM user_addresses WHERE user_id = $1 AND archived_at is null `
 	list := make([]model.AddressModel, 0)
 	err := db.Select(&list, SQL, userId)
 	return list, err
 }
 
-func CreateAddresses(db sqlx.Ext, userId, address string, addressType model.Address, lat, long float64) error {
-	SQL := `INSERT INTO user_addresses(user_id, address, address_type, lat, long) VALUES ($1, $2, $3, $4, $5)`
-	_, err := db.Exec(SQL, userId, address, addressType, lat, long)
+func CreateAddresses(db sqlx.Ext, userId, address string, addressType model.Address, region, postalCode string, lat, long float64) error {
+	SQL := `INSERT INTO user_addresses(user_id, address, address_type, region, postal_code, lat, long)
+			VALUES ($1, $2, $3, UPPER(TRIM($4)), NULLIF(UPPER(REPLACE($5, ' ', '')), ''), $6, $7)`
+	_, err := db.Exec(SQL, userId, address, addressType, region, postalCode, lat, long)
 	return err
 }
 
@@ -165,24 +203,13 @@ func CreateCart(db sqlx.Ext, userId string, status model.Status) (string, error)
 	return cartId, err
 }
 
-func CreateProductInCart(db sqlx.Ext, cartId, productId string, quantity int) error {
-	SQL := `INSERT INTO cart_products(cart_id, product_id, quantity) VALUES ($1, $2, $3)`
+// UpsertCartProduct sets the quantity of the product's single live line in the cart, creating it if needed
+func UpsertCartProduct(db sqlx.Ext, cartId, productId string, quantity int) error {
+	SQL := `INSERT INTO cart_products(cart_id, product_id, quantity) VALUES ($1, $2, $3)
+			ON CONFLICT (cart_id, product_id) WHERE archived_at IS NULL
+			DO UPDATE SET quantity = EXCLUDED.quantity, updated_at = Now()`
 	_, err := db.Exec(SQL, cartId, productId, quantity)
 	return err
-
-}
-
-func IsCartExist(userId string, cartStatus model.Status) (string, bool, error) {
-	SQL := `SELECT id FROM carts WHERE user_id = $1 AND status = $2`
-	var id string
-	err := database.Audiophile.Get(&id, SQL, userId, cartStatus)
-	if err != nil && err != sql.ErrNoRows {
-		return "", false, err
-	}
-	if err == sql.ErrNoRows {
-		return "", false, nil
-	}
-	return id, true, nil
 }
 
 //func GetCartWithProduct(db *sqlx.DB, cartId string) ([]model.CartProduct, error) {
@@ -192,54 +219,58 @@ func IsCartExist(userId string, cartStatus model.Status) (string, bool, error) {
 //	return list, err
 //}
 
-func GetCartWithProduct(db *sqlx.DB, userId string) ([]model.CartProduct, error) {
+func GetCartWithProduct(db sqlx.Queryer, cartId string) ([]model.CartProduct, error) {
 	SQL := `SELECT p.id,
        p.name,
        p.price,
        p.description,
        cp.quantity
-FROM products p INNER JOIN cart_products cp ON p.id = cp.product_id INNER JOIN carts c on cp.cart_id = c.id WHERE user_id = $1 AND cp.archived_at IS NULL `
+FROM products p INNER JOIN cart_products cp ON p.id = cp.product_id WHERE cp.cart_id = $1 AND cp.archived_at IS NULL ORDER BY cp.created_at`
 	list := make([]model.CartProduct, 0)
-	err := db.Select(&list, SQL, userId)
+	err := sqlx.Select(db, &list, SQL, cartId)
 	return list, err
 }
 
-func GetCartProductQuantity(cartId string, productId string) (model.QuantityOfProductInCart, error) {
-	SQL := `SELECT cart_id, product_id, quantity FROM cart_products WHERE cart_id = $1 AND product_id = $2`
-	var cartDetail model.QuantityOfProductInCart
-	err := database.Audiophile.Get(&cartDetail, SQL, cartId, productId)
-	return cartDetail, err
-
+func DeleteProductFromCart(db sqlx.Ext, cartId, productId string) (bool, error) {
+	SQL := `UPDATE cart_products SET archived_at = Now(), updated_at = Now() WHERE cart_id = $1 AND product_id = $2 AND archived_at IS NULL`
+	result, err := db.Exec(SQL, cartId, productId)
+	if err != nil {
+		return false, err
+	}
+	count, err := result.RowsAffected()
+	return count > 0, err
 }
 
-func AddProductQuantityInCart(cartId, productId string) error {
-	SQL := `UPDATE cart_products SET quantity = cart_products.quantity + 1 WHERE cart_id = $1 AND product_id = $2`
-	_, err := database.Audiophile.Exec(SQL, cartId, productId)
+// TouchCart records activity on the cart, which also makes it eligible for a new abandonment reminder
+func TouchCart(db sqlx.Ext, cartId string) error {
+	SQL := `UPDATE carts SET update_at = Now(), reminded_at = NULL WHERE id = $1`
+	_, err := db.Exec(SQL, cartId)
 	return err
 }
 
-func RemoveProductQuantityInCart(cartId, productId string) error {
-	SQL := `UPDATE cart_products SET quantity = cart_products.quantity - 1 WHERE cart_id = $1 AND product_id = $2`
-	_, err := database.Audiophile.Exec(SQL, cartId, productId)
-	return err
-
+// LockUser serialises work on the user's carts, e.g. so that two requests cannot both create an active cart
+func LockUser(tx *sqlx.Tx, userId string) error {
+	SQL := `SELECT id FROM users WHERE id = $1 FOR UPDATE`
+	var id string
+	return tx.Get(&id, SQL, userId)
 }
 
-func DeleteProductFromCart(cartId, productId string) error {
-	SQL := `UPDATE cart_products SET archived_at = Now() WHERE cart_id = $1 AND product_id = $2`
-	_, err := database.Audiophile.Exec(SQL, cartId, productId)
-	return err
+func GetActiveCartId(db sqlx.Queryer, userId string) (string, bool, error) {
+	SQL := `SELECT id FROM carts WHERE user_id = $1 AND status = 'active' ORDER BY created_at DESC LIMIT 1`
+	var id string
+	err := sqlx.Get(db, &id, SQL, userId)
+	if err != nil && err != sql.ErrNoRows {
+		return "", false, err
+	}
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	return id, true, nil
 }
 
-func CreateOrder(cartProductId string) (string, error) {
+func CreateOrder(db sqlx.Ext, cartProductId string) (string, error) {
 	SQL := `INSERT INTO orders(cart_id) VALUES ($1) RETURNING id`
 	var orderId string
-	err := database.Audiophile.QueryRowx(SQL, cartProductId).Scan(&orderId)
+	err := db.QueryRowx(SQL, cartProductId).Scan(&orderId)
 	return orderId, err
 }
-
-func UpdateProductQuantity(productId string, quantity int) error {
-	SQL := `UPDATE products SET quantity = products.quantity - $1 WHERE id = $2`
-	_, err := database.Audiophile.Exec(SQL, quantity, productId)
-	return err
-}
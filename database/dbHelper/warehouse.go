@@ -0,0 +1,139 @@
+package dbHelper
+
+import (
+	"audio_phile/database"
+	"audio_phile/model"
+	"github.com/jmoiron/sqlx"
+)
+
+func CreateWarehouse(db sqlx.Ext, warehouse model.WarehouseRequest) (string, error) {
+	SQL := `INSERT INTO warehouses(code, name, address, lat, long, is_default) VALUES (UPPER($1), $2, $3, $4, $5, $6) RETURNING id`
+	var warehouseId string
+	err := db.QueryRowx(SQL, warehouse.Code, warehouse.Name, warehouse.Address, warehouse.Lat, warehouse.Long, warehouse.IsDefault).Scan(&warehouseId)
+	return warehouseId, err
+}
+
+func ClearDefaultWarehouse(db sqlx.Ext) error {
+	SQL := `UPDATE warehouses SET is_default = FALSE WHERE is_default AND archived_at IS NULL`
+	_, err := db.Exec(SQL)
+	return err
+}
+
+func IsWarehouseCodeExist(code string) (bool, error) {
+	SQL := `SELECT count(*) > 0 FROM warehouses WHERE code = UPPER($1) AND archived_at IS NULL`
+	var exist bool
+	err := database.Audiophile.Get(&exist, SQL, code)
+	return exist, err
+}
+
+func GetWarehouses() ([]model.Warehouse, error) {
+	SQL := `SELECT id, code, name, address, lat, long, is_default FROM warehouses WHERE archived_at IS NULL ORDER BY code`
+	list := make([]model.Warehouse, 0)
+	err := database.Audiophile.Select(&list, SQL)
+	return list, err
+}
+
+func GetWarehouseById(db sqlx.Queryer, warehouseId string) (model.Warehouse, error) {
+	SQL := `SELECT id, code, name, address, lat, long, is_default FROM warehouses WHERE id = $1 AND archived_at IS NULL`
+	var warehouse model.Warehouse
+	err := sqlx.Get(db, &warehouse, SQL, warehouseId)
+	return warehouse, err
+}
+
+func GetDefaultWarehouseId(db sqlx.Queryer) (string, error) {
+	SQL := `SELECT id FROM warehouses WHERE is_default AND archived_at IS NULL`
+	var warehouseId string
+	err := sqlx.Get(db, &warehouseId, SQL)
+	return warehouseId, err
+}
+
+// GetStockLevels lists per-warehouse stock, optionally narrowed to one product and/or one warehouse
+func GetStockLevels(productId, warehouseId string) ([]model.WarehouseStock, error) {
+	SQL := `SELECT ws.warehouse_id,
+       			   w.code AS warehouse_code,
+       			   ws.product_id,
+       			   p.name AS product_name,
+       			   ws.quantity
+			FROM warehouse_stock ws
+				INNER JOIN warehouses w ON ws.warehouse_id = w.id
+				INNER JOIN products p ON ws.product_id = p.id
+			WHERE ($1 = '' OR ws.product_id::text = $1)
+			  AND ($2 = '' OR ws.warehouse_id::text = $2)
+			  AND p.archived_at IS NULL
+			ORDER BY p.name, w.code`
+	list := make([]model.WarehouseStock, 0)
+	err := database.Audiophile.Select(&list, SQL, productId, warehouseId)
+	return list, err
+}
+
+// GetStockedWarehouses returns the warehouses holding the product, fullest first, locking their stock rows
+func GetStockedWarehouses(tx *sqlx.Tx, productId string) ([]model.WarehouseStock, error) {
+	SQL := `SELECT ws.warehouse_id, w.code AS warehouse_code, ws.product_id, '' AS product_name, ws.quantity
+			FROM warehouse_stock ws INNER JOIN warehouses w ON ws.warehouse_id = w.id
+			WHERE ws.product_id = $1 AND ws.quantity > 0 AND w.archived_at IS NULL
+			ORDER BY w.is_default DESC, ws.quantity DESC
+			FOR UPDATE OF ws`
+	list := make([]model.WarehouseStock, 0)
+	err := tx.Select(&list, SQL, productId)
+	return list, err
+}
+
+// ApplyWarehouseStockDelta moves the stock of a product in a warehouse by delta, refusing to go below zero
+func ApplyWarehouseStockDelta(db sqlx.Ext, warehouseId, productId string, delta int) (bool, error) {
+	SQL := `INSERT INTO warehouse_stock(warehouse_id, product_id, quantity)
+			SELECT $1::uuid, $2::uuid, $3::int
+			WHERE $3::int >= 0
+			ON CONFLICT (warehouse_id, product_id) DO UPDATE
+				SET quantity   = warehouse_stock.quantity + $3::int,
+				    updated_at = Now()
+				WHERE warehouse_stock.quantity + $3::int >= 0`
+	result, err := db.Exec(SQL, warehouseId, productId, delta)
+	if err != nil {
+		return false, err
+	}
+	count, err := result.RowsAffected()
+	return count > 0, err
+}
+
+// ApplyProductQuantityDelta keeps products.quantity equal to the sum over warehouses
+func ApplyProductQuantityDelta(db sqlx.Ext, productId string, delta int) (bool, error) {
+	SQL := `UPDATE products
+			SET quantity  = products.quantity + $2,
+			    update_at = Now()
+			WHERE id = $1 AND products.quantity + $2 >= 0`
+	result, err := db.Exec(SQL, productId, delta)
+	if err != nil {
+		return false, err
+	}
+	count, err := result.RowsAffected()
+	return count > 0, err
+}
+
+func CreateStockMovement(db sqlx.Ext, movement model.StockMovement) (model.StockMovement, error) {
+	SQL := `INSERT INTO stock_movements(product_id, warehouse_id, movement_type, quantity, reason, actor_id, reference_id, correlation_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING id, product_id, warehouse_id, movement_type, quantity, reason, actor_id, reference_id, correlation_id, created_at`
+	var created model.StockMovement
+	err := sqlx.Get(db, &created, SQL, movement.ProductId, movement.WarehouseId, movement.Type, movement.Quantity,
+		movement.Reason, movement.ActorId, movement.ReferenceId, movement.CorrelationId)
+	return created, err
+}
+
+func GetStockMovements(filter model.StockMovementFilter) ([]model.StockMovement, error) {
+	SQL := `SELECT id, product_id, warehouse_id, movement_type, quantity, reason, actor_id, reference_id, correlation_id, created_at
+			FROM stock_movements
+			WHERE ($1 = '' OR product_id::text = $1)
+			  AND ($2 = '' OR warehouse_id::text = $2)
+			  AND ($3 = '' OR movement_type::text = $3)
+			ORDER BY created_at DESC
+			LIMIT $4`
+	list := make([]model.StockMovement, 0)
+	err := database.Audiophile.Select(&list, SQL, filter.ProductId, filter.WarehouseId, filter.Type, filter.Limit)
+	return list, err
+}
+
+func GenerateUUID(db sqlx.Queryer) (string, error) {
+	var id string
+	err := sqlx.Get(db, &id, `SELECT gen_random_uuid()`)
+	return id, err
+}
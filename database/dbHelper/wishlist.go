@@ -0,0 +1,141 @@
+package dbHelper
+
+import (
+	"audio_phile/model"
+	"database/sql"
+	"github.com/jmoiron/sqlx"
+)
+
+const wishlistColumns = `w.id,
+       w.name,
+       w.share_token,
+       (SELECT count(*) FROM wishlist_items wi WHERE wi.wishlist_id = w.id AND wi.archived_at IS NULL) AS item_count,
+       w.created_at`
+
+func CreateWishlist(db sqlx.Ext, userId, name string) (string, error) {
+	SQL := `INSERT INTO wishlists(user_id, name) VALUES ($1, TRIM($2)) RETURNING id`
+	var wishlistId string
+	err := db.QueryRowx(SQL, userId, name).Scan(&wishlistId)
+	return wishlistId, err
+}
+
+func IsWishlistNameTaken(db sqlx.Queryer, userId, name string) (bool, error) {
+	SQL := `SELECT count(*) > 0 FROM wishlists WHERE user_id = $1 AND LOWER(name) = LOWER(TRIM($2)) AND archived_at IS NULL`
+	var taken bool
+	err := sqlx.Get(db, &taken, SQL, userId, name)
+	return taken, err
+}
+
+// GetWishlistIdByName returns the user's list with that name, ignoring case
+func GetWishlistIdByName(db sqlx.Queryer, userId, name string) (string, bool, error) {
+	SQL := `SELECT id FROM wishlists WHERE user_id = $1 AND LOWER(name) = LOWER($2) AND archived_at IS NULL`
+	var wishlistId string
+	err := sqlx.Get(db, &wishlistId, SQL, userId, name)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	return wishlistId, err == nil, err
+}
+
+func GetWishlists(db sqlx.Queryer, userId string) ([]model.Wishlist, error) {
+	SQL := `SELECT ` + wishlistColumns + ` FROM wishlists w WHERE w.user_id = $1 AND w.archived_at IS NULL ORDER BY w.created_at`
+	list := make([]model.Wishlist, 0)
+	err := sqlx.Select(db, &list, SQL, userId)
+	return list, err
+}
+
+// GetWishlist returns one of the user's lists, sql.ErrNoRows when it is not theirs
+func GetWishlist(db sqlx.Queryer, userId, wishlistId string) (model.Wishlist, error) {
+	SQL := `SELECT ` + wishlistColumns + ` FROM wishlists w WHERE w.id::text = $2 AND w.user_id = $1 AND w.archived_at IS NULL`
+	var wishlist model.Wishlist
+	err := sqlx.Get(db, &wishlist, SQL, userId, wishlistId)
+	return wishlist, err
+}
+
+func GetWishlistByShareToken(db sqlx.Queryer, shareToken string) (model.Wishlist, error) {
+	SQL := `SELECT ` + wishlistColumns + ` FROM wishlists w WHERE w.share_token = $1 AND w.archived_at IS NULL`
+	var wishlist model.Wishlist
+	err := sqlx.Get(db, &wishlist, SQL, shareToken)
+	return wishlist, err
+}
+
+func ArchiveWishlist(db sqlx.Ext, userId, wishlistId string) (bool, error) {
+	SQL := `UPDATE wishlists SET archived_at = Now(), share_token = NULL WHERE id::text = $2 AND user_id = $1 AND archived_at IS NULL`
+	result, err := db.Exec(SQL, userId, wishlistId)
+	if err != nil {
+		return false, err
+	}
+	count, err := result.RowsAffected()
+	return count > 0, err
+}
+
+// SetWishlistShareToken shares a list under token, or stops sharing it when token is nil
+func SetWishlistShareToken(db sqlx.Ext, wishlistId string, token *string) error {
+	SQL := `UPDATE wishlists SET share_token = $2, updated_at = Now() WHERE id = $1`
+	_, err := db.Exec(SQL, wishlistId, token)
+	return err
+}
+
+func GetWishlistItems(db sqlx.Queryer, wishlistId string) ([]model.WishlistItem, error) {
+	SQL := `SELECT wi.product_id,
+       			   p.name,
+       			   wi.quantity,
+       			   wi.price_at_add,
+       			   p.price,
+       			   p.price < wi.price_at_add AS price_dropped,
+       			   p.is_available AND p.archived_at IS NULL AS is_available,
+       			   wi.created_at
+			FROM wishlist_items wi INNER JOIN products p ON wi.product_id = p.id
+			WHERE wi.wishlist_id = $1 AND wi.archived_at IS NULL
+			ORDER BY wi.created_at DESC`
+	list := make([]model.WishlistItem, 0)
+	err := sqlx.Select(db, &list, SQL, wishlistId)
+	return list, err
+}
+
+func GetWishlistItemQuantity(db sqlx.Queryer, wishlistId, productId string) (int, error) {
+	SQL := `SELECT quantity FROM wishlist_items WHERE wishlist_id = $1 AND product_id::text = $2 AND archived_at IS NULL`
+	var quantity int
+	err := sqlx.Get(db, &quantity, SQL, wishlistId, productId)
+	return quantity, err
+}
+
+// UpsertWishlistItem saves a product with its current price, saving it again only changes the quantity
+// so that a price drop since it was first saved stays visible
+func UpsertWishlistItem(db sqlx.Ext, wishlistId, productId string, quantity int) (bool, error) {
+	SQL := `INSERT INTO wishlist_items(wishlist_id, product_id, quantity, price_at_add)
+			SELECT $1::uuid, p.id, $3::int, p.price FROM products p WHERE p.id::text = $2 AND p.archived_at IS NULL
+			ON CONFLICT (wishlist_id, product_id) WHERE archived_at IS NULL
+			DO UPDATE SET quantity = EXCLUDED.quantity, updated_at = Now()`
+	result, err := db.Exec(SQL, wishlistId, productId, quantity)
+	if err != nil {
+		return false, err
+	}
+	count, err := result.RowsAffected()
+	return count > 0, err
+}
+
+// AddWishlistItem saves a product with its current price like UpsertWishlistItem, but a product already on
+// the list has quantity added to what was saved instead of replaced
+func AddWishlistItem(db sqlx.Ext, wishlistId, productId string, quantity int) (bool, error) {
+	SQL := `INSERT INTO wishlist_items(wishlist_id, product_id, quantity, price_at_add)
+			SELECT $1::uuid, p.id, $3::int, p.price FROM products p WHERE p.id::text = $2 AND p.archived_at IS NULL
+			ON CONFLICT (wishlist_id, product_id) WHERE archived_at IS NULL
+			DO UPDATE SET quantity = wishlist_items.quantity + EXCLUDED.quantity, updated_at = Now()`
+	result, err := db.Exec(SQL, wishlistId, productId, quantity)
+	if err != nil {
+		return false, err
+	}
+	count, err := result.RowsAffected()
+	return count > 0, err
+}
+
+func DeleteWishlistItem(db sqlx.Ext, wishlistId, productId string) (bool, error) {
+	SQL := `UPDATE wishlist_items SET archived_at = Now(), updated_at = Now() WHERE wishlist_id = $1 AND product_id::text = $2 AND archived_at IS NULL`
+	result, err := db.Exec(SQL, wishlistId, productId)
+	if err != nil {
+		return false, err
+	}
+	count, err := result.RowsAffected()
+	return count > 0, err
+}
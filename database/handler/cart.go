@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"audio_phile/cart"
+	"audio_phile/database"
+	"audio_phile/inventory"
+	"audio_phile/model"
+	"audio_phile/utils"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+	"net/http"
+)
+
+// SetCartItem handles PUT /cart/items/{productId} with {"quantity": n}. The line ends up with exactly
+// n units whatever it held before, and n = 0 removes it. The updated cart is returned.
+func SetCartItem(w http.ResponseWriter, r *http.Request) {
+	var body model.CartQuantityRequest
+	if err := utils.ParseBody(r.Body, &body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
+		return
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return
+	}
+	setCartItem(w, r, *body.Quantity)
+}
+
+// RemoveCartItem handles DELETE /cart/items/{productId}
+func RemoveCartItem(w http.ResponseWriter, r *http.Request) {
+	setCartItem(w, r, 0)
+}
+
+func setCartItem(w http.ResponseWriter, r *http.Request, quantity int) {
+	productId, ok := productIdParam(w, r)
+	if !ok {
+		return
+	}
+	userId := getUserId(r)
+
+	var updated model.Cart
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		cartId, _, err := cart.ActiveCartId(tx, userId, quantity > 0)
+		if err != nil {
+			return err
+		}
+		if cartId == "" {
+			updated = model.Cart{Items: make([]model.CartProduct, 0)}
+			return nil
+		}
+		if err := cart.SetQuantity(tx, cartId, productId, quantity); err != nil {
+			return err
+		}
+		if updated, err = cart.Get(tx, cartId); err != nil {
+			return err
+		}
+		return priceCart(tx, r, userId, &updated)
+	})
+	if txErr != nil {
+		respondCartError(w, txErr)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, updated)
+}
+
+// productIdParam reads the productId of the route, answering 400 when it is not a uuid instead of letting
+// the database reject it
+func productIdParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	return uuidParam(w, r, "productId", "invalid product id")
+}
+
+// uuidParam reads the route parameter name, answering 400 with message when it is not a uuid
+func uuidParam(w http.ResponseWriter, r *http.Request, name, message string) (string, bool) {
+	value := chi.URLParam(r, name)
+	if err := validator.New().Var(value, "uuid"); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, message)
+		return "", false
+	}
+	return value, true
+}
+
+func respondCartError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, cart.ErrProductNotFound):
+		utils.RespondError(w, http.StatusNotFound, err, "Product not found!")
+	case errors.Is(err, errAddressNotFound):
+		utils.RespondError(w, http.StatusNotFound, err, "Address not found!")
+	case inventory.IsInsufficientStock(err):
+		utils.RespondError(w, http.StatusConflict, err, "Requested quantity not available")
+	default:
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to update cart")
+	}
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProductIdParam(t *testing.T) {
+	tests := []struct {
+		name      string
+		productId string
+		ok        bool
+	}{
+		{"uuid", "5b6f3c1e-8a2d-4e7f-9c10-2d3e4f5a6b7c", true},
+		{"not a uuid", "headphones", false},
+		{"uuid with a suffix", "5b6f3c1e-8a2d-4e7f-9c10-2d3e4f5a6b7c1", false},
+		{"sql", "1' OR '1'='1", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var productId string
+			var ok bool
+			router := chi.NewRouter()
+			router.Get("/items/{productId}", func(w http.ResponseWriter, r *http.Request) {
+				productId, ok = productIdParam(w, r)
+			})
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/items/"+strings.ReplaceAll(test.productId, " ", "%20"), nil))
+			if ok != test.ok {
+				t.Fatalf("expected ok %v, got %v", test.ok, ok)
+			}
+			if test.ok && productId != test.productId {
+				t.Fatalf("expected product id %s, got %s", test.productId, productId)
+			}
+			if !test.ok && recorder.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d", recorder.Code)
+			}
+		})
+	}
+}
+
+// TestCartItemRejectsInvalidProductId checks that an invalid product id is answered before anything
+// reaches the database
+func TestCartItemRejectsInvalidProductId(t *testing.T) {
+	router := chi.NewRouter()
+	router.Put("/cart/items/{productId}", SetCartItem)
+	router.Delete("/cart/items/{productId}", RemoveCartItem)
+	router.Put("/guest/cart/items/{productId}", SetGuestCartItem)
+	router.Delete("/guest/cart/items/{productId}", RemoveGuestCartItem)
+	router.Post("/cart/items/{productId}/save-for-later", SaveCartItemForLater)
+	router.Put("/wishlist/{id}/items/{productId}", SetWishlistItem)
+	router.Delete("/wishlist/{id}/items/{productId}", RemoveWishlistItem)
+	router.Post("/wishlist/{id}/items/{productId}/move-to-cart", MoveWishlistItemToCart)
+
+	tests := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodPut, "/cart/items/not-a-uuid"},
+		{http.MethodDelete, "/cart/items/not-a-uuid"},
+		{http.MethodPut, "/guest/cart/items/not-a-uuid"},
+		{http.MethodDelete, "/guest/cart/items/not-a-uuid"},
+		{http.MethodPost, "/cart/items/not-a-uuid/save-for-later"},
+		{http.MethodPut, "/wishlist/5b6f3c1e-8a2d-4e7f-9c10-2d3e4f5a6b7c/items/not-a-uuid"},
+		{http.MethodDelete, "/wishlist/5b6f3c1e-8a2d-4e7f-9c10-2d3e4f5a6b7c/items/not-a-uuid"},
+		{http.MethodPost, "/wishlist/5b6f3c1e-8a2d-4e7f-9c10-2d3e4f5a6b7c/items/not-a-uuid/move-to-cart"},
+	}
+	for _, test := range tests {
+		t.Run(test.method+" "+test.path, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(test.method, test.path, strings.NewReader(`{"quantity": 1}`)))
+			if recorder.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d: %s", recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+}
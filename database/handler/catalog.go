@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"audio_phile/catalog"
+	"audio_phile/model"
+	"audio_phile/utils"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxImportSize caps the size of an uploaded catalog file
+const maxImportSize = 10 << 20
+
+func ImportProducts(w http.ResponseWriter, r *http.Request) {
+	format, err := catalog.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "unsupported import format")
+		return
+	}
+
+	dryRun := false
+	if value := r.URL.Query().Get("dryRun"); value != "" {
+		dryRun, err = strconv.ParseBool(value)
+		if err != nil {
+			utils.RespondError(w, http.StatusBadRequest, err, "dryRun must be true or false")
+			return
+		}
+	}
+
+	report, err := catalog.Import(http.MaxBytesReader(w, r.Body, maxImportSize), format, dryRun, getUserId(r))
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "Failed to import products")
+		return
+	}
+
+	if report.Failed > 0 {
+		utils.RespondJSON(w, http.StatusUnprocessableEntity, report)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, report)
+}
+
+func ExportProducts(w http.ResponseWriter, r *http.Request) {
+	format, err := catalog.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "unsupported export format")
+		return
+	}
+
+	contentType := "text/csv"
+	if format == model.ImportFormatNDJSON {
+		contentType = "application/x-ndjson"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=products-%s.%s", time.Now().Format("20060102"), format))
+
+	// headers are already sent once streaming starts, so a failure can only be logged
+	if err := catalog.Export(w, format); err != nil {
+		logrus.Errorf("failed to export products with error: %+v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"audio_phile/credit"
+	"audio_phile/database"
+	"audio_phile/database/dbHelper"
+	"audio_phile/model"
+	"audio_phile/utils"
+	"database/sql"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+	"net/http"
+)
+
+// GetMyCredit handles GET /user/credit, the caller's store credit balance and ledger
+func GetMyCredit(w http.ResponseWriter, r *http.Request) {
+	statement, err := credit.Statement(database.Audiophile, getUserId(r))
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get store credit")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, statement)
+}
+
+// RedeemGiftCard handles POST /user/credit/redeem and moves a gift card's balance into the caller's store credit
+func RedeemGiftCard(w http.ResponseWriter, r *http.Request) {
+	var body model.GiftCardRedeemRequest
+	if err := utils.ParseBody(r.Body, &body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
+		return
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return
+	}
+	var redeemed model.Money
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		var err error
+		redeemed, err = credit.RedeemGiftCard(tx, getUserId(r), body.Code)
+		return err
+	})
+	if txErr != nil {
+		respondCreditError(w, txErr, "Failed to redeem gift card")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message  string
+		Redeemed model.Money
+	}{Message: "Gift card redeemed successfully", Redeemed: redeemed})
+}
+
+// GetUserCredit handles GET /admin/credit/{userId}
+func GetUserCredit(w http.ResponseWriter, r *http.Request) {
+	userId := chi.URLParam(r, "userId")
+	if err := validator.New().Var(userId, "uuid"); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "invalid user id")
+		return
+	}
+	statement, err := credit.Statement(database.Audiophile, userId)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get store credit")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, statement)
+}
+
+// AdjustUserCredit handles POST /admin/credit/{userId}, store credit given or taken back by an admin
+func AdjustUserCredit(w http.ResponseWriter, r *http.Request) {
+	userId := chi.URLParam(r, "userId")
+	if err := validator.New().Var(userId, "uuid"); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "invalid user id")
+		return
+	}
+	var body model.CreditEntryRequest
+	if err := utils.ParseBody(r.Body, &body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
+		return
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return
+	}
+	entry, err := credit.Adjust(userId, body, getUserId(r))
+	if err != nil {
+		respondCreditError(w, err, "Failed to adjust store credit")
+		return
+	}
+	utils.RespondJSON(w, http.StatusCreated, entry)
+}
+
+// IssueGiftCard handles POST /admin/gift-card. The code is in this response only.
+func IssueGiftCard(w http.ResponseWriter, r *http.Request) {
+	var body model.GiftCardRequest
+	if err := utils.ParseBody(r.Body, &body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
+		return
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return
+	}
+	card, code, err := credit.IssueGiftCard(body, getUserId(r))
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to issue gift card")
+		return
+	}
+	utils.RespondJSON(w, http.StatusCreated, struct {
+		model.GiftCard
+		Code string `json:"code"`
+	}{GiftCard: card, Code: code})
+}
+
+func GetGiftCards(w http.ResponseWriter, r *http.Request) {
+	list, err := dbHelper.GetGiftCards(database.Audiophile)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get gift cards")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, list)
+}
+
+func GetGiftCard(w http.ResponseWriter, r *http.Request) {
+	detail, err := credit.GiftCardDetail(database.Audiophile, chi.URLParam(r, "id"))
+	if err != nil {
+		respondCreditError(w, err, "Failed to get gift card")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, detail)
+}
+
+func AdjustGiftCard(w http.ResponseWriter, r *http.Request) {
+	var body model.GiftCardAdjustmentRequest
+	if err := utils.ParseBody(r.Body, &body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
+		return
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return
+	}
+	card, err := credit.AdjustGiftCard(chi.URLParam(r, "id"), body, getUserId(r))
+	if err != nil {
+		respondCreditError(w, err, "Failed to adjust gift card")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, card)
+}
+
+func DisableGiftCard(w http.ResponseWriter, r *http.Request) {
+	if err := credit.DisableGiftCard(chi.URLParam(r, "id"), getUserId(r)); err != nil {
+		respondCreditError(w, err, "Failed to disable gift card")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string
+	}{"Gift card disabled successfully"})
+}
+
+func respondCreditError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows), errors.Is(err, credit.ErrGiftCardNotFound):
+		utils.RespondError(w, http.StatusNotFound, err, "Not found!")
+	case errors.Is(err, credit.ErrGiftCardExpired), errors.Is(err, credit.ErrGiftCardDisabled),
+		errors.Is(err, credit.ErrGiftCardEmpty), errors.Is(err, credit.ErrNegativeBalance):
+		utils.RespondError(w, http.StatusConflict, err, err.Error())
+	default:
+		utils.RespondError(w, http.StatusInternalServerError, err, message)
+	}
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"audio_phile/cart"
+	"audio_phile/database"
+	"audio_phile/middleware"
+	"audio_phile/model"
+	"audio_phile/pricing"
+	"audio_phile/shipping"
+	"audio_phile/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// CreateGuestCart handles POST /cart and hands out the token for a new anonymous cart. The token has
+// to be sent back in the X-Cart-Token header on the other guest cart routes and on login or register.
+func CreateGuestCart(w http.ResponseWriter, r *http.Request) {
+	cartId, token, err := cart.NewGuestCart(database.Audiophile)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to create cart")
+		return
+	}
+	utils.RespondJSON(w, http.StatusCreated, struct {
+		CartId string `json:"cartId"`
+		Token  string `json:"token"`
+	}{CartId: cartId, Token: token})
+}
+
+func GetGuestCart(w http.ResponseWriter, r *http.Request) {
+	guestCart, err := getGuestCart(database.Audiophile, r, middleware.CartIdFromContext(r))
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get cart")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, guestCart)
+}
+
+// SetGuestCartItem handles PUT /cart/items/{productId} for anonymous carts, see SetCartItem
+func SetGuestCartItem(w http.ResponseWriter, r *http.Request) {
+	var body model.CartQuantityRequest
+	if err := utils.ParseBody(r.Body, &body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
+		return
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return
+	}
+	setGuestCartItem(w, r, *body.Quantity)
+}
+
+func RemoveGuestCartItem(w http.ResponseWriter, r *http.Request) {
+	setGuestCartItem(w, r, 0)
+}
+
+func setGuestCartItem(w http.ResponseWriter, r *http.Request, quantity int) {
+	productId, ok := productIdParam(w, r)
+	if !ok {
+		return
+	}
+	cartId := middleware.CartIdFromContext(r)
+	var updated model.Cart
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		if err := cart.SetQuantity(tx, cartId, productId, quantity); err != nil {
+			return err
+		}
+		var err error
+		updated, err = getGuestCart(tx, r, cartId)
+		return err
+	})
+	if txErr != nil {
+		respondCartError(w, txErr)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, updated)
+}
+
+// getGuestCart returns a guest cart priced for the region query parameter and shipped to the lat and long
+// query parameters, since guests have no addresses
+func getGuestCart(db sqlx.Queryer, r *http.Request, cartId string) (model.Cart, error) {
+	guestCart, err := cart.Get(db, cartId)
+	if err != nil {
+		return guestCart, err
+	}
+	breakdown, err := pricing.Quote(db, cartId, r.URL.Query().Get("region"), guestDestination(r))
+	if err != nil {
+		return guestCart, err
+	}
+	guestCart.Pricing = &breakdown
+	return guestCart, nil
+}
+
+// guestDestination reads the lat and long query parameters, a cart without a valid pair is priced without shipping
+func guestDestination(r *http.Request) *shipping.Point {
+	lat, latErr := strconv.ParseFloat(r.URL.Query().Get("lat"), 64)
+	long, longErr := strconv.ParseFloat(r.URL.Query().Get("long"), 64)
+	if latErr != nil || longErr != nil || math.Abs(lat) > 90 || math.Abs(long) > 180 {
+		return nil
+	}
+	return &shipping.Point{Lat: lat, Long: long}
+}
+
+// mergeGuestCart folds the guest cart sent with a login or register request into the user's cart.
+// A failed merge is logged and leaves the guest cart untouched, it never fails the login itself.
+func mergeGuestCart(r *http.Request, userId string) *model.CartMergeResult {
+	guestCartId, exist, err := cart.GuestCartId(database.Audiophile, r.Header.Get(cart.GuestTokenHeader))
+	if err != nil {
+		logrus.Errorf("failed to get guest cart with error: %+v", err)
+		return nil
+	}
+	if !exist {
+		return nil
+	}
+	var result model.CartMergeResult
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		result, err = cart.Merge(tx, userId, guestCartId)
+		return err
+	})
+	if txErr != nil {
+		logrus.Errorf("failed to merge guest cart %s with error: %+v", guestCartId, txErr)
+		return nil
+	}
+	return &result
+}
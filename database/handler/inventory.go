@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"audio_phile/database"
+	"audio_phile/database/dbHelper"
+	"audio_phile/inventory"
+	"audio_phile/model"
+	"audio_phile/utils"
+	"database/sql"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultMovementLimit = 100
+	maxMovementLimit     = 1000
+)
+
+func CreateWarehouse(w http.ResponseWriter, r *http.Request) {
+	var body model.WarehouseRequest
+	if err := utils.ParseBody(r.Body, &body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
+		return
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return
+	}
+
+	exist, err := dbHelper.IsWarehouseCodeExist(body.Code)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to check warehouse existence")
+		return
+	}
+	if exist {
+		utils.RespondError(w, http.StatusBadRequest, nil, "Warehouse already exist")
+		return
+	}
+
+	var warehouseId string
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		if body.IsDefault {
+			if err := dbHelper.ClearDefaultWarehouse(tx); err != nil {
+				return err
+			}
+		}
+		warehouseId, err = dbHelper.CreateWarehouse(tx, body)
+		return err
+	})
+	if txErr != nil {
+		utils.RespondError(w, http.StatusInternalServerError, txErr, "Failed to create warehouse")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, struct {
+		Message     string
+		WarehouseId string
+	}{Message: "Warehouse created successfully", WarehouseId: warehouseId})
+}
+
+func GetWarehouses(w http.ResponseWriter, r *http.Request) {
+	list, err := dbHelper.GetWarehouses()
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get warehouses")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, list)
+}
+
+func GetWarehouseStock(w http.ResponseWriter, r *http.Request) {
+	list, err := dbHelper.GetStockLevels("", chi.URLParam(r, "id"))
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get stock levels")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, list)
+}
+
+func GetProductStock(w http.ResponseWriter, r *http.Request) {
+	productId := chi.URLParam(r, "id")
+	product, err := dbHelper.GetProductById(productId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.RespondError(w, http.StatusNotFound, err, "Product not found!")
+			return
+		}
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get product")
+		return
+	}
+	levels, err := dbHelper.GetStockLevels(productId, "")
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get stock levels")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, struct {
+		ProductId   string                 `json:"productId"`
+		OnHand      int                    `json:"onHand"`
+		Available   int                    `json:"available"`
+		IsAvailable bool                   `json:"isAvailable"`
+		Warehouses  []model.WarehouseStock `json:"warehouses"`
+	}{
+		ProductId:   product.ProductId,
+		OnHand:      product.Quantity,
+		Available:   product.Available,
+		IsAvailable: product.IsAvailable,
+		Warehouses:  levels,
+	})
+}
+
+func RecordStockMovement(w http.ResponseWriter, r *http.Request) {
+	var body model.StockMovementRequest
+	if err := utils.ParseBody(r.Body, &body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
+		return
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return
+	}
+	body.ActorId = getUserId(r)
+
+	var movements []model.StockMovement
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		for _, warehouseId := range []string{body.WarehouseId, body.ToWarehouseId} {
+			if warehouseId == "" {
+				continue
+			}
+			if _, err := dbHelper.GetWarehouseById(tx, warehouseId); err != nil {
+				return err
+			}
+		}
+		var err error
+		movements, err = inventory.RecordMovement(tx, body)
+		return err
+	})
+	if txErr != nil {
+		switch {
+		case errors.Is(txErr, sql.ErrNoRows):
+			utils.RespondError(w, http.StatusNotFound, txErr, "Product or warehouse not found!")
+		case errors.Is(txErr, inventory.ErrInvalidMovement):
+			utils.RespondError(w, http.StatusBadRequest, txErr, "invalid movement quantity")
+		case inventory.IsInsufficientStock(txErr):
+			utils.RespondError(w, http.StatusConflict, txErr, "Not enough stock in warehouse")
+		default:
+			utils.RespondError(w, http.StatusInternalServerError, txErr, "Failed to record stock movement")
+		}
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, movements)
+}
+
+func GetStockMovements(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := model.StockMovementFilter{
+		ProductId:   query.Get("productId"),
+		WarehouseId: query.Get("warehouseId"),
+		Type:        model.MovementType(query.Get("type")),
+		Limit:       defaultMovementLimit,
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxMovementLimit {
+			utils.RespondError(w, http.StatusBadRequest, err, "limit must be between 1 and 1000")
+			return
+		}
+		filter.Limit = limit
+	}
+	list, err := dbHelper.GetStockMovements(filter)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get stock movements")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, list)
+}
+
+func SetReorderThreshold(w http.ResponseWriter, r *http.Request) {
+	productId := chi.URLParam(r, "id")
+	var body model.ThresholdRequest
+	if err := utils.ParseBody(r.Body, &body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
+		return
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return
+	}
+
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		if _, err := dbHelper.LockProductQuantity(tx, productId); err != nil {
+			return err
+		}
+		if _, err := dbHelper.UpdateReorderThreshold(tx, productId, body.ReorderThreshold); err != nil {
+			return err
+		}
+		// a new threshold may already be crossed
+		return inventory.SyncStockState(tx, productId)
+	})
+	if txErr != nil {
+		if errors.Is(txErr, sql.ErrNoRows) {
+			utils.RespondError(w, http.StatusNotFound, txErr, "Product not found!")
+			return
+		}
+		utils.RespondError(w, http.StatusInternalServerError, txErr, "Failed to update reorder threshold")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string
+	}{"Reorder threshold updated successfully"})
+}
+
+func GetStockAlerts(w http.ResponseWriter, r *http.Request) {
+	pendingOnly, _ := strconv.ParseBool(r.URL.Query().Get("pending"))
+	list, err := dbHelper.GetStockAlerts(pendingOnly, defaultMovementLimit)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get stock alerts")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, list)
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"audio_phile/database"
+	"audio_phile/database/dbHelper"
+	"audio_phile/utils"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+)
+
+// GetMyInvoice handles GET /user/order/{id}/invoice, the PDF invoice of one of the caller's orders
+func GetMyInvoice(w http.ResponseWriter, r *http.Request) {
+	orderId := chi.URLParam(r, "id")
+	if _, err := dbHelper.GetOrder(database.Audiophile, orderId, getUserId(r)); err != nil {
+		respondOrderError(w, err, "Failed to get invoice")
+		return
+	}
+	writeInvoice(w, orderId)
+}
+
+// GetInvoice handles GET /admin/order/{id}/invoice
+func GetInvoice(w http.ResponseWriter, r *http.Request) {
+	writeInvoice(w, chi.URLParam(r, "id"))
+}
+
+func writeInvoice(w http.ResponseWriter, orderId string) {
+	invoice, err := dbHelper.GetOrderInvoice(database.Audiophile, orderId)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.RespondError(w, http.StatusNotFound, err, "Invoice not found, orders are invoiced once paid")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get invoice")
+		return
+	}
+	pdf, err := dbHelper.GetInvoicePDF(database.Audiophile, invoice.Id)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get invoice")
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.pdf", strings.ReplaceAll(invoice.Number, "/", "-")))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(pdf); err != nil {
+		logrus.Errorf("failed to write invoice %s with error: %+v", invoice.Number, err)
+	}
+}
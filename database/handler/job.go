@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"audio_phile/database/dbHelper"
+	"audio_phile/utils"
+	"net/http"
+	"strconv"
+)
+
+const defaultJobRunLimit = 50
+
+// GetJobRuns lists the latest runs of the background jobs, optionally of one job, newest first.
+// A run still in progress shows status running with its counters updated as it goes.
+func GetJobRuns(w http.ResponseWriter, r *http.Request) {
+	limit := defaultJobRunLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxMovementLimit {
+			utils.RespondError(w, http.StatusBadRequest, err, "limit must be between 1 and 1000")
+			return
+		}
+		limit = parsed
+	}
+	list, err := dbHelper.GetJobRuns(r.URL.Query().Get("job"), limit)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get job runs")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, list)
+}
@@ -0,0 +1,416 @@
+package handler
+
+import (
+	"audio_phile/credit"
+	"audio_phile/database"
+	"audio_phile/database/dbHelper"
+	"audio_phile/model"
+	"audio_phile/order"
+	"audio_phile/payment"
+	"audio_phile/utils"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UpdateOrderStatus handles POST /order/{id}/status and moves the order along its lifecycle, a cancelled
+// order gets back what was captured for it
+func UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
+	var body model.OrderStatusRequest
+	if err := utils.ParseBody(r.Body, &body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
+		return
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return
+	}
+
+	orderId := chi.URLParam(r, "id")
+	refunds, err := changeOrderStatus(orderId, body, getUserId(r))
+	if errors.Is(err, payment.ErrRefundFailed) {
+		// the order was cancelled, the response tells which refunds went through
+		utils.RespondJSON(w, http.StatusBadGateway, struct {
+			Message string
+			Status  model.OrderStatus
+			Refunds []model.Refund
+		}{Message: "Order cancelled but refunding it failed, send the refund again", Status: body.Status, Refunds: refunds})
+		return
+	}
+	if err != nil {
+		respondOrderError(w, err, "Failed to update order status")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string
+		Status  model.OrderStatus
+		Refunds []model.Refund `json:",omitempty"`
+	}{Message: "Order status updated successfully", Status: body.Status, Refunds: refunds})
+}
+
+// changeOrderStatus moves an order to the requested status, cancelling also refunds what was captured for it
+func changeOrderStatus(orderId string, change model.OrderStatusRequest, actorId string) ([]model.Refund, error) {
+	if change.Status == model.OrderStatusCancelled {
+		return payment.CancelOrder(orderId, change, actorId)
+	}
+	return nil, database.Tx(func(tx *sqlx.Tx) error {
+		return order.Transition(tx, orderId, change, actorId)
+	})
+}
+
+// GetOrders handles GET /admin/order, all orders matching the filters newest first
+func GetOrders(w http.ResponseWriter, r *http.Request) {
+	filter, page, err := parseOrderFilter(r)
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, err.Error())
+		return
+	}
+	orders, total, err := dbHelper.GetOrders(filter)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get orders")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, model.OrderPage{Orders: orders, Page: page, Limit: filter.Limit, Total: total})
+}
+
+// GetOrderById handles GET /admin/order/{id}, the order detail with the internal notes
+func GetOrderById(w http.ResponseWriter, r *http.Request) {
+	detail, err := order.Detail(database.Audiophile, chi.URLParam(r, "id"), "")
+	if err != nil {
+		respondOrderError(w, err, "Failed to get order")
+		return
+	}
+	notes, err := dbHelper.GetOrderNotes(database.Audiophile, detail.Id)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get order notes")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, struct {
+		model.OrderDetail
+		Notes []model.OrderNote `json:"notes"`
+	}{OrderDetail: detail, Notes: notes})
+}
+
+func AddOrderNote(w http.ResponseWriter, r *http.Request) {
+	var body model.OrderNoteRequest
+	if err := utils.ParseBody(r.Body, &body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
+		return
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return
+	}
+	noteId, err := dbHelper.CreateOrderNote(database.Audiophile, chi.URLParam(r, "id"), getUserId(r), body.Body)
+	if err != nil {
+		respondOrderError(w, err, "Failed to add order note")
+		return
+	}
+	utils.RespondJSON(w, http.StatusCreated, struct {
+		Message string
+		NoteId  string
+	}{Message: "Note added successfully", NoteId: noteId})
+}
+
+// BulkUpdateOrderStatus handles POST /admin/order/status and moves several orders to the same status.
+// Each order is moved in its own transaction, so one illegal transition does not hold back the others;
+// the result lists what happened to every order.
+func BulkUpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
+	var body model.BulkOrderStatusRequest
+	if err := utils.ParseBody(r.Body, &body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
+		return
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return
+	}
+	if body.Status == model.OrderStatusShipped && body.Tracking == nil {
+		utils.RespondError(w, http.StatusBadRequest, order.ErrTrackingRequired, order.ErrTrackingRequired.Error())
+		return
+	}
+
+	actorId := getUserId(r)
+	results := make([]model.BulkOrderResult, 0, len(body.OrderIds))
+	for _, orderId := range body.OrderIds {
+		_, txErr := changeOrderStatus(orderId, body.OrderStatusRequest, actorId)
+		result := model.BulkOrderResult{OrderId: orderId, Updated: txErr == nil || errors.Is(txErr, payment.ErrRefundFailed)}
+		switch {
+		case txErr == nil:
+		case errors.Is(txErr, payment.ErrRefundFailed):
+			result.Error = "order cancelled but refunding it failed"
+		case errors.Is(txErr, sql.ErrNoRows):
+			result.Error = "order not found"
+		case order.IsIllegalTransition(txErr), errors.Is(txErr, order.ErrPaymentRequired), errors.Is(txErr, order.ErrNotPaidInFull),
+			errors.Is(txErr, order.ErrTrackingRequired):
+			result.Error = txErr.Error()
+		default:
+			logrus.Errorf("failed to update status of order %s with error: %+v", orderId, txErr)
+			result.Error = "failed to update order status"
+		}
+		results = append(results, result)
+	}
+	utils.RespondJSON(w, http.StatusOK, results)
+}
+
+var orderCSVHeader = []string{"id", "created_at", "status", "customer_email", "items", "subtotal", "discount", "tax", "shipping", "grand_total",
+	"shipping_region", "carrier", "tracking_number"}
+
+// ExportOrders handles GET /admin/order/export, a CSV of every order matching the list filters
+func ExportOrders(w http.ResponseWriter, r *http.Request) {
+	filter, _, err := parseOrderFilter(r)
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=orders-%s.csv", time.Now().Format("20060102")))
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(orderCSVHeader); err != nil {
+		logrus.Errorf("failed to export orders with error: %+v", err)
+		return
+	}
+	err = dbHelper.StreamOrders(filter, func(row model.OrderExportRow) error {
+		return writer.Write(csvSafe([]string{
+			row.Id,
+			row.CreatedAt.Format(time.RFC3339),
+			string(row.Status),
+			optionalText(row.Email),
+			strconv.Itoa(row.ItemCount),
+			row.Subtotal.String(),
+			row.DiscountTotal.String(),
+			row.TaxTotal.String(),
+			row.ShippingTotal.String(),
+			row.GrandTotal.String(),
+			optionalText(row.Region),
+			optionalText(row.Carrier),
+			optionalText(row.TrackingNumber),
+		}))
+	})
+	writer.Flush()
+	// headers are already sent once streaming starts, so a failure can only be logged
+	if err == nil {
+		err = writer.Error()
+	}
+	if err != nil {
+		logrus.Errorf("failed to export orders with error: %+v", err)
+	}
+}
+
+// csvSafe keeps spreadsheets from running cells as formulas: a cell starting with =, +, -, @, a tab or a
+// carriage return, like an email or a tracking number a customer or courier typed, is prefixed with a quote
+// so it is shown as text
+func csvSafe(cells []string) []string {
+	for i, cell := range cells {
+		if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+			cells[i] = "'" + cell
+		}
+	}
+	return cells
+}
+
+func optionalText(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func GetOrderStatusHistory(w http.ResponseWriter, r *http.Request) {
+	history, err := dbHelper.GetOrderStatusHistory(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get order history")
+		return
+	}
+	if len(history) == 0 {
+		utils.RespondError(w, http.StatusNotFound, nil, "Order not found!")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, history)
+}
+
+const (
+	defaultOrderPageSize = 20
+	maxOrderPageSize     = 100
+)
+
+// parseOrderFilter reads page, limit, status, from, to, customer and minTotal from the query. Dates are RFC 3339 timestamps
+// or plain dates, a plain to date includes the whole day.
+func parseOrderFilter(r *http.Request) (model.OrderFilter, int, error) {
+	query := r.URL.Query()
+	filter := model.OrderFilter{Status: model.OrderStatus(query.Get("status")), Limit: defaultOrderPageSize}
+	page := 1
+	if value := query.Get("page"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			return filter, 0, errors.New("page must be a positive number")
+		}
+		page = parsed
+	}
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxOrderPageSize {
+			return filter, 0, errors.New("limit must be between 1 and 100")
+		}
+		filter.Limit = parsed
+	}
+	filter.Offset = (page - 1) * filter.Limit
+	filter.Customer = query.Get("customer")
+	if value := query.Get("minTotal"); value != "" {
+		minTotal, err := strconv.ParseFloat(value, 64)
+		if err != nil || minTotal < 0 {
+			return filter, 0, errors.New("minTotal must be a positive amount")
+		}
+		filter.MinTotal = model.Money(math.Round(minTotal * 100))
+	}
+
+	if filter.Status != "" {
+		if !order.IsKnownStatus(filter.Status) {
+			return filter, 0, errors.New("unknown order status")
+		}
+	}
+	for _, bound := range []struct {
+		name   string
+		target **time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		value := query.Get(bound.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			day, dayErr := time.Parse("2006-01-02", value)
+			if dayErr != nil {
+				return filter, 0, errors.New(bound.name + " must be a date (2006-01-02) or an RFC 3339 timestamp")
+			}
+			if bound.name == "to" {
+				day = day.AddDate(0, 0, 1)
+			}
+			parsed = day
+		}
+		*bound.target = &parsed
+	}
+	return filter, page, nil
+}
+
+// GetMyOrders handles GET /user/order, the caller's orders newest first
+func GetMyOrders(w http.ResponseWriter, r *http.Request) {
+	filter, page, err := parseOrderFilter(r)
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, err.Error())
+		return
+	}
+	filter.UserId = getUserId(r)
+	orders, total, err := dbHelper.GetOrders(filter)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get orders")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, model.OrderPage{Orders: orders, Page: page, Limit: filter.Limit, Total: total})
+}
+
+// GetMyOrder handles GET /user/order/{id}, another user's order is answered with 404
+func GetMyOrder(w http.ResponseWriter, r *http.Request) {
+	detail, err := order.Detail(database.Audiophile, chi.URLParam(r, "id"), getUserId(r))
+	if err != nil {
+		respondOrderError(w, err, "Failed to get order")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, detail)
+}
+
+// PayOrder handles POST /user/order/{id}/pay, it settles what is still due on an order that is awaiting
+// payment, with the methods of the optional body. It can be retried after a failed payment.
+func PayOrder(w http.ResponseWriter, r *http.Request) {
+	body, ok := parsePaymentRequest(w, r)
+	if !ok {
+		return
+	}
+	payments, err := payment.Pay(chi.URLParam(r, "id"), getUserId(r), body)
+	if err != nil {
+		respondPaymentError(w, err, payments)
+		return
+	}
+	status := http.StatusOK
+	if isPaymentPending(payments) {
+		status = http.StatusAccepted
+	}
+	utils.RespondJSON(w, status, payments)
+}
+
+// parsePaymentRequest reads the optional payment body, without one the order is paid through the gateway
+func parsePaymentRequest(w http.ResponseWriter, r *http.Request) (model.PaymentRequest, bool) {
+	var body model.PaymentRequest
+	if r.ContentLength != 0 {
+		if err := utils.ParseBody(r.Body, &body); err != nil {
+			utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
+			return body, false
+		}
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return body, false
+	}
+	return body, true
+}
+
+func isPaymentPending(payments []model.Payment) bool {
+	for _, paid := range payments {
+		if paid.Status == model.PaymentStatusPending {
+			return true
+		}
+	}
+	return false
+}
+
+func respondPaymentError(w http.ResponseWriter, err error, payments []model.Payment) {
+	switch {
+	case errors.Is(err, payment.ErrPaymentFailed):
+		reason := "Payment failed"
+		if len(payments) > 0 && payments[len(payments)-1].FailureReason != nil {
+			reason = "Payment failed: " + *payments[len(payments)-1].FailureReason
+		}
+		utils.RespondError(w, http.StatusPaymentRequired, err, reason)
+	case errors.Is(err, payment.ErrOrderNotPayable):
+		utils.RespondError(w, http.StatusConflict, err, "Order is not awaiting payment")
+	case errors.Is(err, payment.ErrInsufficientCredit), errors.Is(err, payment.ErrStoreCreditUnavailable):
+		utils.RespondError(w, http.StatusConflict, err, err.Error())
+	case errors.Is(err, payment.ErrCodNotServiceable), payment.IsCodLimit(err):
+		utils.RespondError(w, http.StatusUnprocessableEntity, err, err.Error())
+	case errors.Is(err, credit.ErrGiftCardNotFound), errors.Is(err, credit.ErrGiftCardExpired),
+		errors.Is(err, credit.ErrGiftCardDisabled), errors.Is(err, credit.ErrGiftCardEmpty):
+		respondCreditError(w, err, "Failed to redeem gift card")
+	default:
+		respondOrderError(w, err, "Failed to process payment")
+	}
+}
+
+func respondOrderError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		utils.RespondError(w, http.StatusNotFound, err, "Order not found!")
+	case errors.Is(err, order.ErrPaymentRequired), errors.Is(err, order.ErrNotPaidInFull):
+		utils.RespondError(w, http.StatusConflict, err, err.Error())
+	case errors.Is(err, order.ErrTrackingRequired):
+		utils.RespondError(w, http.StatusBadRequest, err, err.Error())
+	case order.IsIllegalTransition(err):
+		utils.RespondError(w, http.StatusConflict, err, err.Error())
+	default:
+		utils.RespondError(w, http.StatusInternalServerError, err, message)
+	}
+}
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCsvSafe(t *testing.T) {
+	tests := []struct {
+		name  string
+		cells []string
+		safe  []string
+	}{
+		{"plain cells", []string{"5b6f3c1e", "paid", "alice@example.com", "1999.00", ""}, []string{"5b6f3c1e", "paid", "alice@example.com", "1999.00", ""}},
+		{"formula", []string{`=HYPERLINK("http://evil","x")`}, []string{`'=HYPERLINK("http://evil","x")`}},
+		{"plus", []string{"+1+cmd|' /C calc'!A0"}, []string{"'+1+cmd|' /C calc'!A0"}},
+		{"minus", []string{"-2+3"}, []string{"'-2+3"}},
+		{"at", []string{"@SUM(A1:A9)"}, []string{"'@SUM(A1:A9)"}},
+		{"tab", []string{"\t=1+1"}, []string{"'\t=1+1"}},
+		{"carriage return", []string{"\r=1+1"}, []string{"'\r=1+1"}},
+		{"only the first character counts", []string{"a=b", "x@y.z"}, []string{"a=b", "x@y.z"}},
+		{"each cell on its own", []string{"KA", "=1", "DTDC", "@2"}, []string{"KA", "'=1", "DTDC", "'@2"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := csvSafe(test.cells); !reflect.DeepEqual(got, test.safe) {
+				t.Fatalf("expected %q, got %q", test.safe, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"audio_phile/database"
+	"audio_phile/database/dbHelper"
+	"audio_phile/model"
+	"audio_phile/payment"
+	"audio_phile/utils"
+	"database/sql"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+const maxWebhookSize = 1 << 20
+
+// PaymentWebhook handles POST /payment/webhook. A non-2xx answer makes the provider deliver the event again
+// later, so only events that can never be accepted, e.g. with a bad signature, are answered with a 4xx.
+func PaymentWebhook(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookSize))
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "Failed to read request body")
+		return
+	}
+	event, duplicate, err := payment.ReceiveWebhook(payload, r.Header)
+	if err != nil {
+		switch {
+		case errors.Is(err, payment.ErrWebhookDisabled):
+			utils.RespondError(w, http.StatusServiceUnavailable, err, "webhooks are not configured")
+		case errors.Is(err, payment.ErrInvalidSignature):
+			utils.RespondError(w, http.StatusUnauthorized, err, "invalid signature")
+		case errors.Is(err, payment.ErrStaleWebhook):
+			utils.RespondError(w, http.StatusBadRequest, err, "webhook timestamp is too old")
+		case event.Id == "":
+			utils.RespondError(w, http.StatusBadRequest, err, "invalid webhook")
+		default:
+			logrus.Errorf("failed to process webhook event %s with error: %+v", event.EventId, err)
+			utils.RespondError(w, http.StatusInternalServerError, err, "Failed to process webhook")
+		}
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Received  bool `json:"received"`
+		Duplicate bool `json:"duplicate"`
+	}{Received: true, Duplicate: duplicate})
+}
+
+func GetWebhookEvents(w http.ResponseWriter, r *http.Request) {
+	unprocessedOnly, _ := strconv.ParseBool(r.URL.Query().Get("unprocessed"))
+	list, err := dbHelper.GetWebhookEvents(unprocessedOnly, defaultMovementLimit)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get webhook events")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, list)
+}
+
+// ReprocessWebhookEvent applies a stored event again, e.g. after the cause of its failure was fixed
+func ReprocessWebhookEvent(w http.ResponseWriter, r *http.Request) {
+	if err := payment.ProcessWebhookEvent(chi.URLParam(r, "id"), true); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.RespondError(w, http.StatusNotFound, err, "Webhook event not found!")
+			return
+		}
+		utils.RespondError(w, http.StatusUnprocessableEntity, err, "Failed to process webhook event")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string
+	}{"Webhook event processed successfully"})
+}
+
+// GetCodPayments handles GET /admin/payment/cod, only the payments still to be collected with ?outstanding=true
+func GetCodPayments(w http.ResponseWriter, r *http.Request) {
+	outstandingOnly, _ := strconv.ParseBool(r.URL.Query().Get("outstanding"))
+	list, err := dbHelper.GetCodPayments(database.Audiophile, outstandingOnly)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get cash on delivery payments")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, list)
+}
+
+// ReconcileCodPayments handles POST /admin/payment/cod/reconcile with what couriers collected. Each
+// collection is recorded on its own, the result tells what happened to every payment.
+func ReconcileCodPayments(w http.ResponseWriter, r *http.Request) {
+	var body model.CodReconcileRequest
+	if err := utils.ParseBody(r.Body, &body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
+		return
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return
+	}
+
+	actorId := getUserId(r)
+	results := make([]model.CodReconcileResult, 0, len(body.Collections))
+	for _, collection := range body.Collections {
+		result := model.CodReconcileResult{PaymentId: collection.PaymentId}
+		reconciled, err := payment.ReconcileCod(collection, actorId)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			result.Error = "payment not found"
+		case err != nil:
+			result.Error = err.Error()
+		case !reconciled:
+			result.Error = "collected amount does not match the payment"
+		default:
+			result.Reconciled = true
+		}
+		results = append(results, result)
+	}
+	utils.RespondJSON(w, http.StatusOK, results)
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"audio_phile/database"
+	"audio_phile/database/dbHelper"
+	"audio_phile/model"
+	"audio_phile/pricing"
+	"audio_phile/shipping"
+	"audio_phile/utils"
+	"database/sql"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+	"net/http"
+)
+
+var errAddressNotFound = errors.New("address not found")
+
+func CreateTaxRate(w http.ResponseWriter, r *http.Request) {
+	var body model.TaxRateRequest
+	if err := utils.ParseBody(r.Body, &body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
+		return
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return
+	}
+
+	exist, err := dbHelper.IsTaxRateExist(body.Category, body.Region)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to check tax rate existence")
+		return
+	}
+	if exist {
+		utils.RespondError(w, http.StatusBadRequest, nil, "Tax rate already exist for this category and region")
+		return
+	}
+
+	taxRateId, err := dbHelper.CreateTaxRate(body)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to create tax rate")
+		return
+	}
+	utils.RespondJSON(w, http.StatusCreated, struct {
+		Message   string
+		TaxRateId string
+	}{Message: "Tax rate created successfully", TaxRateId: taxRateId})
+}
+
+func GetTaxRates(w http.ResponseWriter, r *http.Request) {
+	list, err := dbHelper.GetTaxRates(database.Audiophile)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get tax rates")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, list)
+}
+
+func DeleteTaxRate(w http.ResponseWriter, r *http.Request) {
+	deleted, err := dbHelper.ArchiveTaxRate(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to delete tax rate")
+		return
+	}
+	if !deleted {
+		utils.RespondError(w, http.StatusNotFound, nil, "Tax rate not found!")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string
+	}{"Tax rate deleted successfully"})
+}
+
+// shippingAddress resolves the address a cart is priced for: the addressId query parameter when given,
+// otherwise the user's latest address. A user without any address gets an empty one and pays the
+// catch-all tax rate.
+func shippingAddress(db sqlx.Queryer, r *http.Request, userId string) (model.AddressModel, error) {
+	addressId := r.URL.Query().Get("addressId")
+	address, err := dbHelper.GetUserAddress(db, userId, addressId)
+	if errors.Is(err, sql.ErrNoRows) {
+		if addressId != "" {
+			return address, errAddressNotFound
+		}
+		return address, nil
+	}
+	return address, err
+}
+
+// priceCart attaches the price breakdown for the request's shipping address to the cart
+func priceCart(db sqlx.Queryer, r *http.Request, userId string, userCart *model.Cart) error {
+	if userCart.Id == "" {
+		return nil
+	}
+	address, err := shippingAddress(db, r, userId)
+	if err != nil {
+		return err
+	}
+	breakdown, err := pricing.Quote(db, userCart.Id, address.Region, shipping.AddressPoint(address))
+	if err != nil {
+		return err
+	}
+	userCart.Pricing = &breakdown
+	return nil
+}
@@ -0,0 +1,249 @@
+package handler
+
+import (
+	"audio_phile/cart"
+	"audio_phile/database"
+	"audio_phile/database/dbHelper"
+	"audio_phile/model"
+	"audio_phile/utils"
+	"database/sql"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+	"net/http"
+)
+
+var (
+	errCartNotFound   = errors.New("cart not found")
+	errCouponNotFound = errors.New("coupon not found")
+)
+
+// parsePromotion reads a promotion and checks that the fields its kind relies on are set
+func parsePromotion(w http.ResponseWriter, r *http.Request, promotionId string) (model.PromotionRequest, bool) {
+	var body model.PromotionRequest
+	if err := utils.ParseBody(r.Body, &body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
+		return body, false
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return body, false
+	}
+
+	var message string
+	switch body.Kind {
+	case model.PromotionPercentage:
+		if body.Value < 1 || body.Value > 100 {
+			message = "value must be a percentage between 1 and 100"
+		}
+	case model.PromotionCategoryDiscount:
+		if body.Category == "" || body.Value < 1 || body.Value > 100 {
+			message = "category discount needs a category and a percentage between 1 and 100"
+		}
+	case model.PromotionFixedAmount:
+		if body.Value < 1 {
+			message = "value must be the amount taken off"
+		}
+	case model.PromotionBuyXGetY:
+		if body.ProductId == "" || body.BuyQuantity < 1 || body.GetQuantity < 1 {
+			message = "buy x get y needs a product, a buy quantity and a get quantity"
+		}
+	}
+	if message == "" && body.StartsAt != nil && body.EndsAt != nil && !body.EndsAt.After(*body.StartsAt) {
+		message = "endsAt must be after startsAt"
+	}
+	if message != "" {
+		utils.RespondError(w, http.StatusBadRequest, nil, message)
+		return body, false
+	}
+
+	if body.Code != "" {
+		taken, err := dbHelper.IsPromotionCodeTaken(body.Code, promotionId)
+		if err != nil {
+			utils.RespondError(w, http.StatusInternalServerError, err, "Failed to check coupon code")
+			return body, false
+		}
+		if taken {
+			utils.RespondError(w, http.StatusBadRequest, nil, "Coupon code already exist")
+			return body, false
+		}
+	}
+	return body, true
+}
+
+func CreatePromotion(w http.ResponseWriter, r *http.Request) {
+	body, ok := parsePromotion(w, r, "")
+	if !ok {
+		return
+	}
+	promotionId, err := dbHelper.CreatePromotion(body)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to create promotion")
+		return
+	}
+	utils.RespondJSON(w, http.StatusCreated, struct {
+		Message     string
+		PromotionId string
+	}{Message: "Promotion created successfully", PromotionId: promotionId})
+}
+
+func GetPromotions(w http.ResponseWriter, r *http.Request) {
+	list, err := dbHelper.GetPromotions()
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get promotions")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, list)
+}
+
+func GetPromotionById(w http.ResponseWriter, r *http.Request) {
+	promotion, err := dbHelper.GetPromotionById(chi.URLParam(r, "id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.RespondError(w, http.StatusNotFound, err, "Promotion not found!")
+			return
+		}
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get promotion")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, promotion)
+}
+
+func UpdatePromotion(w http.ResponseWriter, r *http.Request) {
+	promotionId := chi.URLParam(r, "id")
+	body, ok := parsePromotion(w, r, promotionId)
+	if !ok {
+		return
+	}
+	updated, err := dbHelper.UpdatePromotion(promotionId, body)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to update promotion")
+		return
+	}
+	if !updated {
+		utils.RespondError(w, http.StatusNotFound, nil, "Promotion not found!")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string
+	}{"Promotion updated successfully"})
+}
+
+func DeletePromotion(w http.ResponseWriter, r *http.Request) {
+	var deleted bool
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		var err error
+		deleted, err = dbHelper.ArchivePromotion(tx, chi.URLParam(r, "id"))
+		return err
+	})
+	if txErr != nil {
+		utils.RespondError(w, http.StatusInternalServerError, txErr, "Failed to delete promotion")
+		return
+	}
+	if !deleted {
+		utils.RespondError(w, http.StatusNotFound, nil, "Promotion not found!")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string
+	}{"Promotion deleted successfully"})
+}
+
+// ApplyCoupon handles PUT /cart/coupon. The coupon replaces any coupon already on the cart and is only kept
+// when it applies to the cart as it is now; the priced cart is returned.
+func ApplyCoupon(w http.ResponseWriter, r *http.Request) {
+	var body model.CouponRequest
+	if err := utils.ParseBody(r.Body, &body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
+		return
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return
+	}
+	userId := getUserId(r)
+
+	var updated model.Cart
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		cartId, exist, err := cart.ActiveCartId(tx, userId, false)
+		if err != nil {
+			return err
+		}
+		if !exist {
+			return errCartNotFound
+		}
+		couponId, err := dbHelper.GetCouponIdByCode(tx, body.Code)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errCouponNotFound
+			}
+			return err
+		}
+		if err := dbHelper.SetCartCoupon(tx, cartId, &couponId); err != nil {
+			return err
+		}
+		if updated, err = cart.Get(tx, cartId); err != nil {
+			return err
+		}
+		if err := priceCart(tx, r, userId, &updated); err != nil {
+			return err
+		}
+		if updated.Pricing.CouponError != "" {
+			return errors.New(updated.Pricing.CouponError)
+		}
+		return nil
+	})
+	if txErr != nil {
+		switch {
+		case errors.Is(txErr, errCartNotFound):
+			utils.RespondError(w, http.StatusNotFound, txErr, "Cart not found!")
+		case errors.Is(txErr, errCouponNotFound):
+			utils.RespondError(w, http.StatusNotFound, txErr, "Coupon not found!")
+		case errors.Is(txErr, errAddressNotFound):
+			utils.RespondError(w, http.StatusNotFound, txErr, "Address not found!")
+		case updated.Pricing != nil && updated.Pricing.CouponError != "":
+			utils.RespondError(w, http.StatusBadRequest, txErr, updated.Pricing.CouponError)
+		default:
+			utils.RespondError(w, http.StatusInternalServerError, txErr, "Failed to apply coupon")
+		}
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, updated)
+}
+
+// RemoveCoupon handles DELETE /cart/coupon
+func RemoveCoupon(w http.ResponseWriter, r *http.Request) {
+	userId := getUserId(r)
+	var updated model.Cart
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		cartId, exist, err := cart.ActiveCartId(tx, userId, false)
+		if err != nil {
+			return err
+		}
+		if !exist {
+			return errCartNotFound
+		}
+		if err := dbHelper.SetCartCoupon(tx, cartId, nil); err != nil {
+			return err
+		}
+		if updated, err = cart.Get(tx, cartId); err != nil {
+			return err
+		}
+		return priceCart(tx, r, userId, &updated)
+	})
+	if txErr != nil {
+		switch {
+		case errors.Is(txErr, errCartNotFound):
+			utils.RespondError(w, http.StatusNotFound, txErr, "Cart not found!")
+		case errors.Is(txErr, errAddressNotFound):
+			utils.RespondError(w, http.StatusNotFound, txErr, "Address not found!")
+		default:
+			utils.RespondError(w, http.StatusInternalServerError, txErr, "Failed to remove coupon")
+		}
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, updated)
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"audio_phile/database"
+	"audio_phile/database/dbHelper"
+	"audio_phile/utils"
+	"database/sql"
+	"net/http"
+)
+
+func SubscribeRestock(w http.ResponseWriter, r *http.Request) {
+	productId, ok := uuidParam(w, r, "id", "invalid product id")
+	if !ok {
+		return
+	}
+	userId := getUserId(r)
+
+	product, err := dbHelper.GetProductById(productId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.RespondError(w, http.StatusNotFound, err, "Product not found!")
+			return
+		}
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get product")
+		return
+	}
+	if product.IsAvailable {
+		utils.RespondError(w, http.StatusBadRequest, nil, "Product is in stock")
+		return
+	}
+
+	subscriptionId, err := dbHelper.CreateRestockSubscription(database.Audiophile, userId, productId)
+	if database.IsUniqueViolation(err) {
+		utils.RespondError(w, http.StatusConflict, err, "Already subscribed to this product")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to subscribe")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, struct {
+		Message        string
+		SubscriptionId string
+	}{Message: "You will be notified when the product is back in stock", SubscriptionId: subscriptionId})
+}
+
+func UnsubscribeRestock(w http.ResponseWriter, r *http.Request) {
+	productId, ok := uuidParam(w, r, "id", "invalid product id")
+	if !ok {
+		return
+	}
+	deleted, err := dbHelper.DeleteRestockSubscription(database.Audiophile, getUserId(r), productId)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to unsubscribe")
+		return
+	}
+	if !deleted {
+		utils.RespondError(w, http.StatusNotFound, nil, "Subscription not found!")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string
+	}{"Unsubscribed successfully"})
+}
+
+func GetRestockSubscriptions(w http.ResponseWriter, r *http.Request) {
+	list, err := dbHelper.GetUserRestockSubscriptions(getUserId(r))
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get subscriptions")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, list)
+}
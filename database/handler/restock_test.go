@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestRestockRejectsInvalidProductId checks that an invalid product id is answered before anything
+// reaches the database
+func TestRestockRejectsInvalidProductId(t *testing.T) {
+	router := chi.NewRouter()
+	router.Post("/product/{id}/subscribe", SubscribeRestock)
+	router.Delete("/product/{id}/subscribe", UnsubscribeRestock)
+
+	for _, method := range []string{http.MethodPost, http.MethodDelete} {
+		t.Run(method, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(method, "/product/not-a-uuid/subscribe", nil))
+			if recorder.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d: %s", recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+}
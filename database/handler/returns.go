@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"audio_phile/database"
+	"audio_phile/database/dbHelper"
+	"audio_phile/model"
+	"audio_phile/payment"
+	"audio_phile/returns"
+	"audio_phile/utils"
+	"database/sql"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"net/http"
+)
+
+// RequestReturn handles POST /user/order/{id}/returns and opens a return for lines of a delivered order
+func RequestReturn(w http.ResponseWriter, r *http.Request) {
+	var body model.ReturnRequest
+	if err := utils.ParseBody(r.Body, &body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
+		return
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return
+	}
+	returnId, err := returns.Request(chi.URLParam(r, "id"), getUserId(r), body)
+	if err != nil {
+		respondReturnError(w, err, "Failed to request return")
+		return
+	}
+	utils.RespondJSON(w, http.StatusCreated, struct {
+		Message  string
+		ReturnId string
+	}{Message: "Return requested successfully", ReturnId: returnId})
+}
+
+// GetMyReturns handles GET /user/returns, the caller's returns newest first
+func GetMyReturns(w http.ResponseWriter, r *http.Request) {
+	list, err := dbHelper.GetReturns(database.Audiophile, getUserId(r), "")
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get returns")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, list)
+}
+
+// GetMyReturn handles GET /user/returns/{id}, another user's return is answered with 404
+func GetMyReturn(w http.ResponseWriter, r *http.Request) {
+	detail, err := returns.Detail(database.Audiophile, chi.URLParam(r, "id"), getUserId(r))
+	if err != nil {
+		respondReturnError(w, err, "Failed to get return")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, detail)
+}
+
+// ShipReturn handles PUT /user/returns/{id}/shipment with the tracking of the parcel sent back
+func ShipReturn(w http.ResponseWriter, r *http.Request) {
+	var body model.Tracking
+	if err := utils.ParseBody(r.Body, &body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
+		return
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return
+	}
+	if err := returns.Ship(chi.URLParam(r, "id"), getUserId(r), body); err != nil {
+		respondReturnError(w, err, "Failed to record return shipment")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string
+	}{"Return shipment recorded successfully"})
+}
+
+// GetReturns handles GET /admin/returns, optionally only the returns in the status given by ?status=
+func GetReturns(w http.ResponseWriter, r *http.Request) {
+	status := model.ReturnStatus(r.URL.Query().Get("status"))
+	if err := validator.New().Var(string(status), "omitempty,oneof=requested approved rejected in_transit received refunded"); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "invalid status")
+		return
+	}
+	list, err := dbHelper.GetReturns(database.Audiophile, "", status)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get returns")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, list)
+}
+
+func GetReturnById(w http.ResponseWriter, r *http.Request) {
+	detail, err := returns.Detail(database.Audiophile, chi.URLParam(r, "id"), "")
+	if err != nil {
+		respondReturnError(w, err, "Failed to get return")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, detail)
+}
+
+func ApproveReturn(w http.ResponseWriter, r *http.Request) {
+	resolveReturn(w, r, returns.Approve, "Return approved successfully")
+}
+
+func RejectReturn(w http.ResponseWriter, r *http.Request) {
+	resolveReturn(w, r, returns.Reject, "Return rejected successfully")
+}
+
+func resolveReturn(w http.ResponseWriter, r *http.Request, resolve func(returnId, actorId, note string) error, message string) {
+	var body model.ReturnDecisionRequest
+	if err := utils.ParseBody(r.Body, &body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
+		return
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return
+	}
+	if err := resolve(chi.URLParam(r, "id"), getUserId(r), body.Note); err != nil {
+		respondReturnError(w, err, "Failed to resolve return")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string
+	}{message})
+}
+
+// ReceiveReturn handles POST /admin/returns/{id}/receive once the returned items arrived
+func ReceiveReturn(w http.ResponseWriter, r *http.Request) {
+	var body model.ReturnReceiptRequest
+	if err := utils.ParseBody(r.Body, &body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
+		return
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return
+	}
+	if err := returns.Receive(chi.URLParam(r, "id"), getUserId(r), body); err != nil {
+		respondReturnError(w, err, "Failed to receive return")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string
+	}{"Return received successfully"})
+}
+
+// RefundReturn handles POST /admin/returns/{id}/refund for a received return
+func RefundReturn(w http.ResponseWriter, r *http.Request) {
+	body, ok := parseRefundRequest(w, r)
+	if !ok {
+		return
+	}
+	refunds, err := returns.Refund(chi.URLParam(r, "id"), getUserId(r), body)
+	respondRefunds(w, refunds, err)
+}
+
+// RefundOrder handles POST /admin/order/{id}/refund, a full or partial refund that is not tied to a return
+func RefundOrder(w http.ResponseWriter, r *http.Request) {
+	body, ok := parseRefundRequest(w, r)
+	if !ok {
+		return
+	}
+	refunds, err := payment.Refund(chi.URLParam(r, "id"), body.Amount, "", getUserId(r), body.Reason)
+	respondRefunds(w, refunds, err)
+}
+
+func parseRefundRequest(w http.ResponseWriter, r *http.Request) (model.RefundRequest, bool) {
+	var body model.RefundRequest
+	if err := utils.ParseBody(r.Body, &body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
+		return body, false
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return body, false
+	}
+	return body, true
+}
+
+func respondRefunds(w http.ResponseWriter, refunds []model.Refund, err error) {
+	if errors.Is(err, payment.ErrRefundFailed) {
+		// some of the refunds may have gone through, the response tells which
+		utils.RespondJSON(w, http.StatusBadGateway, refunds)
+		return
+	}
+	if err != nil {
+		respondReturnError(w, err, "Failed to refund")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, refunds)
+}
+
+func respondReturnError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		utils.RespondError(w, http.StatusNotFound, err, "Not found!")
+	case errors.Is(err, returns.ErrDuplicateItem), returns.IsItemError(err):
+		utils.RespondError(w, http.StatusBadRequest, err, err.Error())
+	case errors.Is(err, returns.ErrOrderNotReturnable), errors.Is(err, returns.ErrWindowClosed),
+		errors.Is(err, payment.ErrOrderNotRefundable), errors.Is(err, payment.ErrNothingToRefund),
+		errors.Is(err, payment.ErrReturnRefunded), errors.Is(err, payment.ErrCashRefundNoUser),
+		returns.IsRejected(err), payment.IsRefundExceeds(err):
+		utils.RespondError(w, http.StatusConflict, err, err.Error())
+	default:
+		respondOrderError(w, err, message)
+	}
+}
@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"audio_phile/database"
+	"audio_phile/database/dbHelper"
+	"audio_phile/model"
+	"audio_phile/utils"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// parseProductFilter reads ?minRating=4&sortBy=rating&order=desc from the product listing request
+func parseProductFilter(r *http.Request) (model.ProductFilter, error) {
+	query := r.URL.Query()
+	filter := model.ProductFilter{
+		SortBy: model.ProductSort(strings.ToLower(query.Get("sortBy"))),
+		Desc:   strings.EqualFold(query.Get("order"), "desc"),
+	}
+	switch filter.SortBy {
+	case "", model.ProductSortName, model.ProductSortPrice, model.ProductSortRating, model.ProductSortReviews:
+	default:
+		return filter, fmt.Errorf("unknown sortBy %q", filter.SortBy)
+	}
+	if value := query.Get("minRating"); value != "" {
+		minRating, err := strconv.ParseFloat(value, 64)
+		if err != nil || minRating < 0 || minRating > 5 {
+			return filter, errors.New("minRating must be a number between 0 and 5")
+		}
+		filter.MinRating = minRating
+	}
+	return filter, nil
+}
+
+func CreateReview(w http.ResponseWriter, r *http.Request) {
+	productId, ok := uuidParam(w, r, "id", "invalid product id")
+	if !ok {
+		return
+	}
+	userId := getUserId(r)
+
+	var body model.ReviewRequest
+	if err := utils.ParseBody(r.Body, &body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
+		return
+	}
+	body.Title = strings.TrimSpace(body.Title)
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return
+	}
+
+	if _, err := dbHelper.GetProductById(productId); err != nil {
+		if err == sql.ErrNoRows {
+			utils.RespondError(w, http.StatusNotFound, err, "Product not found!")
+			return
+		}
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get product")
+		return
+	}
+
+	ordered, err := dbHelper.HasUserOrderedProduct(userId, productId)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to check order history")
+		return
+	}
+	if !ordered {
+		utils.RespondError(w, http.StatusForbidden, nil, "Only customers who ordered this product can review it")
+		return
+	}
+
+	exist, err := dbHelper.IsReviewExist(userId, productId)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to check review existence")
+		return
+	}
+	if exist {
+		utils.RespondError(w, http.StatusConflict, nil, "You have already reviewed this product")
+		return
+	}
+
+	reviewId, err := dbHelper.CreateReview(database.Audiophile, userId, productId, body)
+	if database.IsUniqueViolation(err) {
+		// a concurrent request created the review after the check above
+		utils.RespondError(w, http.StatusConflict, err, "You have already reviewed this product")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to create review")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, struct {
+		Message  string
+		ReviewId string
+	}{Message: "Review submitted for moderation", ReviewId: reviewId})
+}
+
+func GetProductReviews(w http.ResponseWriter, r *http.Request) {
+	productId, ok := uuidParam(w, r, "id", "invalid product id")
+	if !ok {
+		return
+	}
+	list, err := dbHelper.GetProductReviews(productId, model.ReviewStatusApproved)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get reviews")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, list)
+}
+
+func GetReviewsForModeration(w http.ResponseWriter, r *http.Request) {
+	status := model.ReviewStatus(r.URL.Query().Get("status"))
+	switch status {
+	case "", model.ReviewStatusPending, model.ReviewStatusApproved, model.ReviewStatusHidden:
+	default:
+		utils.RespondError(w, http.StatusBadRequest, nil, "unknown review status")
+		return
+	}
+	list, err := dbHelper.GetReviewsByStatus(status)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get reviews")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, list)
+}
+
+func ApproveReview(w http.ResponseWriter, r *http.Request) {
+	moderateReview(w, r, model.ReviewStatusApproved)
+}
+
+func HideReview(w http.ResponseWriter, r *http.Request) {
+	moderateReview(w, r, model.ReviewStatusHidden)
+}
+
+func moderateReview(w http.ResponseWriter, r *http.Request, status model.ReviewStatus) {
+	reviewId := chi.URLParam(r, "id")
+	updated, err := dbHelper.UpdateReviewStatus(database.Audiophile, reviewId, status)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to update review")
+		return
+	}
+	if !updated {
+		utils.RespondError(w, http.StatusNotFound, nil, "Review not found!")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string
+	}{fmt.Sprintf("Review %s", status)})
+}
@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestReviewRejectsInvalidProductId checks that an invalid product id is answered before anything
+// reaches the database
+func TestReviewRejectsInvalidProductId(t *testing.T) {
+	router := chi.NewRouter()
+	router.Get("/product/{id}/review", GetProductReviews)
+	router.Post("/product/{id}/review", CreateReview)
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		t.Run(method, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			body := strings.NewReader(`{"rating": 5, "title": "Great", "body": "Sounds great"}`)
+			router.ServeHTTP(recorder, httptest.NewRequest(method, "/product/not-a-uuid/review", body))
+			if recorder.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d: %s", recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"audio_phile/database"
+	"audio_phile/database/dbHelper"
+	"audio_phile/model"
+	"audio_phile/shipping"
+	"audio_phile/utils"
+	"database/sql"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+	"net/http"
+)
+
+func CreateShippingRate(w http.ResponseWriter, r *http.Request) {
+	var body model.ShippingRateRequest
+	if err := utils.ParseBody(r.Body, &body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
+		return
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return
+	}
+
+	rateId, err := dbHelper.CreateShippingRate(body)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to create shipping rate")
+		return
+	}
+	utils.RespondJSON(w, http.StatusCreated, struct {
+		Message        string
+		ShippingRateId string
+	}{Message: "Shipping rate created successfully", ShippingRateId: rateId})
+}
+
+func GetShippingRates(w http.ResponseWriter, r *http.Request) {
+	list, err := dbHelper.GetShippingRates(database.Audiophile)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get shipping rates")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, list)
+}
+
+func DeleteShippingRate(w http.ResponseWriter, r *http.Request) {
+	deleted, err := dbHelper.ArchiveShippingRate(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to delete shipping rate")
+		return
+	}
+	if !deleted {
+		utils.RespondError(w, http.StatusNotFound, nil, "Shipping rate not found!")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string
+	}{"Shipping rate deleted successfully"})
+}
+
+// SetProductWeight handles PUT /admin/product/{id}/weight, the shipping weight of one unit
+func SetProductWeight(w http.ResponseWriter, r *http.Request) {
+	var body model.ProductWeightRequest
+	if err := utils.ParseBody(r.Body, &body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
+		return
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return
+	}
+
+	updated, err := dbHelper.UpdateProductWeight(chi.URLParam(r, "id"), body.WeightGrams)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to update product weight")
+		return
+	}
+	if !updated {
+		utils.RespondError(w, http.StatusNotFound, nil, "Product not found!")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string
+	}{"Product weight updated successfully"})
+}
+
+// QuoteShipping handles POST /shipping/quote, what shipping a parcel to a location costs before anything is
+// in a cart
+func QuoteShipping(w http.ResponseWriter, r *http.Request) {
+	var body model.ShippingQuoteRequest
+	if err := utils.ParseBody(r.Body, &body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
+		return
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return
+	}
+
+	destination := shipping.Point{Lat: body.Lat, Long: body.Long}
+	quote, err := shipping.Quote(database.Audiophile, destination, body.WeightGrams, body.OrderValue)
+	if errors.Is(err, shipping.ErrNotShippable) || errors.Is(err, shipping.ErrNoOrigin) {
+		utils.RespondError(w, http.StatusUnprocessableEntity, err, err.Error())
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to quote shipping")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, quote)
+}
+
+func CreateDeliveryZone(w http.ResponseWriter, r *http.Request) {
+	var body model.DeliveryZoneRequest
+	if err := utils.ParseBody(r.Body, &body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
+		return
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return
+	}
+
+	var zoneId string
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		var err error
+		zoneId, err = shipping.CreateZone(tx, body)
+		return err
+	})
+	if txErr != nil {
+		utils.RespondError(w, http.StatusInternalServerError, txErr, "Failed to create delivery zone")
+		return
+	}
+	utils.RespondJSON(w, http.StatusCreated, struct {
+		Message        string
+		DeliveryZoneId string
+	}{Message: "Delivery zone created successfully", DeliveryZoneId: zoneId})
+}
+
+func GetDeliveryZones(w http.ResponseWriter, r *http.Request) {
+	zones, err := shipping.Zones(database.Audiophile, "")
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get delivery zones")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, zones)
+}
+
+func GetDeliveryZone(w http.ResponseWriter, r *http.Request) {
+	zones, err := shipping.Zones(database.Audiophile, chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.RespondError(w, http.StatusNotFound, err, "Delivery zone not found!")
+			return
+		}
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get delivery zone")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, zones[0])
+}
+
+func DeleteDeliveryZone(w http.ResponseWriter, r *http.Request) {
+	deleted, err := dbHelper.ArchiveDeliveryZone(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to delete delivery zone")
+		return
+	}
+	if !deleted {
+		utils.RespondError(w, http.StatusNotFound, nil, "Delivery zone not found!")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string
+	}{"Delivery zone deleted successfully"})
+}
+
+// CheckServiceability handles POST /shipping/serviceable, whether we deliver to a location or postal code
+func CheckServiceability(w http.ResponseWriter, r *http.Request) {
+	var body model.ServiceabilityRequest
+	if err := utils.ParseBody(r.Body, &body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
+		return
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return
+	}
+
+	var location *shipping.Point
+	if body.Lat != nil && body.Long != nil {
+		location = &shipping.Point{Lat: *body.Lat, Long: *body.Long}
+	}
+	result, err := shipping.Serviceable(database.Audiophile, location, body.PostalCode)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to check serviceability")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, result)
+}
@@ -1,10 +1,16 @@
 package handler
 
 import (
+	"audio_phile/cart"
 	"audio_phile/database"
 	"audio_phile/database/dbHelper"
+	"audio_phile/inventory"
 	"audio_phile/middleware"
 	"audio_phile/model"
+	"audio_phile/order"
+	"audio_phile/payment"
+	"audio_phile/pricing"
+	"audio_phile/shipping"
 	"audio_phile/utils"
 	"database/sql"
 	"errors"
@@ -14,7 +20,6 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/sirupsen/logrus"
 	"net/http"
-	"strconv"
 )
 
 func CreateUser(w http.ResponseWriter, r *http.Request) {
@@ -83,10 +88,16 @@ func CreateUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	//code could be 201
-	utils.RespondJSON(w, http.StatusCreated, model.UserResponseBody{
-		UserId: userID,
-		Name:   body.Name,
-		Email:  body.Email,
+	utils.RespondJSON(w, http.StatusCreated, struct {
+		model.UserResponseBody
+		MergedCart *model.CartMergeResult `json:"mergedCart,omitempty"`
+	}{
+		UserResponseBody: model.UserResponseBody{
+			UserId: userID,
+			Name:   body.Name,
+			Email:  body.Email,
+		},
+		MergedCart: mergeGuestCart(r, userID),
 	})
 }
 
@@ -132,9 +143,11 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	utils.RespondJSON(w, http.StatusOK, struct {
-		Token string `json:"token"`
+		Token      string                 `json:"token"`
+		MergedCart *model.CartMergeResult `json:"mergedCart,omitempty"`
 	}{
-		Token: token,
+		Token:      token,
+		MergedCart: mergeGuestCart(r, userId),
 	})
 }
 
@@ -171,10 +184,19 @@ func CreateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	productId, err := dbHelper.CreateProduct(database.Audiophile, body.Name, body.Description, body.IsAvailable, body.Price, body.Quantity, body.Category)
+	var productId string
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		var err error
+		productId, err = dbHelper.CreateProduct(tx, body.Name, body.Description, false, body.Price, 0, body.Category)
+		if err != nil {
+			return err
+		}
+		// stock enters through the ledger, which also derives is_available
+		return inventory.SetStockLevel(tx, productId, body.Quantity, getUserId(r), "initial stock")
+	})
 
-	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to create product")
+	if txErr != nil {
+		utils.RespondError(w, http.StatusInternalServerError, txErr, "Failed to create product")
 		return
 	}
 
@@ -184,16 +206,22 @@ func CreateProduct(w http.ResponseWriter, r *http.Request) {
 		Name:        body.Name,
 		Price:       body.Price,
 		Description: body.Description,
-		IsAvailable: body.IsAvailable,
+		IsAvailable: body.Quantity > 0,
 		Quantity:    body.Quantity,
 		Category:    body.Category,
 	})
 }
 
 func GetAllProduct(w http.ResponseWriter, r *http.Request) {
-	list, err := dbHelper.GetAllProduct()
+	filter, err := parseProductFilter(r)
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "invalid product filter")
+		return
+	}
+	list, err := dbHelper.GetAllProduct(filter)
 	logrus.Println(list)
 	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get products")
 		return
 	}
 	err = utils.EncodeJSONBody(w, list)
@@ -218,14 +246,34 @@ func CreatedAddress(w http.ResponseWriter, r *http.Request) {
 		utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
 		return
 	}
+	validate := validator.New()
+	if err := validate.Var(addresses, "dive"); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return
+	}
 	userId := getUserId(r)
-	for _, address := range addresses {
-		// save the address to the database
-		err := dbHelper.CreateAddresses(database.Audiophile, userId, address.Address, address.AddressType, address.Lat, address.Long)
-		if err != nil {
-			utils.RespondError(w, http.StatusInternalServerError, err, "Failed to create role")
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		for i, address := range addresses {
+			// an address we cannot deliver to is of no use at checkout
+			err := shipping.CheckAddress(tx, model.AddressModel{PostalCode: address.PostalCode, Lat: address.Lat, Long: address.Long})
+			if err != nil {
+				return fmt.Errorf("address %d: %w", i+1, err)
+			}
+			// save the address to the database
+			err = dbHelper.CreateAddresses(tx, userId, address.Address, address.AddressType, address.Region, address.PostalCode, address.Lat, address.Long)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if txErr != nil {
+		if errors.Is(txErr, shipping.ErrNotServiceable) {
+			utils.RespondError(w, http.StatusUnprocessableEntity, txErr, txErr.Error())
 			return
 		}
+		utils.RespondError(w, http.StatusInternalServerError, txErr, "Failed to create address")
+		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, struct {
 		Message string
@@ -285,162 +333,129 @@ func DeleteUserByUserId(w http.ResponseWriter, r *http.Request) {
 	}{"User deleted successfully!"})
 }
 
-func CreateProductToCart(w http.ResponseWriter, r *http.Request) {
+func GetCartWithProductById(w http.ResponseWriter, r *http.Request) {
 	userId := getUserId(r)
-	productId := chi.URLParam(r, "id")
-	quantityStr := chi.URLParam(r, "quantity")
-	quantity, err := strconv.Atoi(quantityStr)
-	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to covert from string to int")
-		return
-	}
-
-	productDetail, err := dbHelper.GetProductById(productId)
+	cartId, exist, err := dbHelper.GetActiveCartId(database.Audiophile, userId)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err, "Product not found!")
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to check cart existence")
 		return
 	}
-
-	if quantity > productDetail.Quantity {
-		utils.RespondError(w, http.StatusBadRequest, nil, "Requested quantity not available")
+	if !exist {
+		utils.RespondJSON(w, http.StatusOK, model.Cart{Items: make([]model.CartProduct, 0)})
 		return
 	}
-
-	existingCartId, exist, existErr := dbHelper.IsCartExist(userId, model.CartStatusActive)
-
-	if existErr != nil {
-		utils.RespondError(w, http.StatusInternalServerError, existErr, "Failed to check cart existence")
+	userCart, err := cart.Get(database.Audiophile, cartId)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get cart")
 		return
 	}
-
-	if exist {
-		err := dbHelper.CreateProductInCart(database.Audiophile, existingCartId, productId, quantity)
-		if err != nil {
-			utils.RespondError(w, http.StatusInternalServerError, err, "Failed to add product")
-			return
-		}
-	} else {
-		txErr := database.Tx(func(tx *sqlx.Tx) error {
-			cartId, err := dbHelper.CreateCart(tx, userId, model.CartStatusActive)
-			if err != nil {
-				utils.RespondError(w, http.StatusInternalServerError, err, "Failed to create cart")
-				return err
-			}
-
-			err = dbHelper.CreateProductInCart(tx, cartId, productId, quantity)
-			if err != nil {
-				utils.RespondError(w, http.StatusInternalServerError, err, "Failed to add product")
-				return err
-			}
-			return nil
-		})
-		// error message correct karo
-		if txErr != nil {
-			utils.RespondError(w, http.StatusInternalServerError, txErr, "transaction error")
+	if err := priceCart(database.Audiophile, r, userId, &userCart); err != nil {
+		if errors.Is(err, errAddressNotFound) {
+			utils.RespondError(w, http.StatusNotFound, err, "Address not found!")
 			return
 		}
-	}
-
-	utils.RespondJSON(w, http.StatusCreated, struct {
-		Message string
-	}{"Product added to cart!"})
-}
-
-func GetCartWithProductById(w http.ResponseWriter, r *http.Request) {
-	userId := getUserId(r)
-	list, err := dbHelper.GetCartWithProduct(database.Audiophile, userId)
-	logrus.Println(list)
-	if err != nil {
-		return
-	}
-	err = utils.EncodeJSONBody(w, list)
-	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to price cart")
 		return
 	}
+	utils.RespondJSON(w, http.StatusOK, userCart)
 }
 
-func AddProductQuantityInCart(w http.ResponseWriter, r *http.Request) {
-	cartProductId := chi.URLParam(r, "cartId")
-	productId := chi.URLParam(r, "productId")
-
-	cartDetail, err := dbHelper.GetCartProductQuantity(cartProductId, productId)
-
-	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err, "Cart detail not found!")
-		return
-	}
-
-	productDetail, err := dbHelper.GetProductById(productId)
+func CheckoutCart(w http.ResponseWriter, r *http.Request) {
+	userId := getUserId(r)
+	cartId, exist, err := dbHelper.GetActiveCartId(database.Audiophile, userId)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err, "Product not found!")
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to check cart existence")
 		return
 	}
-
-	if cartDetail.Quantity >= productDetail.Quantity {
-		utils.RespondError(w, http.StatusBadRequest, nil, "Requested quantity not available")
+	if !exist {
+		utils.RespondError(w, http.StatusNotFound, nil, "Cart not found!")
 		return
 	}
 
-	err = dbHelper.AddProductQuantityInCart(cartProductId, productId)
-	if err != nil {
+	var reservations []model.StockReservation
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		reservations, err = inventory.ReserveCart(tx, cartId)
+		return err
+	})
+	if txErr != nil {
+		respondStockError(w, txErr, "Failed to reserve stock")
 		return
 	}
 
 	utils.RespondJSON(w, http.StatusOK, struct {
-		Message string
-	}{"Quantity updated successfully"})
+		CartId       string
+		Reservations []model.StockReservation
+	}{CartId: cartId, Reservations: reservations})
 }
 
-func RemoveProductQuantityInCart(w http.ResponseWriter, r *http.Request) {
-	cartProductId := chi.URLParam(r, "cartId")
-	productId := chi.URLParam(r, "productId")
-
-	cartDetail, err := dbHelper.GetCartProductQuantity(cartProductId, productId)
-
-	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err, "Cart detail not found!")
+// CreateOrder places the order for the caller's active cart, shipped to the addressId query parameter
+// or to the user's latest address, and pays it with the methods of the optional body
+func CreateOrder(w http.ResponseWriter, r *http.Request) {
+	userId := getUserId(r)
+	cartId := middleware.CartIdFromContext(r)
+	method, ok := parsePaymentRequest(w, r)
+	if !ok {
 		return
 	}
-
-	if cartDetail.Quantity <= 0 {
-		utils.RespondError(w, http.StatusBadRequest, nil, "Requested quantity not available")
+	var placed model.PlacedOrder
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		address, err := shippingAddress(tx, r, userId)
+		if err != nil {
+			return err
+		}
+		placed, err = order.Checkout(tx, userId, cartId, address)
+		return err
+	})
+	if txErr != nil {
+		switch {
+		case errors.Is(txErr, errAddressNotFound):
+			utils.RespondError(w, http.StatusNotFound, txErr, "Address not found!")
+		case errors.Is(txErr, order.ErrAddressRequired):
+			utils.RespondError(w, http.StatusBadRequest, txErr, "Add a shipping address before placing an order")
+		case errors.Is(txErr, order.ErrCartNotActive):
+			utils.RespondError(w, http.StatusConflict, txErr, "Cart was already ordered")
+		default:
+			respondStockError(w, txErr, "Failed to place order")
+		}
 		return
 	}
 
-	err = dbHelper.RemoveProductQuantityInCart(cartProductId, productId)
-	if err != nil {
-		return
+	// the order stands even when the payment does not go through, it can be paid again later
+	payments, err := payment.Pay(placed.OrderId, userId, method)
+	if err != nil && !errors.Is(err, payment.ErrPaymentFailed) && !errors.Is(err, payment.ErrOrderNotPayable) {
+		logrus.Errorf("failed to pay order %s with error: %+v", placed.OrderId, err)
+	}
+	if current, err := dbHelper.GetOrder(database.Audiophile, placed.OrderId, userId); err == nil {
+		placed.Status = current.Status
 	}
 
 	utils.RespondJSON(w, http.StatusOK, struct {
-		Message string
-	}{"Quantity updated successfully"})
-
+		Message      string
+		Order        model.PlacedOrder
+		Payments     []model.Payment `json:",omitempty"`
+		PaymentError string          `json:",omitempty"`
+	}{Message: "Order placed successfully", Order: placed, Payments: payments, PaymentError: errorText(err)})
 }
 
-func DeleteProductFromCart(w http.ResponseWriter, r *http.Request) {
-	cartProductId := chi.URLParam(r, "cartId")
-	productId := chi.URLParam(r, "productId")
-
-	err := dbHelper.DeleteProductFromCart(cartProductId, productId)
-	if err != nil {
-		return
+func errorText(err error) string {
+	if err == nil {
+		return ""
 	}
-
-	utils.RespondJSON(w, http.StatusOK, struct {
-		Message string
-	}{"Product deleted successfully"})
+	return err.Error()
 }
 
-func CreateOrder(w http.ResponseWriter, r *http.Request) {
-	cartProductId := chi.URLParam(r, "cartId")
-	orderId, err := dbHelper.CreateOrder(cartProductId)
-	if err != nil {
-		return
+// respondStockError maps inventory failures to client errors and everything else to a 500
+func respondStockError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case inventory.IsInsufficientStock(err):
+		utils.RespondError(w, http.StatusConflict, err, "Requested quantity not available")
+	case errors.Is(err, pricing.ErrCouponNotApplicable):
+		utils.RespondError(w, http.StatusConflict, err, err.Error())
+	case errors.Is(err, pricing.ErrNotShippable), errors.Is(err, shipping.ErrNotServiceable):
+		utils.RespondError(w, http.StatusBadRequest, err, err.Error())
+	case errors.Is(err, inventory.ErrEmptyCart):
+		utils.RespondError(w, http.StatusBadRequest, err, "Cart is empty")
+	default:
+		utils.RespondError(w, http.StatusInternalServerError, err, message)
 	}
-
-	utils.RespondJSON(w, http.StatusOK, struct {
-		Message string
-		OrderId string
-	}{Message: "Order placed successfully", OrderId: orderId})
 }
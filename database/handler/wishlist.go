@@ -0,0 +1,269 @@
+package handler
+
+import (
+	"audio_phile/cart"
+	"audio_phile/database"
+	"audio_phile/database/dbHelper"
+	"audio_phile/inventory"
+	"audio_phile/model"
+	"audio_phile/utils"
+	"audio_phile/wishlist"
+	"database/sql"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+	"net/http"
+)
+
+func CreateWishlist(w http.ResponseWriter, r *http.Request) {
+	var body model.WishlistRequest
+	if err := utils.ParseBody(r.Body, &body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
+		return
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return
+	}
+	userId := getUserId(r)
+
+	taken, err := dbHelper.IsWishlistNameTaken(database.Audiophile, userId, body.Name)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to check wishlist existence")
+		return
+	}
+	if taken {
+		utils.RespondError(w, http.StatusBadRequest, nil, "Wishlist already exist")
+		return
+	}
+
+	wishlistId, err := dbHelper.CreateWishlist(database.Audiophile, userId, body.Name)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to create wishlist")
+		return
+	}
+	utils.RespondJSON(w, http.StatusCreated, struct {
+		Message    string
+		WishlistId string
+	}{Message: "Wishlist created successfully", WishlistId: wishlistId})
+}
+
+func GetWishlists(w http.ResponseWriter, r *http.Request) {
+	list, err := dbHelper.GetWishlists(database.Audiophile, getUserId(r))
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get wishlists")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, list)
+}
+
+func GetWishlist(w http.ResponseWriter, r *http.Request) {
+	owned, err := wishlist.Owned(database.Audiophile, getUserId(r), chi.URLParam(r, "id"))
+	if err != nil {
+		respondWishlistError(w, err, "Failed to get wishlist")
+		return
+	}
+	detail, err := wishlist.Detail(database.Audiophile, owned)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get wishlist")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, detail)
+}
+
+func DeleteWishlist(w http.ResponseWriter, r *http.Request) {
+	deleted, err := dbHelper.ArchiveWishlist(database.Audiophile, getUserId(r), chi.URLParam(r, "id"))
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to delete wishlist")
+		return
+	}
+	if !deleted {
+		utils.RespondError(w, http.StatusNotFound, nil, "Wishlist not found!")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string
+	}{"Wishlist deleted successfully"})
+}
+
+// ShareWishlist handles POST /wishlist/{id}/share and returns the public read-only link of the list
+func ShareWishlist(w http.ResponseWriter, r *http.Request) {
+	var token string
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		owned, err := wishlist.Owned(tx, getUserId(r), chi.URLParam(r, "id"))
+		if err != nil {
+			return err
+		}
+		token, err = wishlist.Share(tx, owned)
+		return err
+	})
+	if txErr != nil {
+		respondWishlistError(w, txErr, "Failed to share wishlist")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, struct {
+		ShareToken string `json:"shareToken"`
+		Link       string `json:"link"`
+	}{ShareToken: token, Link: "/api/wishlist/shared/" + token})
+}
+
+// UnshareWishlist revokes the public link, a later share hands out a new one
+func UnshareWishlist(w http.ResponseWriter, r *http.Request) {
+	owned, err := wishlist.Owned(database.Audiophile, getUserId(r), chi.URLParam(r, "id"))
+	if err != nil {
+		respondWishlistError(w, err, "Failed to unshare wishlist")
+		return
+	}
+	if err := dbHelper.SetWishlistShareToken(database.Audiophile, owned.Id, nil); err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to unshare wishlist")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string
+	}{"Wishlist is no longer shared"})
+}
+
+// GetSharedWishlist is the public read-only view of a shared list
+func GetSharedWishlist(w http.ResponseWriter, r *http.Request) {
+	shared, err := dbHelper.GetWishlistByShareToken(database.Audiophile, chi.URLParam(r, "token"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.RespondError(w, http.StatusNotFound, err, "Wishlist not found!")
+			return
+		}
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get wishlist")
+		return
+	}
+	items, err := dbHelper.GetWishlistItems(database.Audiophile, shared.Id)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to get wishlist")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Name  string               `json:"name"`
+		Items []model.WishlistItem `json:"items"`
+	}{Name: shared.Name, Items: items})
+}
+
+// SetWishlistItem handles PUT /wishlist/{id}/items/{productId}, a missing or zero quantity saves one unit
+func SetWishlistItem(w http.ResponseWriter, r *http.Request) {
+	var body model.WishlistItemRequest
+	if r.ContentLength != 0 {
+		if err := utils.ParseBody(r.Body, &body); err != nil {
+			utils.RespondError(w, http.StatusBadRequest, err, "Failed to parse request body")
+			return
+		}
+	}
+	validate := validator.New()
+	if err := validate.Struct(body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err, "input field is invalid")
+		return
+	}
+	if body.Quantity == 0 {
+		body.Quantity = 1
+	}
+	productId, ok := productIdParam(w, r)
+	if !ok {
+		return
+	}
+
+	owned, err := wishlist.Owned(database.Audiophile, getUserId(r), chi.URLParam(r, "id"))
+	if err != nil {
+		respondWishlistError(w, err, "Failed to update wishlist")
+		return
+	}
+	saved, err := dbHelper.UpsertWishlistItem(database.Audiophile, owned.Id, productId, body.Quantity)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to update wishlist")
+		return
+	}
+	if !saved {
+		utils.RespondError(w, http.StatusNotFound, nil, "Product not found!")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string
+	}{"Product saved to wishlist"})
+}
+
+func RemoveWishlistItem(w http.ResponseWriter, r *http.Request) {
+	productId, ok := productIdParam(w, r)
+	if !ok {
+		return
+	}
+	owned, err := wishlist.Owned(database.Audiophile, getUserId(r), chi.URLParam(r, "id"))
+	if err != nil {
+		respondWishlistError(w, err, "Failed to update wishlist")
+		return
+	}
+	deleted, err := dbHelper.DeleteWishlistItem(database.Audiophile, owned.Id, productId)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, err, "Failed to update wishlist")
+		return
+	}
+	if !deleted {
+		utils.RespondError(w, http.StatusNotFound, nil, "Item not found!")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string
+	}{"Product removed from wishlist"})
+}
+
+// MoveWishlistItemToCart handles POST /wishlist/{id}/items/{productId}/move-to-cart
+func MoveWishlistItemToCart(w http.ResponseWriter, r *http.Request) {
+	productId, ok := productIdParam(w, r)
+	if !ok {
+		return
+	}
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		return wishlist.MoveToCart(tx, getUserId(r), chi.URLParam(r, "id"), productId)
+	})
+	if txErr != nil {
+		respondWishlistError(w, txErr, "Failed to move item to cart")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message string
+	}{"Product moved to cart"})
+}
+
+// SaveCartItemForLater handles POST /cart/items/{productId}/save-for-later, the optional wishlistId
+// query parameter picks the list, the Saved for later list is used otherwise
+func SaveCartItemForLater(w http.ResponseWriter, r *http.Request) {
+	productId, ok := productIdParam(w, r)
+	if !ok {
+		return
+	}
+	var wishlistId string
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		var err error
+		wishlistId, err = wishlist.SaveForLater(tx, getUserId(r), r.URL.Query().Get("wishlistId"), productId)
+		return err
+	})
+	if txErr != nil {
+		respondWishlistError(w, txErr, "Failed to save item for later")
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, struct {
+		Message    string
+		WishlistId string
+	}{Message: "Product saved for later", WishlistId: wishlistId})
+}
+
+func respondWishlistError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, wishlist.ErrWishlistNotFound):
+		utils.RespondError(w, http.StatusNotFound, err, "Wishlist not found!")
+	case errors.Is(err, wishlist.ErrItemNotFound):
+		utils.RespondError(w, http.StatusNotFound, err, "Item not found!")
+	case errors.Is(err, cart.ErrProductNotFound):
+		utils.RespondError(w, http.StatusNotFound, err, "Product not found!")
+	case inventory.IsInsufficientStock(err):
+		utils.RespondError(w, http.StatusConflict, err, "Requested quantity not available")
+	default:
+		utils.RespondError(w, http.StatusInternalServerError, err, message)
+	}
+}
@@ -0,0 +1,4 @@
+ALTER TABLE products
+    ADD COLUMN sku TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS products_sku_unique ON products (sku) WHERE archived_at IS NULL AND sku IS NOT NULL;
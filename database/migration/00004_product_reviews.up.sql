@@ -0,0 +1,22 @@
+CREATE TYPE review_status AS ENUM (
+    'pending',
+    'approved',
+    'hidden'
+    );
+
+CREATE TABLE IF NOT EXISTS product_reviews
+(
+    id          UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    product_id  UUID REFERENCES products (id) NOT NULL,
+    user_id     UUID REFERENCES users (id)    NOT NULL,
+    rating      SMALLINT                      NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    title       TEXT                          NOT NULL,
+    body        TEXT                          NOT NULL DEFAULT '',
+    status      review_status                 NOT NULL DEFAULT 'pending',
+    created_at  TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at  TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    archived_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS product_reviews_user_product_unique ON product_reviews (user_id, product_id) WHERE archived_at IS NULL;
+CREATE INDEX IF NOT EXISTS product_reviews_product_status_idx ON product_reviews (product_id, status);
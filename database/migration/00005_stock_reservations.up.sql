@@ -0,0 +1,23 @@
+ALTER TABLE products
+    ADD CONSTRAINT products_quantity_non_negative CHECK (quantity >= 0);
+
+CREATE TYPE reservation_status AS ENUM (
+    'active',
+    'released',
+    'committed'
+    );
+
+CREATE TABLE IF NOT EXISTS stock_reservations
+(
+    id         UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    cart_id    UUID REFERENCES carts (id)    NOT NULL,
+    product_id UUID REFERENCES products (id) NOT NULL,
+    quantity   INTEGER                       NOT NULL CHECK (quantity > 0),
+    status     reservation_status            NOT NULL DEFAULT 'active',
+    expires_at TIMESTAMP WITH TIME ZONE      NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS stock_reservations_active_idx ON stock_reservations (product_id, expires_at) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS stock_reservations_cart_idx ON stock_reservations (cart_id) WHERE status = 'active';
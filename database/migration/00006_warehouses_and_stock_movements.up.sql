@@ -0,0 +1,81 @@
+CREATE TABLE IF NOT EXISTS warehouses
+(
+    id          UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    code        TEXT    NOT NULL,
+    name        TEXT    NOT NULL,
+    address     TEXT    NOT NULL DEFAULT '',
+    lat         DECIMAL NOT NULL DEFAULT 0,
+    long        DECIMAL NOT NULL DEFAULT 0,
+    is_default  BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at  TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    archived_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS warehouses_code_unique ON warehouses (code) WHERE archived_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS warehouses_default_unique ON warehouses (is_default) WHERE is_default AND archived_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS warehouse_stock
+(
+    warehouse_id UUID REFERENCES warehouses (id) NOT NULL,
+    product_id   UUID REFERENCES products (id)   NOT NULL,
+    quantity     INTEGER                         NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+    updated_at   TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (warehouse_id, product_id)
+);
+
+CREATE TYPE movement_type AS ENUM (
+    'receipt',
+    'sale',
+    'return',
+    'adjustment',
+    'transfer'
+    );
+
+CREATE TABLE IF NOT EXISTS stock_movements
+(
+    id             UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    product_id     UUID REFERENCES products (id)   NOT NULL,
+    warehouse_id   UUID REFERENCES warehouses (id) NOT NULL,
+    movement_type  movement_type                   NOT NULL,
+    quantity       INTEGER                         NOT NULL CHECK (quantity <> 0),
+    reason         TEXT                            NOT NULL DEFAULT '',
+    actor_id       UUID REFERENCES users (id),
+    reference_id   TEXT,
+    correlation_id UUID,
+    created_at     TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS stock_movements_product_idx ON stock_movements (product_id, created_at);
+CREATE INDEX IF NOT EXISTS stock_movements_warehouse_idx ON stock_movements (warehouse_id, created_at);
+
+-- the ledger is append-only, corrections are recorded as new adjustment movements
+CREATE OR REPLACE FUNCTION stock_movements_append_only() RETURNS TRIGGER AS
+$$
+BEGIN
+    RAISE EXCEPTION 'stock_movements is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER stock_movements_no_update_delete
+    BEFORE UPDATE OR DELETE
+    ON stock_movements
+    FOR EACH ROW
+EXECUTE FUNCTION stock_movements_append_only();
+
+-- move the existing single stock counter into a default warehouse with an opening balance
+INSERT INTO warehouses(code, name, is_default)
+VALUES ('MAIN', 'Main warehouse', TRUE);
+
+INSERT INTO warehouse_stock(warehouse_id, product_id, quantity)
+SELECT w.id, p.id, p.quantity
+FROM products p,
+     warehouses w
+WHERE w.code = 'MAIN'
+  AND p.quantity > 0;
+
+INSERT INTO stock_movements(product_id, warehouse_id, movement_type, quantity, reason)
+SELECT ws.product_id, ws.warehouse_id, 'adjustment', ws.quantity, 'opening balance'
+FROM warehouse_stock ws;
+
+UPDATE products
+SET is_available = quantity > 0;
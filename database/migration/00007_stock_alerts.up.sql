@@ -0,0 +1,25 @@
+ALTER TABLE products
+    ADD COLUMN reorder_threshold INTEGER NOT NULL DEFAULT 0 CHECK (reorder_threshold >= 0),
+    ADD COLUMN low_stock_alerted BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TYPE stock_alert_kind AS ENUM (
+    'low_stock',
+    'out_of_stock',
+    'back_in_stock'
+    );
+
+-- alerts are written in the same transaction as the stock change and delivered afterwards
+CREATE TABLE IF NOT EXISTS stock_alerts
+(
+    id                UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    product_id        UUID REFERENCES products (id) NOT NULL,
+    kind              stock_alert_kind              NOT NULL,
+    sellable_quantity INTEGER                       NOT NULL,
+    threshold         INTEGER                       NOT NULL,
+    attempts          INTEGER                       NOT NULL DEFAULT 0,
+    last_error        TEXT,
+    created_at        TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    delivered_at      TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS stock_alerts_pending_idx ON stock_alerts (created_at) WHERE delivered_at IS NULL;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS restock_subscriptions
+(
+    id          UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    product_id  UUID REFERENCES products (id) NOT NULL,
+    user_id     UUID REFERENCES users (id)    NOT NULL,
+    created_at  TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    queued_at   TIMESTAMP WITH TIME ZONE,
+    notified_at TIMESTAMP WITH TIME ZONE,
+    archived_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS restock_subscriptions_active_unique ON restock_subscriptions (user_id, product_id) WHERE notified_at IS NULL AND archived_at IS NULL;
+CREATE INDEX IF NOT EXISTS restock_subscriptions_queue_idx ON restock_subscriptions (queued_at, created_at) WHERE queued_at IS NOT NULL AND notified_at IS NULL AND archived_at IS NULL;
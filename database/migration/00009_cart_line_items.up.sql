@@ -0,0 +1,38 @@
+ALTER TABLE cart_products
+    ADD COLUMN updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW();
+
+-- merge duplicate live lines of the same product into the oldest one
+WITH ranked AS (
+    SELECT id,
+           ROW_NUMBER() OVER (PARTITION BY cart_id, product_id ORDER BY created_at, id) AS position,
+           SUM(quantity) OVER (PARTITION BY cart_id, product_id)                        AS total
+    FROM cart_products
+    WHERE archived_at IS NULL
+)
+UPDATE cart_products cp
+SET quantity = r.total
+FROM ranked r
+WHERE cp.id = r.id
+  AND r.position = 1;
+
+WITH ranked AS (
+    SELECT id,
+           ROW_NUMBER() OVER (PARTITION BY cart_id, product_id ORDER BY created_at, id) AS position
+    FROM cart_products
+    WHERE archived_at IS NULL
+)
+UPDATE cart_products cp
+SET archived_at = NOW()
+FROM ranked r
+WHERE cp.id = r.id
+  AND r.position > 1;
+
+UPDATE cart_products
+SET archived_at = NOW()
+WHERE archived_at IS NULL
+  AND quantity <= 0;
+
+CREATE UNIQUE INDEX IF NOT EXISTS cart_products_active_line_unique ON cart_products (cart_id, product_id) WHERE archived_at IS NULL;
+
+ALTER TABLE cart_products
+    ADD CONSTRAINT cart_products_live_quantity_positive CHECK (archived_at IS NOT NULL OR quantity > 0);
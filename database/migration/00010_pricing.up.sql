@@ -0,0 +1,24 @@
+ALTER TABLE user_addresses
+    ADD COLUMN region TEXT NOT NULL DEFAULT '';
+
+-- rate_bps is in basis points, 1800 = 18%. NULL category or region matches any.
+CREATE TABLE IF NOT EXISTS tax_rates
+(
+    id          UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    name        TEXT    NOT NULL,
+    category    category,
+    region      TEXT,
+    rate_bps    INTEGER NOT NULL CHECK (rate_bps >= 0 AND rate_bps <= 10000),
+    created_at  TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    archived_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS tax_rates_scope_unique ON tax_rates (COALESCE(category::text, ''), COALESCE(UPPER(region), '')) WHERE archived_at IS NULL;
+
+-- amounts are stored in minor units (1/100 of the currency)
+ALTER TABLE orders
+    ADD COLUMN address_id     UUID REFERENCES user_addresses (id),
+    ADD COLUMN subtotal       BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN discount_total BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN tax_total      BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN grand_total    BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,47 @@
+CREATE TYPE promotion_kind AS ENUM (
+    'percentage',
+    'fixed_amount',
+    'free_shipping',
+    'buy_x_get_y',
+    'category_discount'
+    );
+
+-- a promotion with a code is a coupon and only applies once entered on a cart, the others apply automatically.
+-- value is a percentage for percentage and category_discount and an amount in currency units for fixed_amount.
+CREATE TABLE IF NOT EXISTS promotions
+(
+    id             UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    name           TEXT           NOT NULL,
+    code           TEXT,
+    kind           promotion_kind NOT NULL,
+    value          INTEGER        NOT NULL  DEFAULT 0 CHECK (value >= 0),
+    category       category,
+    product_id     UUID REFERENCES products (id),
+    buy_quantity   INTEGER        NOT NULL  DEFAULT 0 CHECK (buy_quantity >= 0),
+    get_quantity   INTEGER        NOT NULL  DEFAULT 0 CHECK (get_quantity >= 0),
+    min_cart_value INTEGER        NOT NULL  DEFAULT 0 CHECK (min_cart_value >= 0),
+    usage_limit    INTEGER CHECK (usage_limit > 0),
+    per_user_limit INTEGER CHECK (per_user_limit > 0),
+    starts_at      TIMESTAMP WITH TIME ZONE,
+    ends_at        TIMESTAMP WITH TIME ZONE,
+    created_at     TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at     TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    archived_at    TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS promotions_code_unique ON promotions (UPPER(code)) WHERE archived_at IS NULL AND code IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS promotion_redemptions
+(
+    id           UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    promotion_id UUID REFERENCES promotions (id) NOT NULL,
+    user_id      UUID REFERENCES users (id)      NOT NULL,
+    order_id     UUID REFERENCES orders (id)     NOT NULL,
+    amount       BIGINT                          NOT NULL,
+    created_at   TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS promotion_redemptions_promotion_user ON promotion_redemptions (promotion_id, user_id);
+
+ALTER TABLE carts
+    ADD COLUMN coupon_id UUID REFERENCES promotions (id);
@@ -0,0 +1,8 @@
+-- guest carts have no user and are found by the sha256 of the token handed to the visitor
+ALTER TABLE carts
+    ALTER COLUMN user_id DROP NOT NULL,
+    ADD COLUMN guest_token_hash TEXT,
+    ADD COLUMN merged_into      UUID REFERENCES carts (id),
+    ADD CONSTRAINT carts_owner_check CHECK (user_id IS NOT NULL OR guest_token_hash IS NOT NULL);
+
+CREATE UNIQUE INDEX IF NOT EXISTS carts_guest_token_unique ON carts (guest_token_hash) WHERE guest_token_hash IS NOT NULL;
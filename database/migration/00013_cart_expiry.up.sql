@@ -0,0 +1,26 @@
+ALTER TABLE carts
+    ADD COLUMN reminded_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN expired_at  TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS carts_active_idle ON carts (update_at) WHERE status = 'active';
+
+CREATE TYPE job_status AS ENUM (
+    'running',
+    'succeeded',
+    'failed'
+    );
+
+-- one row per run of a background job, updated while it runs so admins can follow its progress
+CREATE TABLE IF NOT EXISTS job_runs
+(
+    id          UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    job         TEXT       NOT NULL,
+    status      job_status NOT NULL      DEFAULT 'running',
+    processed   INTEGER    NOT NULL      DEFAULT 0,
+    failed      INTEGER    NOT NULL      DEFAULT 0,
+    last_error  TEXT,
+    started_at  TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS job_runs_job_started ON job_runs (job, started_at DESC);
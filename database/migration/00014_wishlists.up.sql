@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS wishlists
+(
+    id          UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    user_id     UUID REFERENCES users (id) NOT NULL,
+    name        TEXT                       NOT NULL,
+    share_token TEXT,
+    created_at  TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at  TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    archived_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS wishlists_user_name_unique ON wishlists (user_id, LOWER(name)) WHERE archived_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS wishlists_share_token_unique ON wishlists (share_token) WHERE share_token IS NOT NULL;
+
+-- price_at_add is the product price when it was saved, a lower current price flags a price drop
+CREATE TABLE IF NOT EXISTS wishlist_items
+(
+    id           UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    wishlist_id  UUID REFERENCES wishlists (id) NOT NULL,
+    product_id   UUID REFERENCES products (id)  NOT NULL,
+    quantity     INTEGER                        NOT NULL DEFAULT 1 CHECK (quantity > 0),
+    price_at_add INTEGER                        NOT NULL,
+    created_at   TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at   TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    archived_at  TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS wishlist_items_product_unique ON wishlist_items (wishlist_id, product_id) WHERE archived_at IS NULL;
@@ -0,0 +1,31 @@
+CREATE TYPE order_status AS ENUM (
+    'pending_payment',
+    'paid',
+    'packed',
+    'shipped',
+    'delivered',
+    'cancelled',
+    'refunded'
+    );
+
+ALTER TABLE orders
+    ADD COLUMN status     order_status NOT NULL DEFAULT 'pending_payment',
+    ADD COLUMN updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW();
+
+-- every status an order went through, from_status is NULL for the order's creation
+CREATE TABLE IF NOT EXISTS order_status_history
+(
+    id          UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    order_id    UUID REFERENCES orders (id) NOT NULL,
+    from_status order_status,
+    to_status   order_status                NOT NULL,
+    actor_id    UUID REFERENCES users (id),
+    note        TEXT,
+    created_at  TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS order_status_history_order ON order_status_history (order_id, created_at);
+
+INSERT INTO order_status_history(order_id, to_status, created_at)
+SELECT id, status, created_at
+FROM orders;
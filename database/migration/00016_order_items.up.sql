@@ -0,0 +1,35 @@
+-- amounts are in minor units, copied from the priced cart when the order is placed so later catalog
+-- edits never change what an order contains or cost
+CREATE TABLE IF NOT EXISTS order_items
+(
+    id         UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    order_id   UUID REFERENCES orders (id)   NOT NULL,
+    product_id UUID REFERENCES products (id) NOT NULL,
+    name       TEXT                          NOT NULL,
+    category   category                      NOT NULL,
+    unit_price BIGINT                        NOT NULL,
+    quantity   INTEGER                       NOT NULL CHECK (quantity > 0),
+    line_total BIGINT                        NOT NULL,
+    discount   BIGINT                        NOT NULL DEFAULT 0,
+    tax_rate   INTEGER                       NOT NULL DEFAULT 0,
+    tax        BIGINT                        NOT NULL DEFAULT 0,
+    total      BIGINT                        NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS order_items_order ON order_items (order_id);
+
+-- the shipping address is copied too, the user may edit or delete it later
+ALTER TABLE orders
+    ADD COLUMN user_id          UUID REFERENCES users (id),
+    ADD COLUMN shipping_address TEXT,
+    ADD COLUMN shipping_region  TEXT,
+    ADD COLUMN shipping_lat     DECIMAL,
+    ADD COLUMN shipping_long    DECIMAL;
+
+UPDATE orders o
+SET user_id = c.user_id
+FROM carts c
+WHERE o.cart_id = c.id;
+
+CREATE INDEX IF NOT EXISTS orders_user_created ON orders (user_id, created_at DESC);
@@ -0,0 +1,6 @@
+ALTER TABLE orders
+    ADD COLUMN carrier         TEXT,
+    ADD COLUMN tracking_number TEXT,
+    ADD COLUMN tracking_url    TEXT,
+    ADD COLUMN shipped_at      TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN delivered_at    TIMESTAMP WITH TIME ZONE;
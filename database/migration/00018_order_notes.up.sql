@@ -0,0 +1,12 @@
+-- internal notes by staff, never shown to the customer
+CREATE TABLE IF NOT EXISTS order_notes
+(
+    id         UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    order_id   UUID REFERENCES orders (id) NOT NULL,
+    author_id  UUID REFERENCES users (id)  NOT NULL,
+    body       TEXT                        NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS order_notes_order ON order_notes (order_id, created_at);
+CREATE INDEX IF NOT EXISTS orders_status_created ON orders (status, created_at DESC);
@@ -0,0 +1,28 @@
+CREATE TYPE payment_status AS ENUM (
+    'created',
+    'pending',
+    'captured',
+    'failed',
+    'refunded'
+    );
+
+-- created: intent being set up, pending: capture sent and awaiting the provider's confirmation.
+-- amounts are in minor units.
+CREATE TABLE IF NOT EXISTS payments
+(
+    id              UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    order_id        UUID REFERENCES orders (id) NOT NULL,
+    provider        TEXT                        NOT NULL,
+    intent_id       TEXT,
+    amount          BIGINT                      NOT NULL CHECK (amount > 0),
+    currency        TEXT                        NOT NULL,
+    status          payment_status              NOT NULL DEFAULT 'created',
+    refunded_amount BIGINT                      NOT NULL DEFAULT 0 CHECK (refunded_amount >= 0 AND refunded_amount <= amount),
+    failure_reason  TEXT,
+    captured_at     TIMESTAMP WITH TIME ZONE,
+    created_at      TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at      TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS payments_provider_intent_unique ON payments (provider, intent_id) WHERE intent_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS payments_order ON payments (order_id, created_at);
@@ -0,0 +1,16 @@
+-- raw webhook events as received, after their signature was verified. event_id is the provider's id and
+-- deduplicates redeliveries.
+CREATE TABLE IF NOT EXISTS payment_webhook_events
+(
+    id           UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    provider     TEXT    NOT NULL,
+    event_id     TEXT    NOT NULL,
+    event_type   TEXT    NOT NULL,
+    payload      TEXT    NOT NULL,
+    attempts     INTEGER NOT NULL         DEFAULT 0,
+    last_error   TEXT,
+    received_at  TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    processed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS payment_webhook_events_unique ON payment_webhook_events (provider, event_id);
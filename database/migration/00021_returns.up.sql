@@ -0,0 +1,77 @@
+CREATE TYPE return_status AS ENUM (
+    'requested',
+    'approved',
+    'rejected',
+    'in_transit',
+    'received',
+    'refunded'
+    );
+
+CREATE TYPE return_reason AS ENUM (
+    'defective',
+    'damaged',
+    'wrong_item',
+    'not_as_described',
+    'changed_mind',
+    'other'
+    );
+
+-- in_transit: the customer shipped the items back, received: they arrived and were inspected
+CREATE TABLE IF NOT EXISTS returns
+(
+    id              UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    order_id        UUID REFERENCES orders (id) NOT NULL,
+    user_id         UUID REFERENCES users (id)  NOT NULL,
+    status          return_status               NOT NULL DEFAULT 'requested',
+    comment         TEXT,
+    resolution_note TEXT,
+    resolved_by     UUID REFERENCES users (id),
+    carrier         TEXT,
+    tracking_number TEXT,
+    tracking_url    TEXT,
+    shipped_at      TIMESTAMP WITH TIME ZONE,
+    received_at     TIMESTAMP WITH TIME ZONE,
+    created_at      TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at      TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS returns_order ON returns (order_id, created_at);
+CREATE INDEX IF NOT EXISTS returns_status ON returns (status, created_at);
+
+-- amount is the share of the order line's total, tax included, the returned units are worth
+CREATE TABLE IF NOT EXISTS return_items
+(
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    return_id  UUID REFERENCES returns (id)  NOT NULL,
+    product_id UUID REFERENCES products (id) NOT NULL,
+    quantity   INTEGER                       NOT NULL CHECK (quantity > 0),
+    reason     return_reason                 NOT NULL,
+    amount     BIGINT                        NOT NULL CHECK (amount >= 0),
+    restocked  BOOLEAN                       NOT NULL DEFAULT FALSE,
+    UNIQUE (return_id, product_id)
+);
+
+CREATE TYPE refund_status AS ENUM (
+    'pending',
+    'succeeded',
+    'failed'
+    );
+
+-- a refund is written before the provider is called and its id is the provider's idempotency key
+CREATE TABLE IF NOT EXISTS refunds
+(
+    id                 UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    order_id           UUID REFERENCES orders (id)   NOT NULL,
+    payment_id         UUID REFERENCES payments (id) NOT NULL,
+    return_id          UUID REFERENCES returns (id),
+    amount             BIGINT                        NOT NULL CHECK (amount > 0),
+    status             refund_status                 NOT NULL DEFAULT 'pending',
+    provider_refund_id TEXT,
+    reason             TEXT,
+    failure_reason     TEXT,
+    actor_id           UUID REFERENCES users (id),
+    created_at         TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at         TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS refunds_order ON refunds (order_id, created_at);
@@ -0,0 +1,16 @@
+CREATE TYPE payment_method AS ENUM (
+    'gateway',
+    'cod',
+    'store_credit'
+    );
+
+-- an order may be paid with several payments of different methods. cash on delivery payments stay pending
+-- until an admin reconciles what the courier collected, a differing collected amount is kept for follow up
+ALTER TABLE payments
+    ADD COLUMN method               payment_method NOT NULL DEFAULT 'gateway',
+    ADD COLUMN collected_amount     BIGINT CHECK (collected_amount >= 0),
+    ADD COLUMN collection_reference TEXT,
+    ADD COLUMN collected_at         TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN collected_by         UUID REFERENCES users (id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS payments_order_open_cod ON payments (order_id) WHERE method = 'cod' AND status IN ('created', 'pending');
@@ -0,0 +1,61 @@
+CREATE TYPE credit_entry_kind AS ENUM (
+    'goodwill',
+    'adjustment',
+    'gift_card',
+    'payment',
+    'refund'
+    );
+
+-- append-only ledger of store credit, a user's balance is the sum of their entries. amounts are in minor
+-- units, positive for credit given and negative for credit spent. reference is the id of the payment,
+-- refund, gift card or anything else the entry came from.
+CREATE TABLE IF NOT EXISTS store_credit_entries
+(
+    id         UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    user_id    UUID REFERENCES users (id) NOT NULL,
+    amount     BIGINT                     NOT NULL CHECK (amount != 0),
+    kind       credit_entry_kind          NOT NULL,
+    reference  TEXT,
+    note       TEXT,
+    actor_id   UUID REFERENCES users (id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS store_credit_entries_user ON store_credit_entries (user_id, created_at);
+
+-- only a hash of the code is kept, the code itself is shown once when the card is issued
+CREATE TABLE IF NOT EXISTS gift_cards
+(
+    id              UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    code_hash       TEXT UNIQUE NOT NULL,
+    last_four       TEXT        NOT NULL,
+    initial_balance BIGINT      NOT NULL CHECK (initial_balance > 0),
+    balance         BIGINT      NOT NULL CHECK (balance >= 0),
+    expires_at      TIMESTAMP WITH TIME ZONE,
+    note            TEXT,
+    issued_by       UUID REFERENCES users (id),
+    disabled_at     TIMESTAMP WITH TIME ZONE,
+    created_at      TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TYPE gift_card_transaction_kind AS ENUM (
+    'issue',
+    'redeem',
+    'adjustment',
+    'disable'
+    );
+
+-- audit trail of every change to a gift card's balance
+CREATE TABLE IF NOT EXISTS gift_card_transactions
+(
+    id           UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    gift_card_id UUID REFERENCES gift_cards (id) NOT NULL,
+    amount       BIGINT                          NOT NULL,
+    kind         gift_card_transaction_kind      NOT NULL,
+    user_id      UUID REFERENCES users (id),
+    actor_id     UUID REFERENCES users (id),
+    note         TEXT,
+    created_at   TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS gift_card_transactions_card ON gift_card_transactions (gift_card_id, created_at);
@@ -0,0 +1,44 @@
+-- the last invoice number handed out per financial year, the row lock taken when incrementing it keeps the
+-- numbering free of gaps and duplicates
+CREATE TABLE IF NOT EXISTS invoice_sequences
+(
+    financial_year TEXT PRIMARY KEY,
+    last_number    INTEGER NOT NULL
+);
+
+-- an invoice snapshots the parties and totals of a paid order together with the rendered PDF
+CREATE TABLE IF NOT EXISTS invoices
+(
+    id             UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    order_id       UUID UNIQUE REFERENCES orders (id) NOT NULL,
+    number         TEXT UNIQUE                        NOT NULL,
+    financial_year TEXT                               NOT NULL,
+    sequence       INTEGER                            NOT NULL,
+    seller_name    TEXT                               NOT NULL,
+    seller_address TEXT                               NOT NULL,
+    seller_tax_id  TEXT                               NOT NULL,
+    buyer_name     TEXT                               NOT NULL,
+    buyer_email    TEXT                               NOT NULL,
+    buyer_address  TEXT                               NOT NULL,
+    subtotal       BIGINT                             NOT NULL,
+    discount_total BIGINT                             NOT NULL,
+    tax_total      BIGINT                             NOT NULL,
+    grand_total    BIGINT                             NOT NULL,
+    currency       TEXT                               NOT NULL,
+    pdf            BYTEA                              NOT NULL,
+    issued_at      TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (financial_year, sequence)
+);
+
+CREATE OR REPLACE FUNCTION invoices_immutable() RETURNS trigger AS
+$$
+BEGIN
+    RAISE EXCEPTION 'invoices cannot be changed or deleted';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER invoices_immutable
+    BEFORE UPDATE OR DELETE
+    ON invoices
+    FOR EACH ROW
+EXECUTE FUNCTION invoices_immutable();
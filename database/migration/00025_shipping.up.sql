@@ -0,0 +1,25 @@
+ALTER TABLE products
+    ADD COLUMN weight_grams INTEGER NOT NULL DEFAULT 0 CHECK (weight_grams >= 0);
+
+-- a rate applies to deliveries within its distance band, in km from the nearest warehouse, and its weight
+-- band, in grams; an open upper bound has no limit. fees are in minor units, the per kg fee is charged for
+-- every started kg of the shipment.
+CREATE TABLE IF NOT EXISTS shipping_rates
+(
+    id               UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    name             TEXT    NOT NULL,
+    min_distance_km  DECIMAL NOT NULL DEFAULT 0 CHECK (min_distance_km >= 0),
+    max_distance_km  DECIMAL CHECK (max_distance_km > min_distance_km),
+    min_weight_grams INTEGER NOT NULL DEFAULT 0 CHECK (min_weight_grams >= 0),
+    max_weight_grams INTEGER CHECK (max_weight_grams > min_weight_grams),
+    base_fee         BIGINT  NOT NULL DEFAULT 0 CHECK (base_fee >= 0),
+    per_kg_fee       BIGINT  NOT NULL DEFAULT 0 CHECK (per_kg_fee >= 0),
+    created_at       TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    archived_at      TIMESTAMP WITH TIME ZONE
+);
+
+ALTER TABLE orders
+    ADD COLUMN shipping_total BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE invoices
+    ADD COLUMN shipping_total BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,35 @@
+-- a zone is an area we deliver to, drawn as a polygon, listed as postal codes or both. Without any live
+-- zone every address is served.
+CREATE TABLE IF NOT EXISTS delivery_zones
+(
+    id          UUID PRIMARY KEY         DEFAULT gen_random_uuid(),
+    name        TEXT NOT NULL,
+    created_at  TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    archived_at TIMESTAMP WITH TIME ZONE
+);
+
+-- the corners of a zone's polygon in drawing order, the last one connects back to the first
+CREATE TABLE IF NOT EXISTS delivery_zone_points
+(
+    zone_id  UUID REFERENCES delivery_zones (id) NOT NULL,
+    position INTEGER                             NOT NULL,
+    lat      DECIMAL                             NOT NULL,
+    long     DECIMAL                             NOT NULL,
+    PRIMARY KEY (zone_id, position)
+);
+
+-- postal codes are stored upper case without spaces
+CREATE TABLE IF NOT EXISTS delivery_zone_postal_codes
+(
+    zone_id     UUID REFERENCES delivery_zones (id) NOT NULL,
+    postal_code TEXT                                NOT NULL,
+    PRIMARY KEY (zone_id, postal_code)
+);
+
+CREATE INDEX IF NOT EXISTS delivery_zone_postal_codes_code ON delivery_zone_postal_codes (postal_code);
+
+ALTER TABLE user_addresses
+    ADD COLUMN postal_code TEXT;
+
+ALTER TABLE orders
+    ADD COLUMN shipping_postal_code TEXT;
@@ -0,0 +1,9 @@
+-- the channels, one per email recipient or webhook, a stock alert already reached; a retry of a partly
+-- delivered alert only goes to the channels that failed
+CREATE TABLE IF NOT EXISTS stock_alert_deliveries
+(
+    alert_id     UUID REFERENCES stock_alerts (id) NOT NULL,
+    channel      TEXT                              NOT NULL,
+    delivered_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (alert_id, channel)
+);
@@ -0,0 +1,3 @@
+-- what the return is being refunded in total, it moves to refunded once succeeded refunds cover it
+ALTER TABLE returns
+    ADD COLUMN IF NOT EXISTS refund_amount BIGINT CHECK (refund_amount > 0);
@@ -0,0 +1,15 @@
+-- the part of the grand total paid with store credit, shown with the order totals and on the invoice
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS credit_applied BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE invoices
+    ADD COLUMN IF NOT EXISTS credit_applied BIGINT NOT NULL DEFAULT 0;
+
+UPDATE orders o
+SET credit_applied = p.amount
+FROM (SELECT order_id, SUM(amount) AS amount
+      FROM payments
+      WHERE method = 'store_credit'
+        AND status IN ('captured', 'refunded')
+      GROUP BY order_id) p
+WHERE p.order_id = o.id;
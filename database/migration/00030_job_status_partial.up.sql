@@ -0,0 +1,2 @@
+-- partial: the run went through but some of its items failed, failed counts them
+ALTER TYPE job_status ADD VALUE IF NOT EXISTS 'partial';
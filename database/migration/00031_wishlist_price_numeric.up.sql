@@ -0,0 +1,4 @@
+-- price_at_add snapshots products.price and is compared to it, so it takes the same type; an INTEGER column
+-- rounded fractional prices and flagged price drops that never happened
+ALTER TABLE wishlist_items
+    ALTER COLUMN price_at_add TYPE DECIMAL;
@@ -0,0 +1,4 @@
+-- failed deliveries are retried with a growing delay, the notifier gives up on a subscription after a few attempts
+ALTER TABLE restock_subscriptions ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE restock_subscriptions ADD COLUMN IF NOT EXISTS last_error TEXT;
+ALTER TABLE restock_subscriptions ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,129 @@
+package inventory
+
+import (
+	"audio_phile/database/dbHelper"
+	"audio_phile/model"
+	"audio_phile/notification"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+const (
+	alertBatchSize   = 50
+	alertMaxAttempts = 5
+)
+
+// AlertNotifier receives low-stock, out-of-stock and back-in-stock alerts
+var AlertNotifier notification.Notifier = notification.MailNotifier{Mailer: notification.LogMailer{}, To: []string{"inventory"}}
+
+// SyncStockState must be called, with the product row locked, whenever the stock of a product may have
+// changed. It flips is_available when the sellable stock, on hand less what carts in checkout hold,
+// reaches or leaves zero, so a product whose last units are all reserved shows as sold out until a hold is
+// released, and records one alert per crossing of the reorder
+// threshold; a product has to climb back above it before it can alert again. Coming back in stock also
+// queues the product's back-in-stock subscribers.
+func SyncStockState(tx *sqlx.Tx, productId string) error {
+	state, err := dbHelper.GetStockState(tx, productId)
+	if err != nil {
+		return err
+	}
+
+	isAvailable, isLow := stockFlags(state)
+	if isAvailable != state.IsAvailable {
+		kind := model.StockAlertOutOfStock
+		if isAvailable {
+			kind = model.StockAlertBackIn
+		}
+		if err := dbHelper.CreateStockAlert(tx, productId, kind, state.Sellable, state.ReorderThreshold); err != nil {
+			return err
+		}
+		if isAvailable {
+			if err := dbHelper.QueueRestockNotifications(tx, productId); err != nil {
+				return err
+			}
+		}
+	}
+
+	lowStockAlerted := state.LowStockAlerted
+	switch {
+	case isLow && !lowStockAlerted:
+		if err := dbHelper.CreateStockAlert(tx, productId, model.StockAlertLow, state.Sellable, state.ReorderThreshold); err != nil {
+			return err
+		}
+		lowStockAlerted = true
+	case !isLow && lowStockAlerted:
+		lowStockAlerted = false
+	}
+
+	if isAvailable == state.IsAvailable && lowStockAlerted == state.LowStockAlerted {
+		return nil
+	}
+	return dbHelper.UpdateStockFlags(tx, productId, isAvailable, lowStockAlerted)
+}
+
+// stockFlags tells whether a product can be sold and whether it is at or below its reorder threshold, both
+// from the sellable stock
+func stockFlags(state model.StockState) (isAvailable, isLow bool) {
+	return state.Sellable > 0, state.ReorderThreshold > 0 && state.Sellable <= state.ReorderThreshold
+}
+
+// StartAlertDispatcher delivers pending stock alerts through AlertNotifier. Alerts are stored in the
+// same transaction as the stock change that caused them, so a rolled back change never notifies. Each
+// channel is notified once, a failed alert is retried only for the channels it did not reach.
+func StartAlertDispatcher(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			dispatchAlerts()
+		}
+	}()
+}
+
+func dispatchAlerts() {
+	alerts, err := dbHelper.GetPendingStockAlerts(alertBatchSize, alertMaxAttempts)
+	if err != nil {
+		logrus.Errorf("failed to get pending stock alerts with error: %+v", err)
+		return
+	}
+	for _, alert := range alerts {
+		delivered, err := dbHelper.GetStockAlertChannels(alert.Id)
+		if err != nil {
+			logrus.Errorf("failed to get deliveries of stock alert %s with error: %+v", alert.Id, err)
+			continue
+		}
+		err = notification.NotifyOnce(AlertNotifier, alertMessage(alert), delivered, func(channel string) error {
+			return dbHelper.CreateStockAlertDelivery(alert.Id, channel)
+		})
+		if err != nil {
+			logrus.Errorf("failed to deliver stock alert %s with error: %+v", alert.Id, err)
+			if markErr := dbHelper.MarkStockAlertFailed(alert.Id, err.Error()); markErr != nil {
+				logrus.Errorf("failed to record stock alert failure with error: %+v", markErr)
+			}
+			continue
+		}
+		if err := dbHelper.MarkStockAlertDelivered(alert.Id); err != nil {
+			logrus.Errorf("failed to mark stock alert %s delivered with error: %+v", alert.Id, err)
+		}
+	}
+}
+
+func alertMessage(alert model.StockAlert) notification.Message {
+	var subject string
+	switch alert.Kind {
+	case model.StockAlertLow:
+		subject = fmt.Sprintf("Low stock: %s has %d left (reorder at %d)", alert.ProductName, alert.SellableQuantity, alert.Threshold)
+	case model.StockAlertOutOfStock:
+		subject = fmt.Sprintf("Sold out: %s is no longer available", alert.ProductName)
+	default:
+		subject = fmt.Sprintf("Restocked: %s is available again with %d in stock", alert.ProductName, alert.SellableQuantity)
+	}
+	return notification.Message{
+		Event:   "stock." + string(alert.Kind),
+		Subject: subject,
+		Body:    subject + "\n\nProduct id: " + alert.ProductId,
+		Data:    alert,
+	}
+}
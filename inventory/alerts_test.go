@@ -0,0 +1,30 @@
+package inventory
+
+import (
+	"audio_phile/model"
+	"testing"
+)
+
+func TestStockFlags(t *testing.T) {
+	tests := []struct {
+		name      string
+		state     model.StockState
+		available bool
+		low       bool
+	}{
+		{"in stock", model.StockState{OnHand: 40, Sellable: 35, ReorderThreshold: 10}, true, false},
+		{"at the reorder threshold", model.StockState{OnHand: 12, Sellable: 10, ReorderThreshold: 10}, true, true},
+		{"partly reserved below the threshold", model.StockState{OnHand: 20, Sellable: 3, ReorderThreshold: 5}, true, true},
+		{"fully reserved", model.StockState{OnHand: 4, Sellable: 0, ReorderThreshold: 5}, false, true},
+		{"fully reserved without a threshold", model.StockState{OnHand: 4, Sellable: 0}, false, false},
+		{"nothing on hand", model.StockState{OnHand: 0, Sellable: 0, ReorderThreshold: 5}, false, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			available, low := stockFlags(test.state)
+			if available != test.available || low != test.low {
+				t.Errorf("stockFlags() = %v, %v, want %v, %v", available, low, test.available, test.low)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package inventory
+
+import (
+	"audio_phile/database"
+	"audio_phile/database/dbHelper"
+	"audio_phile/model"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+// ReservationTTL is how long checkout holds stock for a cart before it is handed back
+var ReservationTTL = 15 * time.Minute
+
+var ErrEmptyCart = errors.New("cart has no products")
+
+type InsufficientStockError struct {
+	ProductId string
+	Requested int
+	Available int
+}
+
+func (e *InsufficientStockError) Error() string {
+	return fmt.Sprintf("product %s: requested %d but only %d available", e.ProductId, e.Requested, e.Available)
+}
+
+// IsInsufficientStock reports whether err was caused by a product running out of stock
+func IsInsufficientStock(err error) bool {
+	var stockErr *InsufficientStockError
+	return errors.As(err, &stockErr)
+}
+
+// Available returns the stock of a product that is free for the given cart, i.e. not held by any other cart
+func Available(productId, cartId string) (int, error) {
+	return dbHelper.GetAvailableQuantity(database.Audiophile, productId, cartId)
+}
+
+// ReserveCart holds stock for every line of the cart for ReservationTTL. Product rows are locked
+// with SELECT ... FOR UPDATE in product id order, so concurrent checkouts of the same product are
+// serialised and can never both take the last unit. Any earlier hold of the cart is replaced.
+func ReserveCart(tx *sqlx.Tx, cartId string) ([]model.StockReservation, error) {
+	lines, err := dbHelper.GetCartLines(tx, cartId)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, ErrEmptyCart
+	}
+	previous, err := releaseCart(tx, cartId)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(ReservationTTL)
+	reservations := make([]model.StockReservation, 0, len(lines))
+	for _, line := range lines {
+		onHand, err := dbHelper.LockProductQuantity(tx, line.ProductId)
+		if err != nil {
+			return nil, err
+		}
+		reserved, err := dbHelper.GetReservedQuantity(tx, line.ProductId, cartId)
+		if err != nil {
+			return nil, err
+		}
+		if available := onHand - reserved; line.Quantity > available {
+			return nil, &InsufficientStockError{ProductId: line.ProductId, Requested: line.Quantity, Available: available}
+		}
+		reservation, err := dbHelper.CreateReservation(tx, cartId, line.ProductId, line.Quantity, expiresAt)
+		if err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, reservation)
+	}
+	for _, productId := range previous {
+		if err := SyncStockState(tx, productId); err != nil {
+			return nil, err
+		}
+	}
+	for _, line := range lines {
+		if err := SyncStockState(tx, line.ProductId); err != nil {
+			return nil, err
+		}
+	}
+	return reservations, nil
+}
+
+// CommitCart turns the cart's hold into a sale: stock is (re)reserved under lock, taken out of the
+// warehouses through sale movements referencing the order and the reservations are marked committed.
+// It must run inside the order placement transaction.
+func CommitCart(tx *sqlx.Tx, cartId, actorId, orderId string) ([]model.StockReservation, error) {
+	reservations, err := ReserveCart(tx, cartId)
+	if err != nil {
+		return nil, err
+	}
+	for _, reservation := range reservations {
+		if err := recordSale(tx, reservation.ProductId, reservation.Quantity, actorId, orderId); err != nil {
+			return nil, err
+		}
+	}
+	if err := dbHelper.UpdateCartReservationStatus(tx, cartId, model.ReservationStatusCommitted); err != nil {
+		return nil, err
+	}
+	for _, reservation := range reservations {
+		if err := SyncStockState(tx, reservation.ProductId); err != nil {
+			return nil, err
+		}
+	}
+	return reservations, nil
+}
+
+// ReleaseCart gives back any stock the cart is holding
+func ReleaseCart(tx *sqlx.Tx, cartId string) error {
+	productIds, err := releaseCart(tx, cartId)
+	if err != nil {
+		return err
+	}
+	for _, productId := range productIds {
+		if err := SyncStockState(tx, productId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseCart releases the cart's active reservations and returns the products they were holding
+func releaseCart(tx *sqlx.Tx, cartId string) ([]string, error) {
+	held, err := dbHelper.GetActiveReservations(tx, cartId)
+	if err != nil {
+		return nil, err
+	}
+	if err := dbHelper.UpdateCartReservationStatus(tx, cartId, model.ReservationStatusReleased); err != nil {
+		return nil, err
+	}
+	productIds := make([]string, 0, len(held))
+	for _, reservation := range held {
+		productIds = append(productIds, reservation.ProductId)
+	}
+	return productIds, nil
+}
+
+// StartReleaser periodically releases reservations whose hold expired. Expired holds are already
+// ignored when computing availability; this keeps the table's active set small.
+func StartReleaser(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			productIds, err := dbHelper.ReleaseExpiredReservations(database.Audiophile)
+			if err != nil {
+				logrus.Errorf("failed to release expired reservations with error: %+v", err)
+				continue
+			}
+			for _, productId := range productIds {
+				txErr := database.Tx(func(tx *sqlx.Tx) error {
+					if _, err := dbHelper.LockProductQuantity(tx, productId); err != nil {
+						return err
+					}
+					return SyncStockState(tx, productId)
+				})
+				if txErr != nil {
+					logrus.Errorf("failed to sync stock of product %s with error: %+v", productId, txErr)
+				}
+			}
+			if len(productIds) > 0 {
+				logrus.Infof("released expired stock reservations of %d products", len(productIds))
+			}
+		}
+	}()
+}
@@ -0,0 +1,229 @@
+package inventory
+
+import (
+	"audio_phile/database/dbHelper"
+	"audio_phile/model"
+	"errors"
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrInvalidMovement = errors.New("quantity must be positive for receipts, sales, returns and transfers and non-zero for adjustments")
+
+// RecordMovement appends a movement to the stock ledger and applies it to the warehouse stock and to the
+// product's total quantity, from which is_available is derived. A transfer is recorded as two movements,
+// out of the source and into the destination warehouse, sharing a correlation id.
+func RecordMovement(tx *sqlx.Tx, req model.StockMovementRequest) ([]model.StockMovement, error) {
+	movements, err := recordMovement(tx, req)
+	if err != nil {
+		return nil, err
+	}
+	return movements, SyncStockState(tx, req.ProductId)
+}
+
+func recordMovement(tx *sqlx.Tx, req model.StockMovementRequest) ([]model.StockMovement, error) {
+	if req.Quantity == 0 || (req.Type != model.MovementAdjustment && req.Quantity < 0) {
+		return nil, ErrInvalidMovement
+	}
+	// the product row is locked first, in the same order as checkout, so ledger writes and reservations serialise
+	if _, err := dbHelper.LockProductQuantity(tx, req.ProductId); err != nil {
+		return nil, err
+	}
+
+	switch req.Type {
+	case model.MovementReceipt, model.MovementReturn, model.MovementAdjustment:
+		movement, err := applyMovement(tx, req, req.WarehouseId, req.Quantity, nil, true)
+		if err != nil {
+			return nil, err
+		}
+		return []model.StockMovement{movement}, nil
+	case model.MovementSale:
+		movement, err := applyMovement(tx, req, req.WarehouseId, -req.Quantity, nil, true)
+		if err != nil {
+			return nil, err
+		}
+		return []model.StockMovement{movement}, nil
+	case model.MovementTransfer:
+		correlationId, err := dbHelper.GenerateUUID(tx)
+		if err != nil {
+			return nil, err
+		}
+		out, err := applyMovement(tx, req, req.WarehouseId, -req.Quantity, &correlationId, false)
+		if err != nil {
+			return nil, err
+		}
+		in, err := applyMovement(tx, req, req.ToWarehouseId, req.Quantity, &correlationId, false)
+		if err != nil {
+			return nil, err
+		}
+		return []model.StockMovement{out, in}, nil
+	}
+	return nil, ErrInvalidMovement
+}
+
+// SetStockLevel brings the product's total stock to target with adjustments. Stock is added to the default
+// warehouse and taken out of the warehouses that hold it, the default one first and then the fullest ones.
+func SetStockLevel(tx *sqlx.Tx, productId string, target int, actorId, reason string) error {
+	current, err := dbHelper.LockProductQuantity(tx, productId)
+	if err != nil {
+		return err
+	}
+	if target == current {
+		return nil
+	}
+	req := model.StockMovementRequest{
+		ProductId: productId,
+		Type:      model.MovementAdjustment,
+		Quantity:  target - current,
+		Reason:    reason,
+		ActorId:   actorId,
+	}
+	if target > current {
+		if req.WarehouseId, err = dbHelper.GetDefaultWarehouseId(tx); err != nil {
+			return err
+		}
+		_, err = RecordMovement(tx, req)
+		return err
+	}
+	if err := drawDown(tx, req, current-target); err != nil {
+		return err
+	}
+	return SyncStockState(tx, productId)
+}
+
+// recordSale takes quantity units of the product out of the warehouses that hold it with sale movements
+func recordSale(tx *sqlx.Tx, productId string, quantity int, actorId, referenceId string) error {
+	return drawDown(tx, model.StockMovementRequest{
+		ProductId:   productId,
+		Type:        model.MovementSale,
+		Reason:      "order placed",
+		ReferenceId: referenceId,
+		ActorId:     actorId,
+	}, quantity)
+}
+
+// drawDown takes quantity units of req's product out of the warehouses that hold it, starting with the
+// default warehouse and then the fullest ones, and records one movement like req per warehouse used
+func drawDown(tx *sqlx.Tx, req model.StockMovementRequest, quantity int) error {
+	warehouses, err := dbHelper.GetStockedWarehouses(tx, req.ProductId)
+	if err != nil {
+		return err
+	}
+	remaining := quantity
+	for _, warehouse := range warehouses {
+		if remaining == 0 {
+			break
+		}
+		take := remaining
+		if warehouse.Quantity < take {
+			take = warehouse.Quantity
+		}
+		if _, err := applyMovement(tx, req, warehouse.WarehouseId, -take, nil, true); err != nil {
+			return err
+		}
+		remaining -= take
+	}
+	if remaining > 0 {
+		return &InsufficientStockError{ProductId: req.ProductId, Requested: quantity, Available: quantity - remaining}
+	}
+	return nil
+}
+
+func applyMovement(tx *sqlx.Tx, req model.StockMovementRequest, warehouseId string, delta int, correlationId *string, touchProduct bool) (model.StockMovement, error) {
+	ok, err := dbHelper.ApplyWarehouseStockDelta(tx, warehouseId, req.ProductId, delta)
+	if err != nil {
+		return model.StockMovement{}, err
+	}
+	if !ok {
+		return model.StockMovement{}, &InsufficientStockError{ProductId: req.ProductId, Requested: -delta}
+	}
+	if touchProduct {
+		ok, err = dbHelper.ApplyProductQuantityDelta(tx, req.ProductId, delta)
+		if err != nil {
+			return model.StockMovement{}, err
+		}
+		if !ok {
+			return model.StockMovement{}, &InsufficientStockError{ProductId: req.ProductId, Requested: -delta}
+		}
+	}
+	return dbHelper.CreateStockMovement(tx, model.StockMovement{
+		ProductId:     req.ProductId,
+		WarehouseId:   warehouseId,
+		Type:          req.Type,
+		Quantity:      delta,
+		Reason:        req.Reason,
+		ActorId:       optional(req.ActorId),
+		ReferenceId:   optional(req.ReferenceId),
+		CorrelationId: correlationId,
+	})
+}
+
+func optional(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// RestockOrder puts back what an order took out of stock, into the warehouses it came from, with return
+// movements referencing the order. Units already returned against the order are not returned twice.
+func RestockOrder(tx *sqlx.Tx, orderId, actorId, reason string) error {
+	sales, err := dbHelper.GetOrderSales(tx, orderId)
+	if err != nil {
+		return err
+	}
+	for _, sale := range sales {
+		if _, err := dbHelper.LockProductQuantity(tx, sale.ProductId); err != nil {
+			return err
+		}
+		_, err := applyMovement(tx, model.StockMovementRequest{
+			ProductId:   sale.ProductId,
+			Type:        model.MovementReturn,
+			Reason:      reason,
+			ReferenceId: orderId,
+			ActorId:     actorId,
+		}, sale.WarehouseId, sale.Quantity, nil, true)
+		if err != nil {
+			return err
+		}
+	}
+	for _, sale := range sales {
+		if err := SyncStockState(tx, sale.ProductId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RestockOrderItem puts up to quantity units of one product of an order back into the warehouses they came
+// from, with return movements referencing the order, and returns how many units went back
+func RestockOrderItem(tx *sqlx.Tx, orderId, productId string, quantity int, actorId, reason string) (int, error) {
+	sales, err := dbHelper.GetOrderSales(tx, orderId)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := dbHelper.LockProductQuantity(tx, productId); err != nil {
+		return 0, err
+	}
+	restocked := 0
+	for _, sale := range sales {
+		if sale.ProductId != productId || restocked == quantity {
+			continue
+		}
+		units := sale.Quantity
+		if units > quantity-restocked {
+			units = quantity - restocked
+		}
+		_, err := applyMovement(tx, model.StockMovementRequest{
+			ProductId:   productId,
+			Type:        model.MovementReturn,
+			Reason:      reason,
+			ReferenceId: orderId,
+			ActorId:     actorId,
+		}, sale.WarehouseId, units, nil, true)
+		if err != nil {
+			return restocked, err
+		}
+		restocked += units
+	}
+	return restocked, SyncStockState(tx, productId)
+}
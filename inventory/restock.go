@@ -0,0 +1,73 @@
+package inventory
+
+import (
+	"audio_phile/database/dbHelper"
+	"audio_phile/notification"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+// RestockBatchSize is the most back-in-stock emails sent per tick of the restock notifier, so a popular
+// product coming back does not flood the mailer or send everyone to a product with a handful of units
+var RestockBatchSize = 20
+
+// RestockMaxAttempts is how often a back-in-stock email is tried before the notifier gives up on it, and
+// RestockRetryDelay how long it waits after the first failure, the delay doubles with every further one
+var (
+	RestockMaxAttempts = 5
+	RestockRetryDelay  = 5 * time.Minute
+)
+
+// the queue lookups notifyRestocks makes, variables so that it can be tested without a database
+var (
+	queuedRestocks      = dbHelper.GetQueuedRestockNotifications
+	markRestockNotified = dbHelper.MarkRestockNotified
+	markRestockFailed   = dbHelper.MarkRestockFailed
+)
+
+// StartRestockNotifier emails queued back-in-stock subscribers, oldest subscription first, at most
+// RestockBatchSize per interval. A subscription ends once its email was delivered. A failed email is backed
+// off so it does not hold up the subscribers behind it, and skipped after RestockMaxAttempts failures.
+func StartRestockNotifier(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			notifyRestocks()
+		}
+	}()
+}
+
+func notifyRestocks() {
+	queued, err := queuedRestocks(RestockBatchSize, RestockMaxAttempts)
+	if err != nil {
+		logrus.Errorf("failed to get queued restock notifications with error: %+v", err)
+		return
+	}
+	for _, subscription := range queued {
+		subject := fmt.Sprintf("%s is back in stock", subscription.ProductName)
+		body := fmt.Sprintf("Good news! %s is available again. Grab it before it sells out.\n\nProduct id: %s",
+			subscription.ProductName, subscription.ProductId)
+		if err := notification.Mail.Send([]string{subscription.Email}, subject, body); err != nil {
+			logrus.Errorf("failed to send restock notification %s with error: %+v", subscription.Id, err)
+			retryAt := restockRetryAt(time.Now(), subscription.Attempts+1)
+			if err := markRestockFailed(subscription.Id, err.Error(), retryAt); err != nil {
+				logrus.Errorf("failed to record restock notification %s failure with error: %+v", subscription.Id, err)
+			}
+			continue
+		}
+		if err := markRestockNotified(subscription.Id); err != nil {
+			logrus.Errorf("failed to mark restock notification %s sent with error: %+v", subscription.Id, err)
+		}
+	}
+}
+
+// restockRetryAt is when an email that failed attempts times is tried again
+func restockRetryAt(now time.Time, attempts int) time.Time {
+	delay := RestockRetryDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+	}
+	return now.Add(delay)
+}
@@ -0,0 +1,89 @@
+package inventory
+
+import (
+	"audio_phile/model"
+	"audio_phile/notification"
+	"errors"
+	"testing"
+	"time"
+)
+
+type failingMailer map[string]bool
+
+func (m failingMailer) Send(to []string, _, _ string) error {
+	if m[to[0]] {
+		return errors.New("mailbox unavailable")
+	}
+	return nil
+}
+
+func TestRestockRetryAt(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 5 * time.Minute},
+		{2, 10 * time.Minute},
+		{3, 20 * time.Minute},
+		{4, 40 * time.Minute},
+	}
+	for _, test := range tests {
+		if got := restockRetryAt(now, test.attempts); !got.Equal(now.Add(test.want)) {
+			t.Errorf("restockRetryAt(%d) = %s, want %s", test.attempts, got, now.Add(test.want))
+		}
+	}
+}
+
+func TestNotifyRestocksBacksOffFailures(t *testing.T) {
+	queue := []model.RestockSubscription{
+		{Id: "bounced", Email: "gone@example.com", Attempts: 2},
+		{Id: "first", Email: "alice@example.com"},
+		{Id: "second", Email: "bob@example.com"},
+	}
+	originalQueue, originalNotified, originalFailed, originalMail := queuedRestocks, markRestockNotified, markRestockFailed, notification.Mail
+	t.Cleanup(func() {
+		queuedRestocks, markRestockNotified, markRestockFailed, notification.Mail = originalQueue, originalNotified, originalFailed, originalMail
+	})
+
+	var maxAttempts int
+	queuedRestocks = func(_, attempts int) ([]model.RestockSubscription, error) {
+		maxAttempts = attempts
+		return queue, nil
+	}
+	notified := make(map[string]bool)
+	markRestockNotified = func(subscriptionId string) error {
+		notified[subscriptionId] = true
+		return nil
+	}
+	failed := make(map[string]time.Time)
+	markRestockFailed = func(subscriptionId, lastError string, retryAt time.Time) error {
+		if lastError == "" {
+			t.Errorf("failure of %s recorded without its error", subscriptionId)
+		}
+		failed[subscriptionId] = retryAt
+		return nil
+	}
+	notification.Mail = failingMailer{"gone@example.com": true}
+
+	before := time.Now()
+	notifyRestocks()
+
+	if maxAttempts != RestockMaxAttempts {
+		t.Errorf("queue read with a cap of %d attempts, want %d", maxAttempts, RestockMaxAttempts)
+	}
+	if !notified["first"] || !notified["second"] {
+		t.Errorf("subscriptions behind a failing one were not notified: %v", notified)
+	}
+	if notified["bounced"] {
+		t.Error("failed delivery was marked notified")
+	}
+	retryAt, ok := failed["bounced"]
+	if !ok {
+		t.Fatal("failed delivery was not recorded")
+	}
+	// the third failure waits four times the first delay
+	if retryAt.Before(before.Add(4 * RestockRetryDelay)) {
+		t.Errorf("failed delivery retried at %s, want at least %s", retryAt, before.Add(4*RestockRetryDelay))
+	}
+}
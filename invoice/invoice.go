@@ -0,0 +1,111 @@
+package invoice
+
+import (
+	"audio_phile/database/dbHelper"
+	"audio_phile/model"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"os"
+	"strconv"
+	"time"
+)
+
+var (
+	// Seller is named on every invoice
+	Seller = model.InvoiceParty{Name: "Audiophile"}
+	// Prefix starts every invoice number, like INV/2026-27/000042
+	Prefix = "INV"
+	// Currency the amounts on invoices are in
+	Currency = "INR"
+	// FinancialYearStart is the month financial years, and with them invoice numbering, start in
+	FinancialYearStart = time.April
+)
+
+// FromEnv reads the seller from INVOICE_SELLER_NAME, INVOICE_SELLER_ADDRESS and INVOICE_SELLER_TAX_ID,
+// the number prefix from INVOICE_PREFIX and the first month of the financial year, 1 to 12, from
+// INVOICE_FY_START_MONTH
+func FromEnv() error {
+	if name := os.Getenv("INVOICE_SELLER_NAME"); name != "" {
+		Seller.Name = name
+	}
+	Seller.Address = os.Getenv("INVOICE_SELLER_ADDRESS")
+	Seller.TaxId = os.Getenv("INVOICE_SELLER_TAX_ID")
+	if prefix := os.Getenv("INVOICE_PREFIX"); prefix != "" {
+		Prefix = prefix
+	}
+	if month := os.Getenv("INVOICE_FY_START_MONTH"); month != "" {
+		parsed, err := strconv.Atoi(month)
+		if err != nil || parsed < 1 || parsed > 12 {
+			return fmt.Errorf("invalid INVOICE_FY_START_MONTH %q", month)
+		}
+		FinancialYearStart = time.Month(parsed)
+	}
+	return nil
+}
+
+// FinancialYear names the financial year t falls in after the year it starts in and the one it ends in,
+// like 2026-27, or just the year when financial years follow the calendar
+func FinancialYear(t time.Time) string {
+	start := t.Year()
+	if t.Month() < FinancialYearStart {
+		start--
+	}
+	if FinancialYearStart == time.January {
+		return strconv.Itoa(start)
+	}
+	return fmt.Sprintf("%d-%02d", start, (start+1)%100)
+}
+
+// Issue creates the invoice of a paid order with the next number of the current financial year and renders
+// it to PDF. An order has one invoice only, issuing it again returns the existing one; once stored an invoice
+// cannot be changed.
+func Issue(tx *sqlx.Tx, orderId string) (model.Invoice, error) {
+	existing, err := dbHelper.GetOrderInvoice(tx, orderId)
+	if err == nil || !errors.Is(err, sql.ErrNoRows) {
+		return existing, err
+	}
+	placed, err := dbHelper.GetOrder(tx, orderId, "")
+	if err != nil {
+		return model.Invoice{}, err
+	}
+	items, err := dbHelper.GetOrderItems(tx, placed.Id)
+	if err != nil {
+		return model.Invoice{}, err
+	}
+	buyer, err := dbHelper.GetInvoiceBuyer(tx, placed.Id)
+	if err != nil {
+		return model.Invoice{}, err
+	}
+
+	issuedAt := time.Now()
+	financialYear := FinancialYear(issuedAt)
+	sequence, err := dbHelper.NextInvoiceNumber(tx, financialYear)
+	if err != nil {
+		return model.Invoice{}, err
+	}
+	invoice := model.Invoice{
+		OrderId:       placed.Id,
+		Number:        fmt.Sprintf("%s/%s/%06d", Prefix, financialYear, sequence),
+		FinancialYear: financialYear,
+		Sequence:      sequence,
+		SellerName:    Seller.Name,
+		SellerAddress: Seller.Address,
+		SellerTaxId:   Seller.TaxId,
+		BuyerName:     buyer.Name,
+		BuyerEmail:    buyer.Email,
+		Subtotal:      placed.Subtotal,
+		DiscountTotal: placed.DiscountTotal,
+		TaxTotal:      placed.TaxTotal,
+		ShippingTotal: placed.ShippingTotal,
+		GrandTotal:    placed.GrandTotal,
+		CreditApplied: placed.CreditApplied,
+		Currency:      Currency,
+		IssuedAt:      issuedAt,
+	}
+	if placed.Address != nil {
+		invoice.BuyerAddress = *placed.Address
+	}
+	return dbHelper.CreateInvoice(tx, invoice, Render(invoice, items))
+}
@@ -0,0 +1,35 @@
+package invoice
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFinancialYear(t *testing.T) {
+	ist := time.FixedZone("IST", 5*60*60+30*60)
+	tests := []struct {
+		name  string
+		start time.Month
+		t     time.Time
+		year  string
+	}{
+		{"last moment of march", time.April, time.Date(2026, time.March, 31, 23, 59, 59, 0, ist), "2025-26"},
+		{"first moment of april", time.April, time.Date(2026, time.April, 1, 0, 0, 0, 0, ist), "2026-27"},
+		{"january", time.April, time.Date(2027, time.January, 15, 12, 0, 0, 0, ist), "2026-27"},
+		{"december", time.April, time.Date(2026, time.December, 31, 12, 0, 0, 0, ist), "2026-27"},
+		{"turn of the century", time.April, time.Date(2099, time.June, 1, 0, 0, 0, 0, ist), "2099-00"},
+		{"calendar years", time.January, time.Date(2026, time.December, 31, 23, 59, 59, 0, ist), "2026"},
+		{"calendar years from january", time.January, time.Date(2027, time.January, 1, 0, 0, 0, 0, ist), "2027"},
+		{"july start before", time.July, time.Date(2026, time.June, 30, 0, 0, 0, 0, ist), "2025-26"},
+		{"july start", time.July, time.Date(2026, time.July, 1, 0, 0, 0, 0, ist), "2026-27"},
+	}
+	defer func(start time.Month) { FinancialYearStart = start }(FinancialYearStart)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			FinancialYearStart = test.start
+			if got := FinancialYear(test.t); got != test.year {
+				t.Fatalf("expected %s, got %s", test.year, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package invoice
+
+import (
+	"audio_phile/model"
+	"audio_phile/pdf"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	margin     = 40.0
+	lineHeight = 14.0
+	// bottom is as low as rows go before the table continues on a new page
+	bottom = 80.0
+)
+
+// columns of the item table, amounts are right aligned to their x
+var itemColumns = []struct {
+	title string
+	x     float64
+}{
+	{"Qty", 300},
+	{"Unit price", 365},
+	{"Discount", 425},
+	{"Tax %", 470},
+	{"Tax", 510},
+	{"Total", pdf.PageWidth - margin},
+}
+
+// Render draws an invoice with its items and a breakdown of the tax by rate
+func Render(invoice model.Invoice, items []model.OrderItem) []byte {
+	doc := pdf.New()
+	y := pdf.PageHeight - margin - 10
+	doc.Text(margin, y, pdf.HelveticaBold, 18, "TAX INVOICE")
+	right := pdf.PageWidth - margin
+	doc.TextRight(right, y, 9, "Invoice "+invoice.Number)
+	doc.TextRight(right, y-lineHeight, 9, "Date "+invoice.IssuedAt.Format("02 Jan 2006"))
+	doc.TextRight(right, y-2*lineHeight, 9, "Order "+invoice.OrderId)
+
+	y -= 4 * lineHeight
+	sellerLines := append([]string{invoice.SellerName}, wrap(invoice.SellerAddress, 45)...)
+	if invoice.SellerTaxId != "" {
+		sellerLines = append(sellerLines, "Tax id: "+invoice.SellerTaxId)
+	}
+	buyerLines := append([]string{invoice.BuyerName, invoice.BuyerEmail}, wrap(invoice.BuyerAddress, 45)...)
+	doc.Text(margin, y, pdf.HelveticaBold, 10, "Sold by")
+	doc.Text(pdf.PageWidth/2, y, pdf.HelveticaBold, 10, "Billed and shipped to")
+	for i := 0; i < len(sellerLines) || i < len(buyerLines); i++ {
+		y -= lineHeight
+		if i < len(sellerLines) {
+			doc.Text(margin, y, pdf.Helvetica, 10, sellerLines[i])
+		}
+		if i < len(buyerLines) {
+			doc.Text(pdf.PageWidth/2, y, pdf.Helvetica, 10, buyerLines[i])
+		}
+	}
+
+	y -= 2 * lineHeight
+	y = itemHeader(doc, y)
+	for i, item := range items {
+		if y < bottom {
+			doc.AddPage()
+			y = itemHeader(doc, pdf.PageHeight-margin)
+		}
+		doc.Text(margin, y, pdf.Helvetica, 9, fmt.Sprintf("%d", i+1))
+		doc.Text(margin+20, y, pdf.Helvetica, 9, truncate(item.Name, 38))
+		values := []string{
+			fmt.Sprintf("%d", item.Quantity),
+			item.UnitPrice.String(),
+			item.Discount.String(),
+			rate(item.TaxRate),
+			item.Tax.String(),
+			item.Total.String(),
+		}
+		for c, value := range values {
+			doc.TextRight(itemColumns[c].x, y, 9, value)
+		}
+		y -= lineHeight
+	}
+	doc.Line(margin, y+lineHeight-4, right, y+lineHeight-4, 0.5)
+
+	// the breakdown and the totals need about ten lines, they go on a new page when they do not fit
+	if y-10*lineHeight < bottom {
+		doc.AddPage()
+		y = pdf.PageHeight - margin
+	}
+	// the tax breakdown goes on the left and the totals on the right, side by side
+	y -= lineHeight
+	totalsY := y
+	doc.Text(margin, y, pdf.HelveticaBold, 10, "Tax breakdown")
+	y -= lineHeight
+	doc.Text(margin, y, pdf.HelveticaBold, 9, "Rate")
+	doc.Text(220-pdf.Width(pdf.HelveticaBold, "Taxable value", 9), y, pdf.HelveticaBold, 9, "Taxable value")
+	doc.Text(300-pdf.Width(pdf.HelveticaBold, "Tax", 9), y, pdf.HelveticaBold, 9, "Tax")
+	for _, group := range taxBreakdown(items) {
+		y -= lineHeight
+		doc.Text(margin, y, pdf.Courier, 9, rate(group.rate))
+		doc.TextRight(220, y, 9, group.taxable.String())
+		doc.TextRight(300, y, 9, group.tax.String())
+	}
+
+	totals := []totalLine{
+		{"Subtotal", invoice.Subtotal, false},
+		{"Discount", -invoice.DiscountTotal, false},
+		{"Tax", invoice.TaxTotal, false},
+		{"Shipping", invoice.ShippingTotal, false},
+		{"Grand total (" + invoice.Currency + ")", invoice.GrandTotal, true},
+	}
+	if invoice.CreditApplied > 0 {
+		// store credit is a way of paying, it does not lower the taxable value
+		totals = append(totals,
+			totalLine{"Store credit applied", -invoice.CreditApplied, false},
+			totalLine{"Amount paid (" + invoice.Currency + ")", invoice.GrandTotal - invoice.CreditApplied, true})
+	}
+	for _, total := range totals {
+		font := pdf.Helvetica
+		if total.bold {
+			font = pdf.HelveticaBold
+		}
+		doc.Text(380, totalsY, font, 10, total.label)
+		doc.TextRight(right, totalsY, 10, total.value.String())
+		totalsY -= lineHeight
+	}
+
+	doc.Text(margin, bottom-2*lineHeight, pdf.Helvetica, 8, "This is a computer generated invoice and needs no signature.")
+	return doc.Bytes()
+}
+
+func itemHeader(doc *pdf.Document, y float64) float64 {
+	doc.Text(margin, y, pdf.HelveticaBold, 9, "#")
+	doc.Text(margin+20, y, pdf.HelveticaBold, 9, "Item")
+	for _, column := range itemColumns {
+		doc.Text(column.x-pdf.Width(pdf.HelveticaBold, column.title, 9), y, pdf.HelveticaBold, 9, column.title)
+	}
+	doc.Line(margin, y-4, pdf.PageWidth-margin, y-4, 0.5)
+	return y - lineHeight - 2
+}
+
+// totalLine is a row of the totals, the grand total and what is left to pay are bold
+type totalLine struct {
+	label string
+	value model.Money
+	bold  bool
+}
+
+type taxGroup struct {
+	rate    int
+	taxable model.Money
+	tax     model.Money
+}
+
+// taxBreakdown adds up the taxable value, the line total less its discount, and the tax of the items per rate
+func taxBreakdown(items []model.OrderItem) []taxGroup {
+	byRate := make(map[int]*taxGroup)
+	for _, item := range items {
+		group, ok := byRate[item.TaxRate]
+		if !ok {
+			group = &taxGroup{rate: item.TaxRate}
+			byRate[item.TaxRate] = group
+		}
+		group.taxable += item.LineTotal - item.Discount
+		group.tax += item.Tax
+	}
+	groups := make([]taxGroup, 0, len(byRate))
+	for _, group := range byRate {
+		groups = append(groups, *group)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].rate < groups[j].rate })
+	return groups
+}
+
+// rate formats a rate in basis points as a percentage
+func rate(bps int) string {
+	return fmt.Sprintf("%d.%02d%%", bps/100, bps%100)
+}
+
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-3]) + "..."
+}
+
+// wrap breaks s into lines of at most width characters at spaces
+func wrap(s string, width int) []string {
+	lines := make([]string, 0)
+	line := ""
+	for _, word := range strings.Fields(s) {
+		if line != "" && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,66 @@
+package invoice
+
+import (
+	"audio_phile/model"
+	"reflect"
+	"testing"
+)
+
+func TestTaxBreakdown(t *testing.T) {
+	tests := []struct {
+		name   string
+		items  []model.OrderItem
+		groups []taxGroup
+	}{
+		{
+			name:   "no items",
+			items:  nil,
+			groups: []taxGroup{},
+		},
+		{
+			name: "one rate",
+			items: []model.OrderItem{
+				{LineTotal: 399800, Discount: 39980, TaxRate: 1800, Tax: 64767},
+			},
+			groups: []taxGroup{{rate: 1800, taxable: 359820, tax: 64767}},
+		},
+		{
+			name: "items of a rate are added up and rates sorted",
+			items: []model.OrderItem{
+				{LineTotal: 499900, TaxRate: 1800, Tax: 89982},
+				{LineTotal: 100000, Discount: 10000, TaxRate: 500, Tax: 4500},
+				{LineTotal: 20000, Discount: 20000, TaxRate: 1800, Tax: 0},
+				{LineTotal: 150050, Discount: 50, TaxRate: 1200, Tax: 18000},
+				{LineTotal: 1000, TaxRate: 500, Tax: 50},
+			},
+			groups: []taxGroup{
+				{rate: 500, taxable: 91000, tax: 4550},
+				{rate: 1200, taxable: 150000, tax: 18000},
+				{rate: 1800, taxable: 499900, tax: 89982},
+			},
+		},
+		{
+			name: "tax exempt items",
+			items: []model.OrderItem{
+				{LineTotal: 5000, TaxRate: 0},
+			},
+			groups: []taxGroup{{rate: 0, taxable: 5000}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := taxBreakdown(test.items); !reflect.DeepEqual(got, test.groups) {
+				t.Fatalf("expected %+v, got %+v", test.groups, got)
+			}
+		})
+	}
+}
+
+func TestRate(t *testing.T) {
+	tests := map[int]string{0: "0.00%", 5: "0.05%", 500: "5.00%", 1250: "12.50%", 1800: "18.00%"}
+	for bps, text := range tests {
+		if got := rate(bps); got != text {
+			t.Errorf("rate(%d): expected %s, got %s", bps, text, got)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package jobs
+
+import (
+	"audio_phile/database/dbHelper"
+	"audio_phile/model"
+	"github.com/sirupsen/logrus"
+)
+
+// Run tracks one execution of a background job in job_runs so admins can follow it
+type Run struct {
+	id        string
+	Processed int
+	Failed    int
+}
+
+// Start records the beginning of a run. Tracking is best effort: when the run cannot be recorded the
+// job still runs and only logs.
+func Start(job string) *Run {
+	runId, err := dbHelper.CreateJobRun(job)
+	if err != nil {
+		logrus.Errorf("failed to record start of job %s with error: %+v", job, err)
+	}
+	return &Run{id: runId}
+}
+
+// Done counts one processed item and publishes the progress
+func (run *Run) Done() {
+	run.Processed++
+	run.save(nil)
+}
+
+// Fail counts one item that could not be processed
+func (run *Run) Fail(err error) {
+	run.Processed++
+	run.Failed++
+	message := err.Error()
+	run.save(&message)
+}
+
+// Finish closes the run, err is the error that stopped the whole run if any. A run that got through with
+// some of its items failed is partial and one whose items all failed is failed, the last item error is kept.
+func (run *Run) Finish(err error) {
+	if run.id == "" {
+		return
+	}
+	status, message := run.outcome(err)
+	if err := dbHelper.FinishJobRun(run.id, status, message); err != nil {
+		logrus.Errorf("failed to record end of job run %s with error: %+v", run.id, err)
+	}
+}
+
+func (run *Run) outcome(err error) (model.JobStatus, *string) {
+	switch {
+	case err != nil:
+		message := err.Error()
+		return model.JobStatusFailed, &message
+	case run.Failed > 0 && run.Failed == run.Processed:
+		return model.JobStatusFailed, nil
+	case run.Failed > 0:
+		return model.JobStatusPartial, nil
+	}
+	return model.JobStatusSucceeded, nil
+}
+
+func (run *Run) save(lastError *string) {
+	if run.id == "" {
+		return
+	}
+	if err := dbHelper.UpdateJobRunProgress(run.id, run.Processed, run.Failed, lastError); err != nil {
+		logrus.Errorf("failed to record progress of job run %s with error: %+v", run.id, err)
+	}
+}
@@ -0,0 +1,37 @@
+package jobs
+
+import (
+	"audio_phile/model"
+	"errors"
+	"testing"
+)
+
+func TestOutcome(t *testing.T) {
+	tests := []struct {
+		name      string
+		processed int
+		failed    int
+		err       error
+		status    model.JobStatus
+		message   string
+	}{
+		{"nothing to do", 0, 0, nil, model.JobStatusSucceeded, ""},
+		{"all items done", 5, 0, nil, model.JobStatusSucceeded, ""},
+		{"some items failed", 5, 2, nil, model.JobStatusPartial, ""},
+		{"all items failed", 5, 5, nil, model.JobStatusFailed, ""},
+		{"run stopped", 3, 0, errors.New("database is down"), model.JobStatusFailed, "database is down"},
+		{"run stopped after failures", 3, 1, errors.New("database is down"), model.JobStatusFailed, "database is down"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			run := &Run{id: "run", Processed: test.processed, Failed: test.failed}
+			status, message := run.outcome(test.err)
+			if status != test.status {
+				t.Fatalf("expected status %s, got %s", test.status, status)
+			}
+			if (message == nil) != (test.message == "") || (message != nil && *message != test.message) {
+				t.Fatalf("expected message %q, got %v", test.message, message)
+			}
+		})
+	}
+}
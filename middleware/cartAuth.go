@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"audio_phile/cart"
+	"audio_phile/database"
+	"audio_phile/database/dbHelper"
+	"audio_phile/utils"
+	"context"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+)
+
+const (
+	CartContext ContextKeys = "cartId"
+)
+
+// ActiveCartId looks up the caller's active cart, tests swap it for a lookup that needs no database
+var ActiveCartId = func(userId string) (string, bool, error) {
+	return dbHelper.GetActiveCartId(database.Audiophile, userId)
+}
+
+// UserIdFromContext returns the id of the authenticated user
+func UserIdFromContext(r *http.Request) string {
+	user := r.Context().Value(UserContext).(map[string]interface{})
+	userId, _ := user["id"].(string)
+	return userId
+}
+
+// ActiveCartMiddleware resolves the caller's active cart from the token and only lets the request
+// through when the {cartId} in the URL is that cart. A cart of another user, or one of the caller's
+// past carts, is answered with 404 so that cart ids cannot be probed.
+func ActiveCartMiddleware(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cartId, exist, err := ActiveCartId(UserIdFromContext(r))
+		if err != nil {
+			utils.RespondError(w, http.StatusInternalServerError, err, "Failed to check cart existence")
+			return
+		}
+		if !exist || cartId != chi.URLParam(r, "cartId") {
+			utils.RespondError(w, http.StatusNotFound, nil, "Cart not found!")
+			return
+		}
+		ctx := context.WithValue(r.Context(), CartContext, cartId)
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// CartIdFromContext returns the cart resolved by ActiveCartMiddleware
+func CartIdFromContext(r *http.Request) string {
+	cartId, _ := r.Context().Value(CartContext).(string)
+	return cartId
+}
+
+// GuestCartMiddleware resolves the anonymous cart named by the X-Cart-Token header. Unknown tokens and
+// carts that were already merged into a user's cart are answered with 404.
+func GuestCartMiddleware(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cartId, exist, err := cart.GuestCartId(database.Audiophile, r.Header.Get(cart.GuestTokenHeader))
+		if err != nil {
+			utils.RespondError(w, http.StatusInternalServerError, err, "Failed to check cart existence")
+			return
+		}
+		if !exist {
+			utils.RespondError(w, http.StatusNotFound, nil, "Cart not found!")
+			return
+		}
+		ctx := context.WithValue(r.Context(), CartContext, cartId)
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -1,5 +1,13 @@
 package model
 
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
 type Role string
 type Category string
 type Address string
@@ -26,6 +34,154 @@ const (
 	CartStatusInActive Status = "inactive"
 )
 
+type PromotionKind string
+
+const (
+	PromotionPercentage       PromotionKind = "percentage"
+	PromotionFixedAmount      PromotionKind = "fixed_amount"
+	PromotionFreeShipping     PromotionKind = "free_shipping"
+	PromotionBuyXGetY         PromotionKind = "buy_x_get_y"
+	PromotionCategoryDiscount PromotionKind = "category_discount"
+)
+
+type OrderStatus string
+
+const (
+	OrderStatusPendingPayment OrderStatus = "pending_payment"
+	OrderStatusPaid           OrderStatus = "paid"
+	OrderStatusPacked         OrderStatus = "packed"
+	OrderStatusShipped        OrderStatus = "shipped"
+	OrderStatusDelivered      OrderStatus = "delivered"
+	OrderStatusCancelled      OrderStatus = "cancelled"
+	OrderStatusRefunded       OrderStatus = "refunded"
+)
+
+type PaymentStatus string
+
+const (
+	PaymentStatusCreated  PaymentStatus = "created"
+	PaymentStatusPending  PaymentStatus = "pending"
+	PaymentStatusCaptured PaymentStatus = "captured"
+	PaymentStatusFailed   PaymentStatus = "failed"
+	PaymentStatusRefunded PaymentStatus = "refunded"
+)
+
+type PaymentMethod string
+
+const (
+	PaymentMethodGateway     PaymentMethod = "gateway"
+	PaymentMethodCOD         PaymentMethod = "cod"
+	PaymentMethodStoreCredit PaymentMethod = "store_credit"
+)
+
+type CreditEntryKind string
+
+const (
+	CreditGoodwill   CreditEntryKind = "goodwill"
+	CreditAdjustment CreditEntryKind = "adjustment"
+	CreditGiftCard   CreditEntryKind = "gift_card"
+	CreditPayment    CreditEntryKind = "payment"
+	CreditRefund     CreditEntryKind = "refund"
+)
+
+type GiftCardTransactionKind string
+
+const (
+	GiftCardIssue      GiftCardTransactionKind = "issue"
+	GiftCardRedeem     GiftCardTransactionKind = "redeem"
+	GiftCardAdjustment GiftCardTransactionKind = "adjustment"
+	GiftCardDisable    GiftCardTransactionKind = "disable"
+)
+
+type ReturnStatus string
+
+const (
+	ReturnStatusRequested ReturnStatus = "requested"
+	ReturnStatusApproved  ReturnStatus = "approved"
+	ReturnStatusRejected  ReturnStatus = "rejected"
+	ReturnStatusInTransit ReturnStatus = "in_transit"
+	ReturnStatusReceived  ReturnStatus = "received"
+	ReturnStatusRefunded  ReturnStatus = "refunded"
+)
+
+type ReturnReason string
+
+const (
+	ReturnReasonDefective      ReturnReason = "defective"
+	ReturnReasonDamaged        ReturnReason = "damaged"
+	ReturnReasonWrongItem      ReturnReason = "wrong_item"
+	ReturnReasonNotAsDescribed ReturnReason = "not_as_described"
+	ReturnReasonChangedMind    ReturnReason = "changed_mind"
+	ReturnReasonOther          ReturnReason = "other"
+)
+
+type RefundStatus string
+
+const (
+	RefundStatusPending   RefundStatus = "pending"
+	RefundStatusSucceeded RefundStatus = "succeeded"
+	RefundStatusFailed    RefundStatus = "failed"
+)
+
+type JobStatus string
+
+const (
+	JobStatusRunning   JobStatus = "running"
+	JobStatusSucceeded JobStatus = "succeeded"
+	JobStatusPartial   JobStatus = "partial"
+	JobStatusFailed    JobStatus = "failed"
+)
+
+type ReservationStatus string
+
+const (
+	ReservationStatusActive    ReservationStatus = "active"
+	ReservationStatusReleased  ReservationStatus = "released"
+	ReservationStatusCommitted ReservationStatus = "committed"
+)
+
+type MovementType string
+
+const (
+	MovementReceipt    MovementType = "receipt"
+	MovementSale       MovementType = "sale"
+	MovementReturn     MovementType = "return"
+	MovementAdjustment MovementType = "adjustment"
+	MovementTransfer   MovementType = "transfer"
+)
+
+type StockAlertKind string
+
+const (
+	StockAlertLow        StockAlertKind = "low_stock"
+	StockAlertOutOfStock StockAlertKind = "out_of_stock"
+	StockAlertBackIn     StockAlertKind = "back_in_stock"
+)
+
+type ReviewStatus string
+
+const (
+	ReviewStatusPending  ReviewStatus = "pending"
+	ReviewStatusApproved ReviewStatus = "approved"
+	ReviewStatusHidden   ReviewStatus = "hidden"
+)
+
+type ProductSort string
+
+const (
+	ProductSortName    ProductSort = "name"
+	ProductSortPrice   ProductSort = "price"
+	ProductSortRating  ProductSort = "rating"
+	ProductSortReviews ProductSort = "reviews"
+)
+
+type ImportFormat string
+
+const (
+	ImportFormatCSV    ImportFormat = "csv"
+	ImportFormatNDJSON ImportFormat = "ndjson"
+)
+
 type UserRequestBody struct {
 	Name     string `json:"name" db:"name" validate:"required,min=3,max=15"`
 	Email    string `json:"email" db:"email" validate:"required,email"`
@@ -52,8 +208,8 @@ type ProductsRequest struct {
 	Name        string   `json:"name" db:"name" validate:"required"`
 	Price       int      `json:"price" db:"price" validate:"required"`
 	Description string   `json:"description" db:"description" validate:"required"`
-	IsAvailable bool     `json:"is_available" db:"is_available" validate:"required"`
-	Quantity    int      `json:"quantity" db:"quantity"`
+	IsAvailable bool     `json:"is_available" db:"is_available"`
+	Quantity    int      `json:"quantity" db:"quantity" validate:"gte=0"`
 	Category    Category `json:"category" db:"category" validate:"required"`
 }
 
@@ -69,12 +225,22 @@ type ProductsResponse struct {
 
 type Products struct {
 	ProductId   string   `json:"productId" db:"id"`
+	Sku         string   `json:"sku" db:"sku"`
 	Name        string   `json:"name" db:"name"`
 	Price       int      `json:"price" db:"price"`
 	Description string   `json:"description" db:"description"`
 	IsAvailable bool     `json:"isAvailable" db:"is_available"`
 	Quantity    int      `json:"quantity" db:"quantity"`
 	Category    Category `json:"category" db:"category"`
+	Available   int      `json:"availableQuantity" db:"available_quantity"`
+	Rating      float64  `json:"averageRating" db:"average_rating"`
+	ReviewCount int      `json:"reviewCount" db:"review_count"`
+}
+
+type ProductFilter struct {
+	MinRating float64
+	SortBy    ProductSort
+	Desc      bool
 }
 
 type User struct {
@@ -86,14 +252,18 @@ type AddressRequest struct {
 	UserID      string  `json:"userID" db:"user_id"`
 	Address     string  `json:"address" db:"address"`
 	AddressType Address `json:"address_type" db:"address_type"`
-	Lat         float64 `json:"lat" db:"lat"`
-	Long        float64 `json:"long" db:"long"`
+	Region      string  `json:"region" db:"region"`
+	PostalCode  string  `json:"postalCode" db:"postal_code" validate:"max=20"`
+	Lat         float64 `json:"lat" db:"lat" validate:"gte=-90,lte=90"`
+	Long        float64 `json:"long" db:"long" validate:"gte=-180,lte=180"`
 }
 
 type AddressModel struct {
 	Id          string  `json:"id" db:"id"`
 	Address     string  `json:"address" db:"address"`
 	AddressType Address `json:"address_type" db:"address_type"`
+	Region      string  `json:"region" db:"region"`
+	PostalCode  string  `json:"postalCode" db:"postal_code"`
 	Lat         float64 `json:"lat" db:"lat"`
 	Long        float64 `json:"long" db:"long"`
 }
@@ -118,8 +288,872 @@ type CartProduct struct {
 	Quantity    int    `json:"quantity" db:"quantity"`
 }
 
-type QuantityOfProductInCart struct {
-	CartId    string `json:"cartId" db:"cart_id"`
+type CartQuantityRequest struct {
+	Quantity *int `json:"quantity" validate:"required,gte=0"`
+}
+
+type Cart struct {
+	Id      string          `json:"id"`
+	Items   []CartProduct   `json:"items"`
+	Pricing *PriceBreakdown `json:"pricing,omitempty"`
+}
+
+type ProductImportRow struct {
+	Sku         string   `json:"sku" db:"sku"`
+	Name        string   `json:"name" db:"name" validate:"required"`
+	Price       int      `json:"price" db:"price" validate:"required,gt=0"`
+	Description string   `json:"description" db:"description" validate:"required"`
+	IsAvailable bool     `json:"is_available" db:"is_available"`
+	Quantity    int      `json:"quantity" db:"quantity" validate:"gte=0"`
+	Category    Category `json:"category" db:"category" validate:"required,oneof=headphones speakers earphones"`
+}
+
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Sku   string `json:"sku,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Error string `json:"error"`
+}
+
+type ImportReport struct {
+	DryRun  bool             `json:"dryRun"`
+	Total   int              `json:"total"`
+	Created int              `json:"created"`
+	Updated int              `json:"updated"`
+	Failed  int              `json:"failed"`
+	Errors  []ImportRowError `json:"errors"`
+}
+
+type ReviewRequest struct {
+	Rating int    `json:"rating" validate:"required,min=1,max=5"`
+	Title  string `json:"title" validate:"required,max=120"`
+	Body   string `json:"body" validate:"max=5000"`
+}
+
+type Review struct {
+	Id        string       `json:"id" db:"id"`
+	ProductId string       `json:"productId" db:"product_id"`
+	UserId    string       `json:"userId" db:"user_id"`
+	UserName  string       `json:"userName" db:"user_name"`
+	Rating    int          `json:"rating" db:"rating"`
+	Title     string       `json:"title" db:"title"`
+	Body      string       `json:"body" db:"body"`
+	Status    ReviewStatus `json:"status" db:"status"`
+	CreatedAt time.Time    `json:"createdAt" db:"created_at"`
+}
+
+type CartLine struct {
 	ProductId string `json:"productId" db:"product_id"`
 	Quantity  int    `json:"quantity" db:"quantity"`
 }
+
+type StockReservation struct {
+	Id        string            `json:"id" db:"id"`
+	CartId    string            `json:"cartId" db:"cart_id"`
+	ProductId string            `json:"productId" db:"product_id"`
+	Quantity  int               `json:"quantity" db:"quantity"`
+	Status    ReservationStatus `json:"status" db:"status"`
+	ExpiresAt time.Time         `json:"expiresAt" db:"expires_at"`
+}
+
+type WarehouseRequest struct {
+	Code      string  `json:"code" validate:"required,max=20"`
+	Name      string  `json:"name" validate:"required"`
+	Address   string  `json:"address"`
+	Lat       float64 `json:"lat" validate:"gte=-90,lte=90"`
+	Long      float64 `json:"long" validate:"gte=-180,lte=180"`
+	IsDefault bool    `json:"isDefault"`
+}
+
+type Warehouse struct {
+	Id        string  `json:"id" db:"id"`
+	Code      string  `json:"code" db:"code"`
+	Name      string  `json:"name" db:"name"`
+	Address   string  `json:"address" db:"address"`
+	Lat       float64 `json:"lat" db:"lat"`
+	Long      float64 `json:"long" db:"long"`
+	IsDefault bool    `json:"isDefault" db:"is_default"`
+}
+
+type WarehouseStock struct {
+	WarehouseId   string `json:"warehouseId" db:"warehouse_id"`
+	WarehouseCode string `json:"warehouseCode" db:"warehouse_code"`
+	ProductId     string `json:"productId" db:"product_id"`
+	ProductName   string `json:"productName" db:"product_name"`
+	Quantity      int    `json:"quantity" db:"quantity"`
+}
+
+type StockMovementRequest struct {
+	ProductId     string       `json:"productId" validate:"required,uuid"`
+	WarehouseId   string       `json:"warehouseId" validate:"required,uuid"`
+	ToWarehouseId string       `json:"toWarehouseId" validate:"required_if=Type transfer,omitempty,uuid,nefield=WarehouseId"`
+	Type          MovementType `json:"type" validate:"required,oneof=receipt sale return adjustment transfer"`
+	Quantity      int          `json:"quantity" validate:"required"`
+	Reason        string       `json:"reason" validate:"required"`
+	ReferenceId   string       `json:"referenceId"`
+	ActorId       string       `json:"-"`
+}
+
+type StockMovement struct {
+	Id            string       `json:"id" db:"id"`
+	ProductId     string       `json:"productId" db:"product_id"`
+	WarehouseId   string       `json:"warehouseId" db:"warehouse_id"`
+	Type          MovementType `json:"type" db:"movement_type"`
+	Quantity      int          `json:"quantity" db:"quantity"`
+	Reason        string       `json:"reason" db:"reason"`
+	ActorId       *string      `json:"actorId" db:"actor_id"`
+	ReferenceId   *string      `json:"referenceId" db:"reference_id"`
+	CorrelationId *string      `json:"correlationId" db:"correlation_id"`
+	CreatedAt     time.Time    `json:"createdAt" db:"created_at"`
+}
+
+type StockMovementFilter struct {
+	ProductId   string
+	WarehouseId string
+	Type        MovementType
+	Limit       int
+}
+
+type ThresholdRequest struct {
+	ReorderThreshold int `json:"reorderThreshold" validate:"gte=0"`
+}
+
+type StockState struct {
+	ProductId        string `db:"id"`
+	Name             string `db:"name"`
+	IsAvailable      bool   `db:"is_available"`
+	OnHand           int    `db:"on_hand"`
+	Sellable         int    `db:"sellable"`
+	ReorderThreshold int    `db:"reorder_threshold"`
+	LowStockAlerted  bool   `db:"low_stock_alerted"`
+}
+
+type StockAlert struct {
+	Id               string         `json:"id" db:"id"`
+	ProductId        string         `json:"productId" db:"product_id"`
+	ProductName      string         `json:"productName" db:"product_name"`
+	Kind             StockAlertKind `json:"kind" db:"kind"`
+	SellableQuantity int            `json:"sellableQuantity" db:"sellable_quantity"`
+	Threshold        int            `json:"threshold" db:"threshold"`
+	Attempts         int            `json:"attempts" db:"attempts"`
+	LastError        *string        `json:"lastError" db:"last_error"`
+	CreatedAt        time.Time      `json:"createdAt" db:"created_at"`
+	DeliveredAt      *time.Time     `json:"deliveredAt" db:"delivered_at"`
+}
+
+type RestockSubscription struct {
+	Id          string     `json:"id" db:"id"`
+	ProductId   string     `json:"productId" db:"product_id"`
+	ProductName string     `json:"productName" db:"product_name"`
+	UserId      string     `json:"userId" db:"user_id"`
+	Email       string     `json:"-" db:"email"`
+	CreatedAt   time.Time  `json:"createdAt" db:"created_at"`
+	QueuedAt    *time.Time `json:"queuedAt" db:"queued_at"`
+	Attempts    int        `json:"-" db:"attempts"`
+}
+
+// Money is an amount in minor units (1/100 of the currency) and is written to JSON as a decimal
+type Money int64
+
+func (m Money) String() string {
+	sign := ""
+	value := int64(m)
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, value/100, value%100)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	value, err := strconv.ParseFloat(strings.Trim(string(data), `"`), 64)
+	if err != nil {
+		return err
+	}
+	*m = Money(math.Round(value * 100))
+	return nil
+}
+
+// FromPrice converts a catalog price, kept in whole currency units, to Money
+func FromPrice(price int) Money {
+	return Money(price) * 100
+}
+
+type TaxRateRequest struct {
+	Name     string   `json:"name" validate:"required"`
+	Category Category `json:"category" validate:"omitempty,oneof=headphones speakers earphones"`
+	Region   string   `json:"region"`
+	RateBps  int      `json:"rateBps" validate:"gte=0,lte=10000"`
+}
+
+type TaxRate struct {
+	Id       string  `json:"id" db:"id"`
+	Name     string  `json:"name" db:"name"`
+	Category *string `json:"category" db:"category"`
+	Region   *string `json:"region" db:"region"`
+	RateBps  int     `json:"rateBps" db:"rate_bps"`
+}
+
+type PricingLine struct {
+	ProductId string   `json:"productId" db:"product_id"`
+	Name      string   `json:"name" db:"name"`
+	Category  Category `json:"category" db:"category"`
+	Price     int      `json:"-" db:"price"`
+	Quantity  int      `json:"quantity" db:"quantity"`
+	// WeightGrams is the weight of one unit
+	WeightGrams int `json:"-" db:"weight_grams"`
+}
+
+type PricedLine struct {
+	ProductId string   `json:"productId"`
+	Name      string   `json:"name"`
+	Category  Category `json:"category"`
+	UnitPrice Money    `json:"unitPrice"`
+	Quantity  int      `json:"quantity"`
+	LineTotal Money    `json:"lineTotal"`
+	Discount  Money    `json:"discount"`
+	TaxRate   int      `json:"taxRateBps"`
+	Tax       Money    `json:"tax"`
+	Total     Money    `json:"total"`
+}
+
+type AppliedDiscount struct {
+	PromotionId string        `json:"promotionId"`
+	Name        string        `json:"name"`
+	Code        *string       `json:"code"`
+	Kind        PromotionKind `json:"kind"`
+	Amount      Money         `json:"amount"`
+}
+
+type PriceBreakdown struct {
+	Region        string            `json:"region"`
+	Lines         []PricedLine      `json:"lines"`
+	Discounts     []AppliedDiscount `json:"discounts"`
+	Coupon        *string           `json:"coupon"`
+	CouponError   string            `json:"couponError,omitempty"`
+	FreeShipping  bool              `json:"freeShipping"`
+	Shipping      *ShippingQuote    `json:"shipping"`
+	ShippingError string            `json:"shippingError,omitempty"`
+	Subtotal      Money             `json:"subtotal"`
+	DiscountTotal Money             `json:"discountTotal"`
+	TaxTotal      Money             `json:"taxTotal"`
+	ShippingTotal Money             `json:"shippingTotal"`
+	GrandTotal    Money             `json:"grandTotal"`
+}
+
+type PromotionRequest struct {
+	Name         string        `json:"name" validate:"required"`
+	Code         string        `json:"code" validate:"omitempty,alphanum,max=32"`
+	Kind         PromotionKind `json:"kind" validate:"required,oneof=percentage fixed_amount free_shipping buy_x_get_y category_discount"`
+	Value        int           `json:"value" validate:"gte=0"`
+	Category     Category      `json:"category" validate:"omitempty,oneof=headphones speakers earphones"`
+	ProductId    string        `json:"productId" validate:"omitempty,uuid"`
+	BuyQuantity  int           `json:"buyQuantity" validate:"gte=0"`
+	GetQuantity  int           `json:"getQuantity" validate:"gte=0"`
+	MinCartValue int           `json:"minCartValue" validate:"gte=0"`
+	UsageLimit   *int          `json:"usageLimit" validate:"omitempty,gt=0"`
+	PerUserLimit *int          `json:"perUserLimit" validate:"omitempty,gt=0"`
+	StartsAt     *time.Time    `json:"startsAt"`
+	EndsAt       *time.Time    `json:"endsAt"`
+}
+
+type Promotion struct {
+	Id           string        `json:"id" db:"id"`
+	Name         string        `json:"name" db:"name"`
+	Code         *string       `json:"code" db:"code"`
+	Kind         PromotionKind `json:"kind" db:"kind"`
+	Value        int           `json:"value" db:"value"`
+	Category     *Category     `json:"category" db:"category"`
+	ProductId    *string       `json:"productId" db:"product_id"`
+	BuyQuantity  int           `json:"buyQuantity" db:"buy_quantity"`
+	GetQuantity  int           `json:"getQuantity" db:"get_quantity"`
+	MinCartValue int           `json:"minCartValue" db:"min_cart_value"`
+	UsageLimit   *int          `json:"usageLimit" db:"usage_limit"`
+	PerUserLimit *int          `json:"perUserLimit" db:"per_user_limit"`
+	StartsAt     *time.Time    `json:"startsAt" db:"starts_at"`
+	EndsAt       *time.Time    `json:"endsAt" db:"ends_at"`
+	Uses         int           `json:"uses" db:"uses"`
+	UserUses     int           `json:"-" db:"user_uses"`
+	CreatedAt    time.Time     `json:"createdAt" db:"created_at"`
+}
+
+type CartMergeLine struct {
+	ProductId string `json:"productId"`
+	Requested int    `json:"requested"`
+	Quantity  int    `json:"quantity"`
+}
+
+// CartMergeResult describes how a guest cart was folded into a user's cart. Lines lists the products whose
+// quantity had to be cut to the available stock, or dropped because the product is gone.
+type CartMergeResult struct {
+	CartId string          `json:"cartId"`
+	Lines  []CartMergeLine `json:"adjustedLines"`
+}
+
+type CouponRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type JobRun struct {
+	Id         string     `json:"id" db:"id"`
+	Job        string     `json:"job" db:"job"`
+	Status     JobStatus  `json:"status" db:"status"`
+	Processed  int        `json:"processed" db:"processed"`
+	Failed     int        `json:"failed" db:"failed"`
+	LastError  *string    `json:"lastError" db:"last_error"`
+	StartedAt  time.Time  `json:"startedAt" db:"started_at"`
+	FinishedAt *time.Time `json:"finishedAt" db:"finished_at"`
+}
+
+type AbandonedCart struct {
+	CartId string `db:"id"`
+	Name   string `db:"name"`
+	Email  string `db:"email"`
+}
+
+type WishlistRequest struct {
+	Name string `json:"name" validate:"required,max=60"`
+}
+
+type WishlistItemRequest struct {
+	Quantity int `json:"quantity" validate:"gte=0"`
+}
+
+type Wishlist struct {
+	Id         string    `json:"id" db:"id"`
+	Name       string    `json:"name" db:"name"`
+	ShareToken *string   `json:"shareToken" db:"share_token"`
+	ItemCount  int       `json:"itemCount" db:"item_count"`
+	CreatedAt  time.Time `json:"createdAt" db:"created_at"`
+}
+
+type WishlistItem struct {
+	ProductId    string    `json:"productId" db:"product_id"`
+	Name         string    `json:"name" db:"name"`
+	Quantity     int       `json:"quantity" db:"quantity"`
+	PriceAtAdd   int       `json:"priceAtAdd" db:"price_at_add"`
+	Price        int       `json:"price" db:"price"`
+	PriceDropped bool      `json:"priceDropped" db:"price_dropped"`
+	IsAvailable  bool      `json:"isAvailable" db:"is_available"`
+	AddedAt      time.Time `json:"addedAt" db:"created_at"`
+}
+
+type WishlistDetail struct {
+	Wishlist
+	Items []WishlistItem `json:"items"`
+}
+
+type OrderStatusRequest struct {
+	Status   OrderStatus `json:"status" validate:"required,oneof=pending_payment paid packed shipped delivered cancelled refunded"`
+	Note     string      `json:"note" validate:"max=1000"`
+	Tracking *Tracking   `json:"tracking"`
+}
+
+// Tracking is given when an order is shipped
+type Tracking struct {
+	Carrier        string `json:"carrier" validate:"required"`
+	TrackingNumber string `json:"trackingNumber" validate:"required"`
+	TrackingUrl    string `json:"trackingUrl" validate:"omitempty,url"`
+}
+
+type OrderStatusChange struct {
+	Id         string       `json:"id" db:"id"`
+	OrderId    string       `json:"orderId" db:"order_id"`
+	FromStatus *OrderStatus `json:"fromStatus" db:"from_status"`
+	ToStatus   OrderStatus  `json:"toStatus" db:"to_status"`
+	ActorId    *string      `json:"actorId" db:"actor_id"`
+	Note       *string      `json:"note" db:"note"`
+	CreatedAt  time.Time    `json:"createdAt" db:"created_at"`
+}
+
+type OrderSale struct {
+	ProductId   string `db:"product_id"`
+	WarehouseId string `db:"warehouse_id"`
+	Quantity    int    `db:"quantity"`
+}
+
+type OrderItem struct {
+	ProductId string   `json:"productId" db:"product_id"`
+	Name      string   `json:"name" db:"name"`
+	Category  Category `json:"category" db:"category"`
+	UnitPrice Money    `json:"unitPrice" db:"unit_price"`
+	Quantity  int      `json:"quantity" db:"quantity"`
+	LineTotal Money    `json:"lineTotal" db:"line_total"`
+	Discount  Money    `json:"discount" db:"discount"`
+	TaxRate   int      `json:"taxRateBps" db:"tax_rate"`
+	Tax       Money    `json:"tax" db:"tax"`
+	Total     Money    `json:"total" db:"total"`
+}
+
+type PlacedOrder struct {
+	OrderId string         `json:"orderId"`
+	Status  OrderStatus    `json:"status"`
+	Address AddressModel   `json:"shippingAddress"`
+	Items   []OrderItem    `json:"items"`
+	Pricing PriceBreakdown `json:"pricing"`
+}
+
+type OrderFilter struct {
+	UserId   string
+	Customer string
+	Status   OrderStatus
+	From     *time.Time
+	To       *time.Time
+	MinTotal Money
+	Limit    int
+	Offset   int
+}
+
+type OrderSummary struct {
+	Id         string      `json:"id" db:"id"`
+	Email      *string     `json:"customerEmail" db:"email"`
+	Status     OrderStatus `json:"status" db:"status"`
+	ItemCount  int         `json:"itemCount" db:"item_count"`
+	GrandTotal Money       `json:"grandTotal" db:"grand_total"`
+	CreatedAt  time.Time   `json:"createdAt" db:"created_at"`
+}
+
+type OrderPage struct {
+	Orders []OrderSummary `json:"orders"`
+	Page   int            `json:"page"`
+	Limit  int            `json:"limit"`
+	Total  int            `json:"total"`
+}
+
+type OrderShipping struct {
+	Address        *string    `json:"address" db:"shipping_address"`
+	Region         *string    `json:"region" db:"shipping_region"`
+	PostalCode     *string    `json:"postalCode" db:"shipping_postal_code"`
+	Lat            *float64   `json:"lat" db:"shipping_lat"`
+	Long           *float64   `json:"long" db:"shipping_long"`
+	Carrier        *string    `json:"carrier" db:"carrier"`
+	TrackingNumber *string    `json:"trackingNumber" db:"tracking_number"`
+	TrackingUrl    *string    `json:"trackingUrl" db:"tracking_url"`
+	ShippedAt      *time.Time `json:"shippedAt" db:"shipped_at"`
+	DeliveredAt    *time.Time `json:"deliveredAt" db:"delivered_at"`
+}
+
+type Order struct {
+	Id            string      `json:"id" db:"id"`
+	UserId        *string     `json:"userId" db:"user_id"`
+	Status        OrderStatus `json:"status" db:"status"`
+	Subtotal      Money       `json:"subtotal" db:"subtotal"`
+	DiscountTotal Money       `json:"discountTotal" db:"discount_total"`
+	TaxTotal      Money       `json:"taxTotal" db:"tax_total"`
+	ShippingTotal Money       `json:"shippingTotal" db:"shipping_total"`
+	GrandTotal    Money       `json:"grandTotal" db:"grand_total"`
+	// CreditApplied is the part of the grand total paid with store credit
+	CreditApplied Money `json:"creditApplied" db:"credit_applied"`
+	OrderShipping `json:"shipping"`
+	CreatedAt     time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt     *time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+type OrderDetail struct {
+	Order
+	Items    []OrderItem         `json:"items"`
+	History  []OrderStatusChange `json:"history"`
+	Payments []Payment           `json:"payments"`
+	Refunds  []Refund            `json:"refunds"`
+	Invoice  *Invoice            `json:"invoice"`
+}
+
+type OrderNoteRequest struct {
+	Body string `json:"body" validate:"required,max=5000"`
+}
+
+type OrderNote struct {
+	Id        string    `json:"id" db:"id"`
+	AuthorId  string    `json:"authorId" db:"author_id"`
+	Author    string    `json:"author" db:"author"`
+	Body      string    `json:"body" db:"body"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+type BulkOrderStatusRequest struct {
+	OrderIds []string `json:"orderIds" validate:"required,min=1,max=500,dive,uuid"`
+	OrderStatusRequest
+}
+
+type BulkOrderResult struct {
+	OrderId string `json:"orderId"`
+	Updated bool   `json:"updated"`
+	Error   string `json:"error,omitempty"`
+}
+
+// OrderExportRow is one line of the admin CSV export
+type OrderExportRow struct {
+	Id             string      `db:"id"`
+	CreatedAt      time.Time   `db:"created_at"`
+	Status         OrderStatus `db:"status"`
+	Email          *string     `db:"email"`
+	ItemCount      int         `db:"item_count"`
+	Subtotal       Money       `db:"subtotal"`
+	DiscountTotal  Money       `db:"discount_total"`
+	TaxTotal       Money       `db:"tax_total"`
+	ShippingTotal  Money       `db:"shipping_total"`
+	GrandTotal     Money       `db:"grand_total"`
+	Region         *string     `db:"shipping_region"`
+	Carrier        *string     `db:"carrier"`
+	TrackingNumber *string     `db:"tracking_number"`
+}
+
+type Payment struct {
+	Id             string        `json:"id" db:"id"`
+	OrderId        string        `json:"orderId" db:"order_id"`
+	Method         PaymentMethod `json:"method" db:"method"`
+	Provider       string        `json:"provider" db:"provider"`
+	IntentId       *string       `json:"intentId" db:"intent_id"`
+	Amount         Money         `json:"amount" db:"amount"`
+	Currency       string        `json:"currency" db:"currency"`
+	Status         PaymentStatus `json:"status" db:"status"`
+	RefundedAmount Money         `json:"refundedAmount" db:"refunded_amount"`
+	FailureReason  *string       `json:"failureReason" db:"failure_reason"`
+	CapturedAt     *time.Time    `json:"capturedAt" db:"captured_at"`
+	CreatedAt      time.Time     `json:"createdAt" db:"created_at"`
+	PaymentCollection
+}
+
+// PaymentCollection is what was collected in cash for a cash on delivery payment
+type PaymentCollection struct {
+	CollectedAmount     *Money     `json:"collectedAmount,omitempty" db:"collected_amount"`
+	CollectionReference *string    `json:"collectionReference,omitempty" db:"collection_reference"`
+	CollectedAt         *time.Time `json:"collectedAt,omitempty" db:"collected_at"`
+	CollectedBy         *string    `json:"collectedBy,omitempty" db:"collected_by"`
+}
+
+// PaymentRequest chooses how an order is paid. StoreCredit is taken off the user's credit first and the
+// rest is paid with Method, through the gateway when it is left out.
+type PaymentRequest struct {
+	Method      PaymentMethod `json:"method" validate:"omitempty,oneof=gateway cod"`
+	StoreCredit Money         `json:"storeCredit" validate:"gte=0"`
+	// GiftCardCode is redeemed into the user's store credit with the payment and used on top of StoreCredit
+	GiftCardCode string `json:"giftCardCode" validate:"max=64"`
+}
+
+// CodPayment is a cash on delivery payment with the state of its order
+type CodPayment struct {
+	Payment
+	OrderStatus OrderStatus `json:"orderStatus" db:"order_status"`
+	DeliveredAt *time.Time  `json:"deliveredAt" db:"delivered_at"`
+}
+
+type CodCollection struct {
+	PaymentId string `json:"paymentId" validate:"required,uuid"`
+	Amount    Money  `json:"amount" validate:"gte=0"`
+	Reference string `json:"reference" validate:"max=200"`
+}
+
+type CodReconcileRequest struct {
+	Collections []CodCollection `json:"collections" validate:"required,min=1,max=500,dive"`
+}
+
+type CodReconcileResult struct {
+	PaymentId  string `json:"paymentId"`
+	Reconciled bool   `json:"reconciled"`
+	Error      string `json:"error,omitempty"`
+}
+
+type PaymentWebhookEvent struct {
+	Id          string     `json:"id" db:"id"`
+	Provider    string     `json:"provider" db:"provider"`
+	EventId     string     `json:"eventId" db:"event_id"`
+	EventType   string     `json:"eventType" db:"event_type"`
+	Payload     string     `json:"payload" db:"payload"`
+	Attempts    int        `json:"attempts" db:"attempts"`
+	LastError   *string    `json:"lastError" db:"last_error"`
+	ReceivedAt  time.Time  `json:"receivedAt" db:"received_at"`
+	ProcessedAt *time.Time `json:"processedAt" db:"processed_at"`
+}
+
+type RefundRequest struct {
+	// Amount left out refunds everything that can still be refunded
+	Amount Money  `json:"amount" validate:"gte=0"`
+	Reason string `json:"reason" validate:"max=1000"`
+}
+
+type Refund struct {
+	Id               string       `json:"id" db:"id"`
+	OrderId          string       `json:"orderId" db:"order_id"`
+	PaymentId        string       `json:"paymentId" db:"payment_id"`
+	ReturnId         *string      `json:"returnId" db:"return_id"`
+	Amount           Money        `json:"amount" db:"amount"`
+	Status           RefundStatus `json:"status" db:"status"`
+	ProviderRefundId *string      `json:"providerRefundId" db:"provider_refund_id"`
+	Reason           *string      `json:"reason" db:"reason"`
+	FailureReason    *string      `json:"failureReason" db:"failure_reason"`
+	ActorId          *string      `json:"actorId" db:"actor_id"`
+	CreatedAt        time.Time    `json:"createdAt" db:"created_at"`
+}
+
+// RefundablePayment is a captured payment with what is left of it to refund
+type RefundablePayment struct {
+	Id         string        `db:"id"`
+	Method     PaymentMethod `db:"method"`
+	IntentId   string        `db:"intent_id"`
+	Refundable Money         `db:"refundable"`
+}
+
+// PendingRefund is a refund waiting for the provider, with what is needed to send it
+type PendingRefund struct {
+	Refund
+	Method   PaymentMethod `db:"method"`
+	IntentId string        `db:"intent_id"`
+	UserId   string        `db:"user_id"`
+}
+
+type ReturnItemRequest struct {
+	ProductId string       `json:"productId" validate:"required,uuid"`
+	Quantity  int          `json:"quantity" validate:"required,min=1"`
+	Reason    ReturnReason `json:"reason" validate:"required,oneof=defective damaged wrong_item not_as_described changed_mind other"`
+}
+
+type ReturnRequest struct {
+	Items   []ReturnItemRequest `json:"items" validate:"required,min=1,dive"`
+	Comment string              `json:"comment" validate:"max=2000"`
+}
+
+type ReturnDecisionRequest struct {
+	Note string `json:"note" validate:"max=1000"`
+}
+
+type ReturnReceiptRequest struct {
+	// Restock puts the returned units back into sellable stock, leave it out for defective goods
+	Restock bool   `json:"restock"`
+	Note    string `json:"note" validate:"max=1000"`
+}
+
+// ReturnableItem is an order line with the units of it already claimed by returns that were not rejected
+type ReturnableItem struct {
+	ProductId string `db:"product_id"`
+	Quantity  int    `db:"quantity"`
+	Total     Money  `db:"total"`
+	Returned  int    `db:"returned"`
+}
+
+type Return struct {
+	Id             string       `json:"id" db:"id"`
+	OrderId        string       `json:"orderId" db:"order_id"`
+	UserId         string       `json:"userId" db:"user_id"`
+	Status         ReturnStatus `json:"status" db:"status"`
+	Comment        *string      `json:"comment" db:"comment"`
+	ResolutionNote *string      `json:"resolutionNote" db:"resolution_note"`
+	ResolvedBy     *string      `json:"resolvedBy" db:"resolved_by"`
+	Carrier        *string      `json:"carrier" db:"carrier"`
+	TrackingNumber *string      `json:"trackingNumber" db:"tracking_number"`
+	TrackingUrl    *string      `json:"trackingUrl" db:"tracking_url"`
+	ShippedAt      *time.Time   `json:"shippedAt" db:"shipped_at"`
+	ReceivedAt     *time.Time   `json:"receivedAt" db:"received_at"`
+	RefundAmount   *Money       `json:"refundAmount" db:"refund_amount"`
+	CreatedAt      time.Time    `json:"createdAt" db:"created_at"`
+	UpdatedAt      *time.Time   `json:"updatedAt" db:"updated_at"`
+}
+
+type ReturnItem struct {
+	ProductId string       `json:"productId" db:"product_id"`
+	Name      string       `json:"name" db:"name"`
+	Quantity  int          `json:"quantity" db:"quantity"`
+	Reason    ReturnReason `json:"reason" db:"reason"`
+	Amount    Money        `json:"amount" db:"amount"`
+	Restocked bool         `json:"restocked" db:"restocked"`
+}
+
+type ReturnDetail struct {
+	Return
+	Items   []ReturnItem `json:"items"`
+	Refunds []Refund     `json:"refunds"`
+}
+
+type CreditEntryRequest struct {
+	// Amount is positive to give credit and negative to take it back
+	Amount    Money           `json:"amount" validate:"required"`
+	Kind      CreditEntryKind `json:"kind" validate:"required,oneof=goodwill adjustment"`
+	Note      string          `json:"note" validate:"required,max=1000"`
+	Reference string          `json:"reference" validate:"max=200"`
+}
+
+type CreditEntry struct {
+	Id        string          `json:"id" db:"id"`
+	UserId    string          `json:"userId" db:"user_id"`
+	Amount    Money           `json:"amount" db:"amount"`
+	Kind      CreditEntryKind `json:"kind" db:"kind"`
+	Reference *string         `json:"reference" db:"reference"`
+	Note      *string         `json:"note" db:"note"`
+	ActorId   *string         `json:"actorId" db:"actor_id"`
+	CreatedAt time.Time       `json:"createdAt" db:"created_at"`
+}
+
+type CreditStatement struct {
+	Balance Money         `json:"balance"`
+	Entries []CreditEntry `json:"entries"`
+}
+
+type GiftCardRequest struct {
+	Amount    Money      `json:"amount" validate:"required,gt=0"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+	Note      string     `json:"note" validate:"max=1000"`
+}
+
+type GiftCardAdjustmentRequest struct {
+	Amount Money  `json:"amount" validate:"required"`
+	Note   string `json:"note" validate:"required,max=1000"`
+}
+
+type GiftCardRedeemRequest struct {
+	Code string `json:"code" validate:"required,max=64"`
+}
+
+type GiftCard struct {
+	Id             string     `json:"id" db:"id"`
+	LastFour       string     `json:"lastFour" db:"last_four"`
+	InitialBalance Money      `json:"initialBalance" db:"initial_balance"`
+	Balance        Money      `json:"balance" db:"balance"`
+	ExpiresAt      *time.Time `json:"expiresAt" db:"expires_at"`
+	Note           *string    `json:"note" db:"note"`
+	IssuedBy       *string    `json:"issuedBy" db:"issued_by"`
+	DisabledAt     *time.Time `json:"disabledAt" db:"disabled_at"`
+	CreatedAt      time.Time  `json:"createdAt" db:"created_at"`
+}
+
+type GiftCardTransaction struct {
+	Id        string                  `json:"id" db:"id"`
+	Amount    Money                   `json:"amount" db:"amount"`
+	Kind      GiftCardTransactionKind `json:"kind" db:"kind"`
+	UserId    *string                 `json:"userId" db:"user_id"`
+	ActorId   *string                 `json:"actorId" db:"actor_id"`
+	Note      *string                 `json:"note" db:"note"`
+	CreatedAt time.Time               `json:"createdAt" db:"created_at"`
+}
+
+type GiftCardDetail struct {
+	GiftCard
+	Transactions []GiftCardTransaction `json:"transactions"`
+}
+
+// InvoiceParty is the seller or the buyer named on an invoice
+type InvoiceParty struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	Email   string `json:"email,omitempty"`
+	TaxId   string `json:"taxId,omitempty"`
+}
+
+type Invoice struct {
+	Id            string    `json:"id" db:"id"`
+	OrderId       string    `json:"orderId" db:"order_id"`
+	Number        string    `json:"number" db:"number"`
+	FinancialYear string    `json:"financialYear" db:"financial_year"`
+	Sequence      int       `json:"sequence" db:"sequence"`
+	SellerName    string    `json:"sellerName" db:"seller_name"`
+	SellerAddress string    `json:"sellerAddress" db:"seller_address"`
+	SellerTaxId   string    `json:"sellerTaxId" db:"seller_tax_id"`
+	BuyerName     string    `json:"buyerName" db:"buyer_name"`
+	BuyerEmail    string    `json:"buyerEmail" db:"buyer_email"`
+	BuyerAddress  string    `json:"buyerAddress" db:"buyer_address"`
+	Subtotal      Money     `json:"subtotal" db:"subtotal"`
+	DiscountTotal Money     `json:"discountTotal" db:"discount_total"`
+	TaxTotal      Money     `json:"taxTotal" db:"tax_total"`
+	ShippingTotal Money     `json:"shippingTotal" db:"shipping_total"`
+	GrandTotal    Money     `json:"grandTotal" db:"grand_total"`
+	CreditApplied Money     `json:"creditApplied" db:"credit_applied"`
+	Currency      string    `json:"currency" db:"currency"`
+	IssuedAt      time.Time `json:"issuedAt" db:"issued_at"`
+}
+
+type InvoiceBuyer struct {
+	Name  string `db:"name"`
+	Email string `db:"email"`
+}
+
+type ShippingRateRequest struct {
+	Name           string   `json:"name" validate:"required"`
+	MinDistanceKm  float64  `json:"minDistanceKm" validate:"gte=0"`
+	MaxDistanceKm  *float64 `json:"maxDistanceKm" validate:"omitempty,gtfield=MinDistanceKm"`
+	MinWeightGrams int      `json:"minWeightGrams" validate:"gte=0"`
+	MaxWeightGrams *int     `json:"maxWeightGrams" validate:"omitempty,gtfield=MinWeightGrams"`
+	BaseFee        Money    `json:"baseFee" validate:"gte=0"`
+	PerKgFee       Money    `json:"perKgFee" validate:"gte=0"`
+}
+
+type ShippingRate struct {
+	Id             string   `json:"id" db:"id"`
+	Name           string   `json:"name" db:"name"`
+	MinDistanceKm  float64  `json:"minDistanceKm" db:"min_distance_km"`
+	MaxDistanceKm  *float64 `json:"maxDistanceKm" db:"max_distance_km"`
+	MinWeightGrams int      `json:"minWeightGrams" db:"min_weight_grams"`
+	MaxWeightGrams *int     `json:"maxWeightGrams" db:"max_weight_grams"`
+	BaseFee        Money    `json:"baseFee" db:"base_fee"`
+	PerKgFee       Money    `json:"perKgFee" db:"per_kg_fee"`
+}
+
+// ShippingQuoteRequest asks what shipping a parcel of WeightGrams worth OrderValue to a location costs
+type ShippingQuoteRequest struct {
+	Lat         float64 `json:"lat" validate:"gte=-90,lte=90"`
+	Long        float64 `json:"long" validate:"gte=-180,lte=180"`
+	WeightGrams int     `json:"weightGrams" validate:"gte=0"`
+	OrderValue  Money   `json:"orderValue" validate:"gte=0"`
+}
+
+type ShippingQuote struct {
+	WarehouseId string  `json:"warehouseId"`
+	Warehouse   string  `json:"warehouse"`
+	DistanceKm  float64 `json:"distanceKm"`
+	WeightGrams int     `json:"weightGrams"`
+	RateId      string  `json:"rateId"`
+	Rate        string  `json:"rate"`
+	Fee         Money   `json:"fee"`
+	// Free tells the fee is waived, FreeReason why
+	Free       bool   `json:"free"`
+	FreeReason string `json:"freeReason,omitempty"`
+}
+
+type ProductWeightRequest struct {
+	WeightGrams int `json:"weightGrams" validate:"gte=0"`
+}
+
+type GeoPoint struct {
+	Lat  float64 `json:"lat" db:"lat" validate:"gte=-90,lte=90"`
+	Long float64 `json:"long" db:"long" validate:"gte=-180,lte=180"`
+}
+
+// DeliveryZoneRequest draws a zone as a polygon of at least three corners, lists its postal codes, or both
+type DeliveryZoneRequest struct {
+	Name        string     `json:"name" validate:"required"`
+	Polygon     []GeoPoint `json:"polygon" validate:"omitempty,min=3,dive"`
+	PostalCodes []string   `json:"postalCodes" validate:"required_without=Polygon,omitempty,dive,required,max=20"`
+}
+
+type DeliveryZone struct {
+	Id          string     `json:"id" db:"id"`
+	Name        string     `json:"name" db:"name"`
+	Polygon     []GeoPoint `json:"polygon"`
+	PostalCodes []string   `json:"postalCodes"`
+	CreatedAt   time.Time  `json:"createdAt" db:"created_at"`
+}
+
+type DeliveryZonePoint struct {
+	ZoneId string `db:"zone_id"`
+	GeoPoint
+}
+
+type DeliveryZonePostalCode struct {
+	ZoneId     string `db:"zone_id"`
+	PostalCode string `db:"postal_code"`
+}
+
+// ServiceabilityRequest asks whether we deliver to a location, a postal code or either
+type ServiceabilityRequest struct {
+	Lat        *float64 `json:"lat" validate:"required_with=Long,omitempty,gte=-90,lte=90"`
+	Long       *float64 `json:"long" validate:"required_with=Lat,omitempty,gte=-180,lte=180"`
+	PostalCode string   `json:"postalCode" validate:"required_without=Lat,max=20"`
+}
+
+type Serviceability struct {
+	Serviceable bool   `json:"serviceable"`
+	ZoneId      string `json:"zoneId,omitempty"`
+	Zone        string `json:"zone,omitempty"`
+}
@@ -0,0 +1,190 @@
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// Message is a single notification; Data is sent as the JSON payload to webhooks
+type Message struct {
+	Event   string      `json:"event"`
+	Subject string      `json:"subject"`
+	Body    string      `json:"body"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// Notifier delivers a message to wherever it is configured to go
+type Notifier interface {
+	Notify(message Message) error
+}
+
+// Mailer sends a plain text email
+type Mailer interface {
+	Send(to []string, subject, body string) error
+}
+
+// LogMailer writes emails to the log, it is used when no SMTP server is configured
+type LogMailer struct{}
+
+func (LogMailer) Send(to []string, subject, body string) error {
+	logrus.Infof("mail to %s: %s\n%s", strings.Join(to, ", "), subject, body)
+	return nil
+}
+
+type SMTPMailer struct {
+	Addr string
+	From string
+	Auth smtp.Auth
+}
+
+func (m SMTPMailer) Send(to []string, subject, body string) error {
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s",
+		m.From, strings.Join(to, ", "), subject, body)
+	return smtp.SendMail(m.Addr, m.Auth, m.From, to, []byte(msg))
+}
+
+// MailNotifier emails every message to a fixed list of recipients
+type MailNotifier struct {
+	Mailer Mailer
+	To     []string
+}
+
+func (n MailNotifier) Notify(message Message) error {
+	return n.Mailer.Send(n.To, message.Subject, message.Body)
+}
+
+// WebhookNotifier posts every message as JSON to a URL and treats any non-2xx answer as a failure
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+func (n WebhookNotifier) Notify(message Message) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	resp, err := n.Client.Post(n.URL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook answered with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Multi fans a message out to several notifiers and fails if any of them failed
+type Multi []Notifier
+
+func (m Multi) Notify(message Message) error {
+	var failures []string
+	for _, notifier := range m {
+		if err := notifier.Notify(message); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("notification failed: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// Channel is a single destination, one email recipient or one webhook, named by a stable key so that
+// deliveries can be tracked per destination
+type Channel struct {
+	Key      string
+	Notifier Notifier
+}
+
+// Channels splits a notifier into its destinations: a Multi into its parts and a MailNotifier into one
+// channel per recipient
+func Channels(notifier Notifier) []Channel {
+	switch n := notifier.(type) {
+	case Multi:
+		channels := make([]Channel, 0, len(n))
+		for _, part := range n {
+			channels = append(channels, Channels(part)...)
+		}
+		return channels
+	case MailNotifier:
+		channels := make([]Channel, 0, len(n.To))
+		for _, to := range n.To {
+			to = strings.TrimSpace(to)
+			channels = append(channels, Channel{Key: "mail:" + to, Notifier: MailNotifier{Mailer: n.Mailer, To: []string{to}}})
+		}
+		return channels
+	case WebhookNotifier:
+		return []Channel{{Key: "webhook:" + n.URL, Notifier: n}}
+	}
+	return []Channel{{Key: fmt.Sprintf("%T", notifier), Notifier: notifier}}
+}
+
+// NotifyOnce sends a message to the channels of notifier that are not in delivered yet and calls sent for
+// every channel it reached, so that a retry after a partial failure skips the channels already notified.
+// It fails if any channel failed.
+func NotifyOnce(notifier Notifier, message Message, delivered []string, sent func(key string) error) error {
+	done := make(map[string]bool, len(delivered))
+	for _, key := range delivered {
+		done[key] = true
+	}
+	var failures []string
+	for _, channel := range Channels(notifier) {
+		if done[channel.Key] {
+			continue
+		}
+		if err := channel.Notifier.Notify(message); err != nil {
+			failures = append(failures, channel.Key+": "+err.Error())
+			continue
+		}
+		done[channel.Key] = true
+		if err := sent(channel.Key); err != nil {
+			return err
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("notification failed: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// Mail is the mailer used for emails to customers and staff
+var Mail Mailer = LogMailer{}
+
+// MailerFromEnv returns an SMTP mailer when SMTP_ADDR is set and a LogMailer otherwise
+func MailerFromEnv() Mailer {
+	addr := os.Getenv("SMTP_ADDR")
+	if addr == "" {
+		return LogMailer{}
+	}
+	mailer := SMTPMailer{Addr: addr, From: os.Getenv("SMTP_FROM")}
+	if user := os.Getenv("SMTP_USER"); user != "" {
+		host := strings.Split(addr, ":")[0]
+		mailer.Auth = smtp.PlainAuth("", user, os.Getenv("SMTP_PASS"), host)
+	}
+	return mailer
+}
+
+// NotifierFromEnv builds the notifier for one kind of staff alert from <prefix>_EMAIL (comma separated
+// recipients) and <prefix>_WEBHOOK_URL. With neither set, messages are only logged.
+func NotifierFromEnv(prefix string, mailer Mailer) Notifier {
+	var notifiers Multi
+	if to := os.Getenv(prefix + "_EMAIL"); to != "" {
+		notifiers = append(notifiers, MailNotifier{Mailer: mailer, To: strings.Split(to, ",")})
+	}
+	if url := os.Getenv(prefix + "_WEBHOOK_URL"); url != "" {
+		notifiers = append(notifiers, WebhookNotifier{URL: url, Client: &http.Client{Timeout: 10 * time.Second}})
+	}
+	if len(notifiers) == 0 {
+		return MailNotifier{Mailer: LogMailer{}, To: []string{prefix}}
+	}
+	return notifiers
+}
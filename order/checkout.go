@@ -0,0 +1,77 @@
+package order
+
+import (
+	"audio_phile/cart"
+	"audio_phile/database/dbHelper"
+	"audio_phile/inventory"
+	"audio_phile/model"
+	"audio_phile/pricing"
+	"audio_phile/shipping"
+	"errors"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrCartNotActive   = errors.New("cart is not the user's active cart")
+	ErrAddressRequired = errors.New("a shipping address is required")
+)
+
+// Checkout turns the user's active cart into an order. It must run in a single transaction: the cart is
+// checked to still be the active one, stock is taken out of the warehouses, each priced line is copied
+// into order_items, the shipping address and totals are copied onto the order and the cart is closed.
+// Any failure rolls all of it back.
+func Checkout(tx *sqlx.Tx, userId, cartId string, address model.AddressModel) (model.PlacedOrder, error) {
+	if address.Id == "" {
+		return model.PlacedOrder{}, ErrAddressRequired
+	}
+	// zones may have changed since the address was saved
+	if err := shipping.CheckAddress(tx, address); err != nil {
+		return model.PlacedOrder{}, err
+	}
+	// locks the user, so a second checkout of the same cart waits and then finds it inactive
+	activeCartId, exist, err := cart.ActiveCartId(tx, userId, false)
+	if err != nil {
+		return model.PlacedOrder{}, err
+	}
+	if !exist || activeCartId != cartId {
+		return model.PlacedOrder{}, ErrCartNotActive
+	}
+
+	orderId, err := Create(tx, cartId, userId)
+	if err != nil {
+		return model.PlacedOrder{}, err
+	}
+	if _, err := inventory.CommitCart(tx, cartId, userId, orderId); err != nil {
+		return model.PlacedOrder{}, err
+	}
+	breakdown, err := pricing.QuoteForOrder(tx, cartId, address.Region, shipping.AddressPoint(address))
+	if err != nil {
+		return model.PlacedOrder{}, err
+	}
+	for _, line := range breakdown.Lines {
+		if err := dbHelper.CreateOrderItem(tx, orderId, line); err != nil {
+			return model.PlacedOrder{}, err
+		}
+	}
+	if err := pricing.RecordRedemptions(tx, breakdown, userId, orderId); err != nil {
+		return model.PlacedOrder{}, err
+	}
+	if err := dbHelper.UpdateOrderCheckout(tx, orderId, userId, address, breakdown); err != nil {
+		return model.PlacedOrder{}, err
+	}
+	if err := dbHelper.DeactivateCart(tx, cartId); err != nil {
+		return model.PlacedOrder{}, err
+	}
+
+	items, err := dbHelper.GetOrderItems(tx, orderId)
+	if err != nil {
+		return model.PlacedOrder{}, err
+	}
+	return model.PlacedOrder{
+		OrderId: orderId,
+		Status:  model.OrderStatusPendingPayment,
+		Address: address,
+		Items:   items,
+		Pricing: breakdown,
+	}, nil
+}
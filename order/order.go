@@ -0,0 +1,180 @@
+package order
+
+import (
+	"audio_phile/database/dbHelper"
+	"audio_phile/inventory"
+	"audio_phile/invoice"
+	"audio_phile/model"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+)
+
+// transitions lists, for every status, the statuses an order may move to next.
+// Cancelled and refunded are final. An order paid in cash on delivery is packed while still pending_payment.
+var transitions = map[model.OrderStatus][]model.OrderStatus{
+	model.OrderStatusPendingPayment: {model.OrderStatusPaid, model.OrderStatusPacked, model.OrderStatusCancelled},
+	model.OrderStatusPaid:           {model.OrderStatusPacked, model.OrderStatusCancelled, model.OrderStatusRefunded},
+	model.OrderStatusPacked:         {model.OrderStatusShipped, model.OrderStatusCancelled, model.OrderStatusRefunded},
+	model.OrderStatusShipped:        {model.OrderStatusDelivered},
+	model.OrderStatusDelivered:      {model.OrderStatusRefunded},
+}
+
+var (
+	ErrTrackingRequired = errors.New("tracking details are required to ship an order")
+	ErrPaymentRequired  = errors.New("only orders paid in cash on delivery can be packed before they are paid")
+	ErrNotPaidInFull    = errors.New("an order is paid only once captured payments cover its grand total")
+)
+
+// IllegalTransitionError is returned when an order is asked to move to a status it cannot reach from its current one
+type IllegalTransitionError struct {
+	From model.OrderStatus
+	To   model.OrderStatus
+}
+
+func (e *IllegalTransitionError) Error() string {
+	return fmt.Sprintf("order cannot move from %s to %s", e.From, e.To)
+}
+
+// IsKnownStatus tells whether status is one of the order statuses
+func IsKnownStatus(status model.OrderStatus) bool {
+	_, hasNext := transitions[status]
+	return hasNext || status == model.OrderStatusCancelled || status == model.OrderStatusRefunded
+}
+
+// CanTransition tells whether an order in status from may move to status to
+func CanTransition(from, to model.OrderStatus) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Create places an order for the cart in pending_payment and starts its status history
+func Create(tx *sqlx.Tx, cartId, actorId string) (string, error) {
+	orderId, err := dbHelper.CreateOrder(tx, cartId)
+	if err != nil {
+		return "", err
+	}
+	return orderId, dbHelper.CreateOrderStatusChange(tx, orderId, nil, model.OrderStatusPendingPayment, actorId, "")
+}
+
+// Transition moves an order to the requested status, recording who did it and why. Only the moves listed
+// in transitions are allowed. Shipping requires tracking details, which are stored on the order, and
+// cancelling an order puts the stock it took back into the warehouses and drops a cash on delivery payment.
+// Captured payments are not touched here, orders are cancelled through payment.CancelOrder, which refunds them.
+// An order moves to paid only once its captured payments cover the grand total, and then gets its invoice.
+func Transition(tx *sqlx.Tx, orderId string, change model.OrderStatusRequest, actorId string) error {
+	if change.Status == model.OrderStatusShipped && change.Tracking == nil {
+		return ErrTrackingRequired
+	}
+	from, err := dbHelper.LockOrderStatus(tx, orderId)
+	if err != nil {
+		return err
+	}
+	if !CanTransition(from, change.Status) {
+		return &IllegalTransitionError{From: from, To: change.Status}
+	}
+	if change.Status == model.OrderStatusPaid {
+		if err := requirePaidInFull(tx, orderId); err != nil {
+			return err
+		}
+	}
+	if from == model.OrderStatusPendingPayment && change.Status == model.OrderStatusPacked {
+		cod, err := dbHelper.HasOpenCodPayment(tx, orderId)
+		if err != nil {
+			return err
+		}
+		if !cod {
+			return ErrPaymentRequired
+		}
+	}
+	if err := dbHelper.UpdateOrderStatus(tx, orderId, change.Status); err != nil {
+		return err
+	}
+	if err := dbHelper.CreateOrderStatusChange(tx, orderId, &from, change.Status, actorId, change.Note); err != nil {
+		return err
+	}
+	switch change.Status {
+	case model.OrderStatusPaid:
+		_, err := invoice.Issue(tx, orderId)
+		return err
+	case model.OrderStatusShipped:
+		return dbHelper.UpdateOrderTracking(tx, orderId, *change.Tracking)
+	case model.OrderStatusDelivered:
+		return dbHelper.MarkOrderDelivered(tx, orderId)
+	case model.OrderStatusCancelled:
+		if err := dbHelper.FailOpenCodPayment(tx, orderId, "order cancelled"); err != nil {
+			return err
+		}
+		return inventory.RestockOrder(tx, orderId, actorId, "order cancelled")
+	}
+	return nil
+}
+
+func requirePaidInFull(tx *sqlx.Tx, orderId string) error {
+	placed, err := dbHelper.GetOrder(tx, orderId, "")
+	if err != nil {
+		return err
+	}
+	captured, err := dbHelper.GetOrderCaptured(tx, orderId)
+	if err != nil {
+		return err
+	}
+	if captured < placed.GrandTotal {
+		return ErrNotPaidInFull
+	}
+	return nil
+}
+
+// RecordEvent writes something that happened to an order without changing its status, e.g. a return or a
+// partial refund, into the order's status history
+func RecordEvent(tx *sqlx.Tx, orderId, actorId, note string) error {
+	status, err := dbHelper.LockOrderStatus(tx, orderId)
+	if err != nil {
+		return err
+	}
+	return dbHelper.CreateOrderStatusChange(tx, orderId, &status, status, actorId, note)
+}
+
+// IsIllegalTransition reports whether err is an IllegalTransitionError
+func IsIllegalTransition(err error) bool {
+	var transitionErr *IllegalTransitionError
+	return errors.As(err, &transitionErr)
+}
+
+// Detail returns an order with its items, status history, payments, refunds and invoice. With a userId only that user's order is
+// returned, anybody else's is reported as sql.ErrNoRows.
+func Detail(db sqlx.Queryer, orderId, userId string) (model.OrderDetail, error) {
+	placed, err := dbHelper.GetOrder(db, orderId, userId)
+	if err != nil {
+		return model.OrderDetail{}, err
+	}
+	items, err := dbHelper.GetOrderItems(db, placed.Id)
+	if err != nil {
+		return model.OrderDetail{}, err
+	}
+	history, err := dbHelper.GetOrderStatusHistory(placed.Id)
+	if err != nil {
+		return model.OrderDetail{}, err
+	}
+	payments, err := dbHelper.GetOrderPayments(db, placed.Id)
+	if err != nil {
+		return model.OrderDetail{}, err
+	}
+	refunds, err := dbHelper.GetOrderRefunds(db, placed.Id)
+	if err != nil {
+		return model.OrderDetail{}, err
+	}
+	detail := model.OrderDetail{Order: placed, Items: items, History: history, Payments: payments, Refunds: refunds}
+	issued, err := dbHelper.GetOrderInvoice(db, placed.Id)
+	if err == nil {
+		detail.Invoice = &issued
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return model.OrderDetail{}, err
+	}
+	return detail, nil
+}
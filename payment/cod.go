@@ -0,0 +1,65 @@
+package payment
+
+import (
+	"audio_phile/database"
+	"audio_phile/database/dbHelper"
+	"audio_phile/invoice"
+	"audio_phile/model"
+	"audio_phile/order"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrNotCodPayment   = errors.New("payment is not a cash on delivery payment")
+	ErrCodSettled      = errors.New("cash on delivery payment was already settled")
+	ErrCodNotDelivered = errors.New("cash is only collected once the order was delivered")
+)
+
+// ReconcileCod records the cash the courier collected for a cash on delivery payment of a delivered order.
+// The payment is captured, and the order invoiced, when the full amount was collected; otherwise it stays pending with the collected
+// amount recorded, reconciled is false, and it can be reconciled again once the difference was sorted out.
+func ReconcileCod(collection model.CodCollection, actorId string) (bool, error) {
+	var reconciled bool
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		// the order first, then the payment, like every other transaction touching both
+		status, err := dbHelper.LockPaymentOrderStatus(tx, collection.PaymentId)
+		if err != nil {
+			return err
+		}
+		payment, err := dbHelper.LockPayment(tx, collection.PaymentId)
+		if err != nil {
+			return err
+		}
+		if payment.Method != model.PaymentMethodCOD {
+			return ErrNotCodPayment
+		}
+		if payment.Status != model.PaymentStatusPending {
+			return ErrCodSettled
+		}
+		if status != model.OrderStatusDelivered {
+			return ErrCodNotDelivered
+		}
+		if err := dbHelper.RecordCodCollection(tx, payment.Id, collection.Amount, collection.Reference, actorId); err != nil {
+			return err
+		}
+
+		note := fmt.Sprintf("cash collected: %s of %s", collection.Amount, payment.Amount)
+		if collection.Reference != "" {
+			note += ", reference " + collection.Reference
+		}
+		reconciled = collection.Amount == payment.Amount
+		if reconciled {
+			if err := dbHelper.UpdatePaymentStatus(tx, payment.Id, model.PaymentStatusCaptured, ""); err != nil {
+				return err
+			}
+			// orders paid in cash never pass through paid, they are invoiced once the cash is in
+			if _, err := invoice.Issue(tx, payment.OrderId); err != nil {
+				return err
+			}
+		}
+		return order.RecordEvent(tx, payment.OrderId, actorId, note)
+	})
+	return reconciled, txErr
+}
@@ -0,0 +1,72 @@
+package payment
+
+import (
+	"audio_phile/model"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"strings"
+)
+
+var (
+	ErrStoreCreditUnavailable = errors.New("store credit cannot be used to pay")
+	ErrInsufficientCredit     = errors.New("not enough store credit")
+	ErrCodNotServiceable      = errors.New("cash on delivery is not available for the shipping address")
+)
+
+// CodLimitError is returned when an order is too large to be paid in cash on delivery
+type CodLimitError struct {
+	Amount model.Money
+	Limit  model.Money
+}
+
+func (e *CodLimitError) Error() string {
+	return fmt.Sprintf("cash on delivery is limited to %s, %s is due", e.Limit, e.Amount)
+}
+
+// IsCodLimit reports whether err is a CodLimitError
+func IsCodLimit(err error) bool {
+	var limitErr *CodLimitError
+	return errors.As(err, &limitErr)
+}
+
+// CODRules decide which orders may be paid in cash on delivery
+type CODRules struct {
+	// MaxAmount is the most that may be collected in cash for one order, zero disables cash on delivery
+	MaxAmount model.Money
+	// Regions are the shipping regions couriers collect cash in, any region when empty
+	Regions []string
+}
+
+// CashOnDelivery holds the rules for cash on delivery payments
+var CashOnDelivery = CODRules{MaxAmount: model.FromPrice(50000)}
+
+func (rules CODRules) check(amount model.Money, region string) error {
+	if amount > rules.MaxAmount {
+		return &CodLimitError{Amount: amount, Limit: rules.MaxAmount}
+	}
+	if len(rules.Regions) == 0 {
+		return nil
+	}
+	for _, allowed := range rules.Regions {
+		if strings.EqualFold(strings.TrimSpace(allowed), strings.TrimSpace(region)) {
+			return nil
+		}
+	}
+	return ErrCodNotServiceable
+}
+
+// Wallet holds balances customers can pay with, like store credit. Both calls run in the transaction
+// that records the payment or the refund, reference is the id of that payment or refund.
+type Wallet interface {
+	// Debit takes amount off the user's balance, failing with ErrInsufficientCredit when it is too low
+	Debit(tx *sqlx.Tx, userId string, amount model.Money, reference string) error
+	// Credit gives amount back to the user's balance
+	Credit(tx *sqlx.Tx, userId string, amount model.Money, reference string) error
+	// Redeem moves the balance of the gift card with code into the user's balance and returns it
+	Redeem(tx *sqlx.Tx, userId, code string) (model.Money, error)
+}
+
+// StoreCredit is the wallet store credit payments are drawn from, orders cannot be paid with store credit
+// while it is nil
+var StoreCredit Wallet
@@ -0,0 +1,125 @@
+package payment
+
+import (
+	"audio_phile/model"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	MockSignatureHeader = "X-Mock-Signature"
+	webhookTolerance    = 5 * time.Minute
+)
+
+// Mock is a local provider for development and tests. It keeps no state and its answers only depend on
+// its inputs: ids are derived from the idempotency key, and the cents of the amount choose the outcome
+// of a capture, .02 is declined, .03 stays pending until a webhook confirms it, anything else succeeds.
+// Webhooks are signed like real providers do, with HMAC-SHA256 over "<timestamp>.<payload>", and all
+// rejected while Secret is empty.
+type Mock struct {
+	Secret string
+	Now    func() time.Time
+}
+
+func (m Mock) Name() string {
+	return "mock"
+}
+
+func (m Mock) CreateIntent(idempotencyKey string, amount model.Money, currency string) (Intent, error) {
+	if amount <= 0 {
+		return Intent{}, fmt.Errorf("amount must be positive")
+	}
+	return Intent{Id: "mock_pi_" + digest(idempotencyKey), Amount: amount}, nil
+}
+
+func (m Mock) Capture(idempotencyKey, intentId string, amount model.Money) (CaptureResult, error) {
+	result := CaptureResult{IntentId: intentId, Status: CaptureSucceeded}
+	switch amount % 100 {
+	case 2:
+		result.Status, result.Reason = CaptureFailed, "card declined"
+	case 3:
+		result.Status = CapturePending
+	}
+	return result, nil
+}
+
+func (m Mock) Refund(idempotencyKey, intentId string, amount model.Money) (RefundResult, error) {
+	if amount <= 0 {
+		return RefundResult{}, fmt.Errorf("amount must be positive")
+	}
+	return RefundResult{Id: "mock_re_" + digest(intentId+":"+idempotencyKey), Amount: amount}, nil
+}
+
+func (m Mock) VerifyWebhook(payload []byte, header http.Header) (WebhookEvent, error) {
+	if m.Secret == "" {
+		return WebhookEvent{}, ErrWebhookDisabled
+	}
+	var timestamp, signature string
+	for _, part := range strings.Split(header.Get(MockSignatureHeader), ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || signature == "" {
+		return WebhookEvent{}, ErrInvalidSignature
+	}
+	expected, _ := hex.DecodeString(m.sign(timestamp, payload))
+	given, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, given) {
+		return WebhookEvent{}, ErrInvalidSignature
+	}
+	age := m.now().Sub(time.Unix(seconds, 0))
+	if age > webhookTolerance || age < -webhookTolerance {
+		return WebhookEvent{}, ErrStaleWebhook
+	}
+	return m.ParseEvent(payload)
+}
+
+func (m Mock) ParseEvent(payload []byte) (WebhookEvent, error) {
+	var event WebhookEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return WebhookEvent{}, err
+	}
+	if event.Id == "" || event.IntentId == "" {
+		return WebhookEvent{}, fmt.Errorf("event id and intent id are required")
+	}
+	return event, nil
+}
+
+// SignatureHeader returns the X-Mock-Signature value for a payload sent at the given time, so that
+// webhooks can be simulated locally
+func (m Mock) SignatureHeader(payload []byte, at time.Time) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + m.sign(timestamp, payload)
+}
+
+func (m Mock) sign(timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(m.Secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (m Mock) now() time.Time {
+	if m.Now != nil {
+		return m.Now()
+	}
+	return time.Now()
+}
+
+func digest(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:12])
+}
@@ -0,0 +1,115 @@
+package payment
+
+import (
+	"audio_phile/model"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMockCreateIntentIsDeterministic(t *testing.T) {
+	gateway := Mock{Secret: "test"}
+	first, err := gateway.CreateIntent("payment-1", 1500, "INR")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	again, _ := gateway.CreateIntent("payment-1", 1500, "INR")
+	other, _ := gateway.CreateIntent("payment-2", 1500, "INR")
+	if first.Id != again.Id {
+		t.Fatalf("same key gave intents %s and %s", first.Id, again.Id)
+	}
+	if first.Id == other.Id {
+		t.Fatalf("different keys gave the same intent %s", first.Id)
+	}
+	if first.Amount != 1500 {
+		t.Fatalf("expected amount 1500, got %d", first.Amount)
+	}
+	if _, err := gateway.CreateIntent("payment-3", 0, "INR"); err == nil {
+		t.Fatal("expected an error for a zero amount")
+	}
+}
+
+func TestMockCapture(t *testing.T) {
+	tests := []struct {
+		amount model.Money
+		status CaptureStatus
+	}{
+		{150000, CaptureSucceeded},
+		{150001, CaptureSucceeded},
+		{150002, CaptureFailed},
+		{150003, CapturePending},
+		{150099, CaptureSucceeded},
+	}
+	gateway := Mock{Secret: "test"}
+	for _, test := range tests {
+		result, err := gateway.Capture("payment-1", "mock_pi_1", test.amount)
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %v", test.amount, err)
+		}
+		if result.Status != test.status {
+			t.Errorf("%d: expected %s, got %s", test.amount, test.status, result.Status)
+		}
+		if result.IntentId != "mock_pi_1" {
+			t.Errorf("%d: expected intent mock_pi_1, got %s", test.amount, result.IntentId)
+		}
+		if test.status == CaptureFailed && result.Reason == "" {
+			t.Errorf("%d: a failed capture needs a reason", test.amount)
+		}
+	}
+}
+
+func TestMockRefundIsDeterministic(t *testing.T) {
+	gateway := Mock{Secret: "test"}
+	first, err := gateway.Refund("refund-1", "mock_pi_1", 500)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	again, _ := gateway.Refund("refund-1", "mock_pi_1", 500)
+	other, _ := gateway.Refund("refund-2", "mock_pi_1", 500)
+	if first.Id != again.Id || first.Id == other.Id {
+		t.Fatalf("refund ids not keyed by idempotency key: %s %s %s", first.Id, again.Id, other.Id)
+	}
+	if _, err := gateway.Refund("refund-3", "mock_pi_1", -1); err == nil {
+		t.Fatal("expected an error for a negative amount")
+	}
+}
+
+func TestMockVerifyWebhook(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	gateway := Mock{Secret: "test", Now: func() time.Time { return now }}
+	payload := []byte(`{"id":"evt_1","type":"capture.updated","intentId":"mock_pi_1","status":"succeeded","amount":15.00}`)
+
+	tests := []struct {
+		name    string
+		gateway Mock
+		payload []byte
+		header  string
+		err     error
+	}{
+		{"valid", gateway, payload, gateway.SignatureHeader(payload, now), nil},
+		{"within tolerance", gateway, payload, gateway.SignatureHeader(payload, now.Add(-4*time.Minute)), nil},
+		{"stale", gateway, payload, gateway.SignatureHeader(payload, now.Add(-6*time.Minute)), ErrStaleWebhook},
+		{"from the future", gateway, payload, gateway.SignatureHeader(payload, now.Add(6*time.Minute)), ErrStaleWebhook},
+		{"other secret", gateway, payload, Mock{Secret: "other"}.SignatureHeader(payload, now), ErrInvalidSignature},
+		{"tampered payload", gateway, []byte(`{"id":"evt_1","type":"capture.updated","intentId":"mock_pi_1","status":"succeeded","amount":1.00}`),
+			gateway.SignatureHeader(payload, now), ErrInvalidSignature},
+		{"missing header", gateway, payload, "", ErrInvalidSignature},
+		{"no secret configured", Mock{Now: gateway.Now}, payload, Mock{}.SignatureHeader(payload, now), ErrWebhookDisabled},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			header := http.Header{}
+			if test.header != "" {
+				header.Set(MockSignatureHeader, test.header)
+			}
+			event, err := test.gateway.VerifyWebhook(test.payload, header)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected error %v, got %v", test.err, err)
+			}
+			if test.err == nil && (event.Id != "evt_1" || event.Amount != 1500 || event.Status != CaptureSucceeded) {
+				t.Fatalf("unexpected event %+v", event)
+			}
+		})
+	}
+}
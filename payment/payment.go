@@ -0,0 +1,306 @@
+package payment
+
+import (
+	"audio_phile/database"
+	"audio_phile/database/dbHelper"
+	"audio_phile/model"
+	"audio_phile/order"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"os"
+	"strings"
+)
+
+var (
+	// Gateway is the provider payments go through, set from FromEnv at startup
+	Gateway Provider
+	// Currency is charged for every order
+	Currency = "INR"
+
+	ErrOrderNotPayable = errors.New("order is not awaiting payment")
+	ErrNoProvider      = errors.New("PAYMENT_PROVIDER is not set")
+	ErrMockProvider    = errors.New("the mock payment provider is only allowed with APP_ENV=development")
+	ErrPaymentFailed   = errors.New("payment failed")
+)
+
+// FromEnv picks the provider from PAYMENT_PROVIDER, only the mock gateway exists so far, with the webhook
+// secret from PAYMENT_WEBHOOK_SECRET, and the currency from PAYMENT_CURRENCY. There is no default provider
+// and the mock one, which approves payments without charging anybody, is refused unless APP_ENV is
+// development. Without a secret every
+// webhook is rejected, there is no default a sender could guess. The cash on delivery limit,
+// in currency units, is read from COD_MAX_AMOUNT and the comma separated regions it is offered in from
+// COD_REGIONS.
+func FromEnv() (Provider, error) {
+	if currency := os.Getenv("PAYMENT_CURRENCY"); currency != "" {
+		Currency = currency
+	}
+	if limit := os.Getenv("COD_MAX_AMOUNT"); limit != "" {
+		if err := CashOnDelivery.MaxAmount.UnmarshalJSON([]byte(limit)); err != nil {
+			return nil, fmt.Errorf("invalid COD_MAX_AMOUNT %q: %w", limit, err)
+		}
+	}
+	if regions := os.Getenv("COD_REGIONS"); regions != "" {
+		CashOnDelivery.Regions = strings.Split(regions, ",")
+	}
+	switch name := os.Getenv("PAYMENT_PROVIDER"); name {
+	case "":
+		return nil, ErrNoProvider
+	case "mock":
+		if os.Getenv("APP_ENV") != "development" {
+			return nil, ErrMockProvider
+		}
+		return Mock{Secret: os.Getenv("PAYMENT_WEBHOOK_SECRET")}, nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider %q", name)
+	}
+}
+
+// Pay settles what is still due on a pending_payment order of the user, returning the payments it made.
+// Store credit is drawn first, up to req.StoreCredit plus what the gift card of req.GiftCardCode is worth,
+// and the rest is paid with req.Method. The gift card is redeemed in the transaction that draws the credit,
+// so a payment that fails before then leaves the card as it was:
+//   - cash on delivery, within the CashOnDelivery rules, leaves the order in pending_payment with a pending
+//     payment that an admin reconciles once the courier collected the cash;
+//   - the gateway, where the payment record is written before the provider is called and its id is the
+//     idempotency key, so a retry after a crash never charges twice. The order only moves to paid once the
+//     provider confirms the capture; a pending capture is confirmed later through a webhook.
+func Pay(orderId, userId string, req model.PaymentRequest) ([]model.Payment, error) {
+	if req.Method == "" {
+		req.Method = model.PaymentMethodGateway
+	}
+	payments := make([]model.Payment, 0)
+	var charge model.Payment
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		placed, err := dbHelper.GetOrder(tx, orderId, userId)
+		if err != nil {
+			return err
+		}
+		status, err := dbHelper.LockOrderStatus(tx, placed.Id)
+		if err != nil {
+			return err
+		}
+		if status != model.OrderStatusPendingPayment {
+			return ErrOrderNotPayable
+		}
+		open, err := dbHelper.GetOpenPayment(tx, placed.Id, model.PaymentMethodGateway)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if open.Status == model.PaymentStatusPending {
+			// already captured once, waiting for the provider to confirm
+			payments = append(payments, open)
+			return nil
+		}
+		cod, err := dbHelper.GetOpenPayment(tx, placed.Id, model.PaymentMethodCOD)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if cod.Id != "" {
+			if req.Method == model.PaymentMethodCOD {
+				payments = append(payments, cod)
+				return nil
+			}
+			if err := dbHelper.UpdatePaymentStatus(tx, cod.Id, model.PaymentStatusFailed, "replaced by a prepaid payment"); err != nil {
+				return err
+			}
+		}
+
+		captured, err := dbHelper.GetOrderCaptured(tx, placed.Id)
+		if err != nil {
+			return err
+		}
+		due := placed.GrandTotal - captured
+		if req.GiftCardCode != "" && due > 0 {
+			if StoreCredit == nil {
+				return ErrStoreCreditUnavailable
+			}
+			redeemed, err := StoreCredit.Redeem(tx, userId, req.GiftCardCode)
+			if err != nil {
+				return err
+			}
+			req.StoreCredit += redeemed
+		}
+		if req.StoreCredit > 0 && due > 0 {
+			credit, err := payWithStoreCredit(tx, placed.Id, userId, minMoney(req.StoreCredit, due))
+			if err != nil {
+				return err
+			}
+			payments = append(payments, credit)
+			due -= credit.Amount
+		}
+		if due <= 0 {
+			if open.Id != "" {
+				if err := dbHelper.UpdatePaymentStatus(tx, open.Id, model.PaymentStatusFailed, "nothing left to pay"); err != nil {
+					return err
+				}
+			}
+			note := "nothing to pay"
+			if len(payments) > 0 {
+				note = "paid with store credit"
+			}
+			return order.Transition(tx, placed.Id, model.OrderStatusRequest{Status: model.OrderStatusPaid, Note: note}, userId)
+		}
+
+		if open.Id != "" && (req.Method != model.PaymentMethodGateway || open.Amount != due) {
+			if err := dbHelper.UpdatePaymentStatus(tx, open.Id, model.PaymentStatusFailed, "replaced by a new payment"); err != nil {
+				return err
+			}
+			open = model.Payment{}
+		}
+		switch req.Method {
+		case model.PaymentMethodCOD:
+			var region string
+			if placed.Region != nil {
+				region = *placed.Region
+			}
+			if err := CashOnDelivery.check(due, region); err != nil {
+				return err
+			}
+			cod, err := dbHelper.CreatePayment(tx, placed.Id, model.PaymentMethodCOD, string(model.PaymentMethodCOD), due, Currency)
+			if err != nil {
+				return err
+			}
+			cod.Status = model.PaymentStatusPending
+			if err := dbHelper.UpdatePaymentStatus(tx, cod.Id, cod.Status, ""); err != nil {
+				return err
+			}
+			payments = append(payments, cod)
+			return order.RecordEvent(tx, placed.Id, userId, fmt.Sprintf("%s to be paid on delivery", due))
+		default:
+			charge = open
+			if charge.Id == "" {
+				charge, err = dbHelper.CreatePayment(tx, placed.Id, model.PaymentMethodGateway, Gateway.Name(), due, Currency)
+			}
+			return err
+		}
+	})
+	if txErr != nil || charge.Id == "" {
+		return payments, txErr
+	}
+
+	charged, err := capture(charge, userId)
+	payments = append(payments, charged)
+	return payments, err
+}
+
+// capture runs a gateway payment through the provider. The order and the payment stay locked until the
+// outcome is recorded, so a concurrent Pay of the same order waits and then finds the payment settled
+// instead of capturing it again; the payment id is the idempotency key of every provider call besides.
+func capture(payment model.Payment, actorId string) (model.Payment, error) {
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		if _, err := dbHelper.LockOrderStatus(tx, payment.OrderId); err != nil {
+			return err
+		}
+		locked, err := dbHelper.LockPayment(tx, payment.Id)
+		if err != nil {
+			return err
+		}
+		payment = locked
+		if payment.Status != model.PaymentStatusCreated {
+			// captured by a concurrent call, or already waiting for the provider to confirm
+			return nil
+		}
+		if payment.IntentId == nil {
+			intent, err := Gateway.CreateIntent(payment.Id, payment.Amount, payment.Currency)
+			if err != nil {
+				return err
+			}
+			if err := dbHelper.SetPaymentIntent(tx, payment.Id, intent.Id); err != nil {
+				return err
+			}
+			payment.IntentId = &intent.Id
+		}
+		result, err := Gateway.Capture(payment.Id, *payment.IntentId, payment.Amount)
+		if err != nil {
+			return err
+		}
+		payment, err = ApplyCapture(tx, payment.Id, result, actorId)
+		return err
+	})
+	if txErr != nil {
+		return payment, txErr
+	}
+	if payment.Status == model.PaymentStatusFailed {
+		return payment, ErrPaymentFailed
+	}
+	return payment, nil
+}
+
+func payWithStoreCredit(tx *sqlx.Tx, orderId, userId string, amount model.Money) (model.Payment, error) {
+	if StoreCredit == nil {
+		return model.Payment{}, ErrStoreCreditUnavailable
+	}
+	credit, err := dbHelper.CreatePayment(tx, orderId, model.PaymentMethodStoreCredit, string(model.PaymentMethodStoreCredit), amount, Currency)
+	if err != nil {
+		return credit, err
+	}
+	if err := StoreCredit.Debit(tx, userId, amount, credit.Id); err != nil {
+		return credit, err
+	}
+	if err := dbHelper.AddOrderCreditApplied(tx, orderId, amount); err != nil {
+		return credit, err
+	}
+	credit.Status = model.PaymentStatusCaptured
+	return credit, dbHelper.UpdatePaymentStatus(tx, credit.Id, credit.Status, "")
+}
+
+func minMoney(a, b model.Money) model.Money {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// ApplyCapture records the outcome of a capture on the payment and, when it succeeded and the order is
+// covered in full, moves the order to paid. Settled payments are left alone, so the same outcome can be applied any number of times.
+// The order is locked before the payment, in the order Pay and Refund take them.
+func ApplyCapture(tx *sqlx.Tx, paymentId string, result CaptureResult, actorId string) (model.Payment, error) {
+	status, err := dbHelper.LockPaymentOrderStatus(tx, paymentId)
+	if err != nil {
+		return model.Payment{}, err
+	}
+	payment, err := dbHelper.LockPayment(tx, paymentId)
+	if err != nil {
+		return payment, err
+	}
+	if payment.Status != model.PaymentStatusCreated && payment.Status != model.PaymentStatusPending {
+		return payment, nil
+	}
+
+	switch result.Status {
+	case CaptureSucceeded:
+		payment.Status = model.PaymentStatusCaptured
+	case CaptureFailed:
+		payment.Status = model.PaymentStatusFailed
+	default:
+		payment.Status = model.PaymentStatusPending
+	}
+	if err := dbHelper.UpdatePaymentStatus(tx, payment.Id, payment.Status, result.Reason); err != nil {
+		return payment, err
+	}
+	if payment.Status != model.PaymentStatusCaptured {
+		return payment, nil
+	}
+
+	if status != model.OrderStatusPendingPayment {
+		// e.g. cancelled while the capture was in flight, the payment has to be refunded by staff
+		return payment, nil
+	}
+	placed, err := dbHelper.GetOrder(tx, payment.OrderId, "")
+	if err != nil {
+		return payment, err
+	}
+	captured, err := dbHelper.GetOrderCaptured(tx, placed.Id)
+	if err != nil {
+		return payment, err
+	}
+	if captured < placed.GrandTotal {
+		return payment, nil
+	}
+	return payment, order.Transition(tx, payment.OrderId, model.OrderStatusRequest{
+		Status: model.OrderStatusPaid,
+		Note:   "payment " + payment.Id + " captured",
+	}, actorId)
+}
@@ -0,0 +1,48 @@
+package payment
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFromEnv(t *testing.T) {
+	unknown := errors.New("unknown")
+	tests := []struct {
+		name     string
+		provider string
+		appEnv   string
+		err      error
+	}{
+		{"no provider", "", "development", ErrNoProvider},
+		{"no provider in production", "", "production", ErrNoProvider},
+		{"mock in development", "mock", "development", nil},
+		{"mock in production", "mock", "production", ErrMockProvider},
+		{"mock without an environment", "mock", "", ErrMockProvider},
+		{"unknown provider", "cash-in-envelope", "development", unknown},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("PAYMENT_PROVIDER", test.provider)
+			t.Setenv("APP_ENV", test.appEnv)
+			t.Setenv("PAYMENT_WEBHOOK_SECRET", "secret")
+			provider, err := FromEnv()
+			switch {
+			case test.err == unknown:
+				if err == nil {
+					t.Fatalf("expected an unknown provider to be refused")
+				}
+			case !errors.Is(err, test.err):
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+			if err != nil {
+				if provider != nil {
+					t.Fatalf("expected no provider with an error, got %#v", provider)
+				}
+				return
+			}
+			if mock, ok := provider.(Mock); !ok || mock.Secret != "secret" {
+				t.Fatalf("expected the mock provider with the webhook secret, got %#v", provider)
+			}
+		})
+	}
+}
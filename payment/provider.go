@@ -0,0 +1,64 @@
+package payment
+
+import (
+	"audio_phile/model"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// CaptureStatus is what a provider says about a capture
+type CaptureStatus string
+
+const (
+	CaptureSucceeded CaptureStatus = "succeeded"
+	CapturePending   CaptureStatus = "pending"
+	CaptureFailed    CaptureStatus = "failed"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrStaleWebhook     = errors.New("webhook timestamp outside the accepted window")
+	ErrWebhookDisabled  = errors.New("no webhook secret configured")
+)
+
+// Intent is a payment set up with the provider for an amount, ready to be captured
+type Intent struct {
+	Id     string
+	Amount model.Money
+}
+
+type CaptureResult struct {
+	IntentId string
+	Status   CaptureStatus
+	Reason   string
+}
+
+type RefundResult struct {
+	Id     string
+	Amount model.Money
+}
+
+// WebhookEvent is a verified notification from the provider about an intent
+type WebhookEvent struct {
+	Id        string        `json:"id"`
+	Type      string        `json:"type"`
+	IntentId  string        `json:"intentId"`
+	Status    CaptureStatus `json:"status"`
+	Amount    model.Money   `json:"amount"`
+	Reason    string        `json:"reason,omitempty"`
+	CreatedAt time.Time     `json:"createdAt"`
+}
+
+// Provider is a payment gateway. idempotencyKey makes retries of the same call safe: the provider
+// answers a repeated call with the result of the first one instead of charging or refunding again.
+type Provider interface {
+	Name() string
+	CreateIntent(idempotencyKey string, amount model.Money, currency string) (Intent, error)
+	Capture(idempotencyKey, intentId string, amount model.Money) (CaptureResult, error)
+	Refund(idempotencyKey, intentId string, amount model.Money) (RefundResult, error)
+	// VerifyWebhook checks the signature and the age of a webhook and returns the event it carries
+	VerifyWebhook(payload []byte, header http.Header) (WebhookEvent, error)
+	// ParseEvent reads the event out of a payload that was verified before
+	ParseEvent(payload []byte) (WebhookEvent, error)
+}
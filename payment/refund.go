@@ -0,0 +1,318 @@
+package payment
+
+import (
+	"audio_phile/database"
+	"audio_phile/database/dbHelper"
+	"audio_phile/jobs"
+	"audio_phile/model"
+	"audio_phile/order"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+var (
+	ErrOrderNotRefundable = errors.New("order cannot be refunded in its current status")
+	ErrNothingToRefund    = errors.New("nothing left to refund on the order")
+	ErrRefundFailed       = errors.New("refund failed")
+	ErrReturnRefunded     = errors.New("return was already refunded")
+	ErrCashRefundNoUser   = errors.New("cash paid on delivery is refunded as store credit, which needs the order to belong to a user")
+)
+
+// RefundReconcileJob is the name runs of the pending refund reconciliation are recorded under
+const RefundReconcileJob = "refund_reconciliation"
+
+const reconcileBatchSize = 50
+
+// RefundExceedsError is returned when more is asked to be refunded than what is left of the captured payments
+type RefundExceedsError struct {
+	Requested  model.Money
+	Refundable model.Money
+}
+
+func (e *RefundExceedsError) Error() string {
+	return fmt.Sprintf("cannot refund %s, only %s is refundable", e.Requested, e.Refundable)
+}
+
+// refundable lists the statuses of orders money can be given back for. A cancelled order may still hold a
+// payment that was captured while it was being cancelled.
+var refundable = map[model.OrderStatus]bool{
+	model.OrderStatusCancelled: true,
+	model.OrderStatusPaid:      true,
+	model.OrderStatusPacked:    true,
+	model.OrderStatusShipped:   true,
+	model.OrderStatusDelivered: true,
+}
+
+// Refund gives amount back through the provider, or everything still refundable when amount is zero, taking
+// it from the order's captured payments oldest first. What was paid with store credit, or in cash on delivery,
+// goes back to the user's store credit. Like payments, every refund is written before the
+// provider is called and its id is the idempotency key. Each refund shows up in the order's status history
+// and the order moves to refunded once all of the captured money was given back. returnId links the
+// refunds to a return and may be empty; amount is then what the whole return is refunded, less what its
+// earlier refunds already gave back or are still giving back, so a retry after a failed refund only sends
+// the missing part.
+func Refund(orderId string, amount model.Money, returnId, actorId, reason string) ([]model.Refund, error) {
+	var pending []model.PendingRefund
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		placed, err := dbHelper.GetOrder(tx, orderId, "")
+		if err != nil {
+			return err
+		}
+		var userId string
+		if placed.UserId != nil {
+			userId = *placed.UserId
+		}
+		status, err := dbHelper.LockOrderStatus(tx, placed.Id)
+		if err != nil {
+			return err
+		}
+		if !refundable[status] {
+			return ErrOrderNotRefundable
+		}
+		payments, err := dbHelper.LockRefundablePayments(tx, orderId)
+		if err != nil {
+			return err
+		}
+		if returnId != "" {
+			// checked with the payments locked so two refunds of the same return cannot both go through
+			refunded, err := dbHelper.GetReturnRefunded(tx, returnId)
+			if err != nil {
+				return err
+			}
+			if refunded >= amount {
+				return ErrReturnRefunded
+			}
+			if err := dbHelper.SetReturnRefundAmount(tx, returnId, amount); err != nil {
+				return err
+			}
+			amount -= refunded
+		}
+		shares, err := split(payments, amount, userId)
+		if err != nil {
+			return err
+		}
+		for _, share := range shares {
+			refund, err := dbHelper.CreateRefund(tx, orderId, share.payment.Id, returnId, share.amount, reason, actorId)
+			if err != nil {
+				return err
+			}
+			pending = append(pending, model.PendingRefund{Refund: refund, Method: share.payment.Method, IntentId: share.payment.IntentId, UserId: userId})
+		}
+		return nil
+	})
+	if txErr != nil {
+		return nil, txErr
+	}
+
+	refunds := make([]model.Refund, 0, len(pending))
+	var refundErr error
+	for _, refund := range pending {
+		settled, err := settleRefund(refund, actorId)
+		if err != nil && !errors.Is(err, ErrRefundFailed) {
+			return refunds, err
+		}
+		if err != nil {
+			refundErr = err
+		}
+		refunds = append(refunds, settled)
+	}
+	return refunds, refundErr
+}
+
+// refundShare is what is given back of one payment
+type refundShare struct {
+	payment model.RefundablePayment
+	amount  model.Money
+}
+
+// split takes amount from the payments oldest first, or everything refundable when amount is zero. Cash
+// collected on delivery can only be given back as store credit, so it needs the user the order belongs to.
+func split(payments []model.RefundablePayment, amount model.Money, userId string) ([]refundShare, error) {
+	var total model.Money
+	for _, payment := range payments {
+		total += payment.Refundable
+	}
+	if total <= 0 {
+		return nil, ErrNothingToRefund
+	}
+	if amount == 0 {
+		amount = total
+	}
+	if amount > total {
+		return nil, &RefundExceedsError{Requested: amount, Refundable: total}
+	}
+
+	shares := make([]refundShare, 0, len(payments))
+	remaining := amount
+	for _, payment := range payments {
+		if remaining == 0 {
+			break
+		}
+		take := payment.Refundable
+		if take <= 0 {
+			continue
+		}
+		if take > remaining {
+			take = remaining
+		}
+		if payment.Method == model.PaymentMethodCOD && userId == "" {
+			return nil, ErrCashRefundNoUser
+		}
+		shares = append(shares, refundShare{payment: payment, amount: take})
+		remaining -= take
+	}
+	return shares, nil
+}
+
+// CancelOrder cancels an order and gives back everything captured for it, the way it was paid. The order is
+// cancelled first, which puts its stock back, so a refund that fails leaves a cancelled order whose refund can
+// be sent again through Refund, cancelled orders stay refundable for that.
+func CancelOrder(orderId string, change model.OrderStatusRequest, actorId string) ([]model.Refund, error) {
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		return order.Transition(tx, orderId, change, actorId)
+	})
+	if txErr != nil {
+		return nil, txErr
+	}
+	refunds, err := Refund(orderId, 0, "", actorId, "order cancelled")
+	if errors.Is(err, ErrNothingToRefund) {
+		return make([]model.Refund, 0), nil
+	}
+	return refunds, err
+}
+
+// settleRefund sends a pending refund and records the outcome, ErrRefundFailed tells the refund failed and was
+// marked so. Store credit and cash on delivery go straight back to the user's balance, gateway payments through the provider with
+// the refund id as idempotency key, so sending a refund again never gives the money back twice.
+func settleRefund(refund model.PendingRefund, actorId string) (model.Refund, error) {
+	var sendErr error
+	var providerRefundId string
+	if refund.Method != model.PaymentMethodGateway {
+		sendErr = database.Tx(func(tx *sqlx.Tx) error {
+			if StoreCredit == nil {
+				return ErrStoreCreditUnavailable
+			}
+			status, err := dbHelper.LockRefundStatus(tx, refund.Id)
+			if err != nil || status != model.RefundStatusPending {
+				// settled by a concurrent call, the balance was credited then
+				return err
+			}
+			if err := StoreCredit.Credit(tx, refund.UserId, refund.Amount, refund.Id); err != nil {
+				return err
+			}
+			return completeRefund(tx, refund.Refund, "", actorId)
+		})
+	} else {
+		result, err := Gateway.Refund(refund.Id, refund.IntentId, refund.Amount)
+		if err != nil {
+			sendErr = err
+		} else {
+			providerRefundId = result.Id
+			txErr := database.Tx(func(tx *sqlx.Tx) error {
+				return completeRefund(tx, refund.Refund, providerRefundId, actorId)
+			})
+			if txErr != nil {
+				return refund.Refund, txErr
+			}
+		}
+	}
+	if sendErr != nil {
+		logrus.Errorf("refund %s failed with error: %+v", refund.Id, sendErr)
+		refund.Status = model.RefundStatusFailed
+		if err := dbHelper.UpdateRefund(database.Audiophile, refund.Id, refund.Status, "", sendErr.Error()); err != nil {
+			return refund.Refund, err
+		}
+		return refund.Refund, ErrRefundFailed
+	}
+	refund.Status = model.RefundStatusSucceeded
+	if providerRefundId != "" {
+		refund.ProviderRefundId = &providerRefundId
+	}
+	return refund.Refund, nil
+}
+
+// StartRefundReconciler periodically sends again the refunds still pending after staleAfter, which a crash
+// left between writing the refund and recording the provider's answer. A refund the provider already made
+// is answered from its idempotency key and only recorded.
+func StartRefundReconciler(interval, staleAfter time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			reconcileRefunds(time.Now().Add(-staleAfter))
+		}
+	}()
+}
+
+func reconcileRefunds(staleSince time.Time) {
+	refunds, err := dbHelper.GetStalePendingRefunds(database.Audiophile, staleSince, reconcileBatchSize)
+	if err != nil {
+		logrus.Errorf("failed to get pending refunds with error: %+v", err)
+		return
+	}
+	if len(refunds) == 0 {
+		return
+	}
+	run := jobs.Start(RefundReconcileJob)
+	for _, refund := range refunds {
+		var actorId string
+		if refund.ActorId != nil {
+			actorId = *refund.ActorId
+		}
+		if _, err := settleRefund(refund, actorId); err != nil {
+			run.Fail(fmt.Errorf("refund %s: %w", refund.Id, err))
+			continue
+		}
+		run.Done()
+	}
+	run.Finish(nil)
+}
+
+// completeRefund books a refund the provider confirmed on its payment and on the order, once. It locks the
+// order, the payment and the refund in that order, the same as Refund.
+func completeRefund(tx *sqlx.Tx, refund model.Refund, providerRefundId, actorId string) error {
+	status, err := dbHelper.LockOrderStatus(tx, refund.OrderId)
+	if err != nil {
+		return err
+	}
+	if _, err := dbHelper.LockPayment(tx, refund.PaymentId); err != nil {
+		return err
+	}
+	refundStatus, err := dbHelper.LockRefundStatus(tx, refund.Id)
+	if err != nil || refundStatus != model.RefundStatusPending {
+		return err
+	}
+	if err := dbHelper.UpdateRefund(tx, refund.Id, model.RefundStatusSucceeded, providerRefundId, ""); err != nil {
+		return err
+	}
+	if err := dbHelper.AddPaymentRefund(tx, refund.PaymentId, refund.Amount); err != nil {
+		return err
+	}
+	if refund.ReturnId != nil {
+		if err := dbHelper.CompleteReturnRefund(tx, *refund.ReturnId); err != nil {
+			return err
+		}
+	}
+	note := fmt.Sprintf("refunded %s of payment %s", refund.Amount, refund.PaymentId)
+	if refund.Reason != nil {
+		note += ": " + *refund.Reason
+	}
+
+	unrefunded, err := dbHelper.GetOrderUnrefunded(tx, refund.OrderId)
+	if err != nil {
+		return err
+	}
+	if unrefunded == 0 && order.CanTransition(status, model.OrderStatusRefunded) {
+		return order.Transition(tx, refund.OrderId, model.OrderStatusRequest{Status: model.OrderStatusRefunded, Note: note}, actorId)
+	}
+	return order.RecordEvent(tx, refund.OrderId, actorId, note)
+}
+
+// IsRefundExceeds reports whether err is a RefundExceedsError
+func IsRefundExceeds(err error) bool {
+	var exceedsErr *RefundExceedsError
+	return errors.As(err, &exceedsErr)
+}
@@ -0,0 +1,85 @@
+package payment
+
+import (
+	"audio_phile/model"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	gateway := model.RefundablePayment{Id: "gateway", Method: model.PaymentMethodGateway, IntentId: "intent", Refundable: 60000}
+	credit := model.RefundablePayment{Id: "credit", Method: model.PaymentMethodStoreCredit, Refundable: 20000}
+	cash := model.RefundablePayment{Id: "cash", Method: model.PaymentMethodCOD, Refundable: 80000}
+	spent := model.RefundablePayment{Id: "spent", Method: model.PaymentMethodGateway, IntentId: "intent", Refundable: 0}
+
+	tests := []struct {
+		name     string
+		payments []model.RefundablePayment
+		amount   model.Money
+		userId   string
+		want     []refundShare
+		err      error
+	}{
+		{
+			name:     "everything when no amount is given",
+			payments: []model.RefundablePayment{credit, gateway},
+			userId:   "user",
+			want:     []refundShare{{credit, 20000}, {gateway, 60000}},
+		},
+		{
+			name:     "oldest payments first",
+			payments: []model.RefundablePayment{credit, gateway},
+			amount:   30000,
+			userId:   "user",
+			want:     []refundShare{{credit, 20000}, {gateway, 10000}},
+		},
+		{
+			name:     "fully refunded payments are skipped",
+			payments: []model.RefundablePayment{spent, gateway},
+			amount:   10000,
+			userId:   "user",
+			want:     []refundShare{{gateway, 10000}},
+		},
+		{
+			name:     "return of a cash on delivery order",
+			payments: []model.RefundablePayment{cash},
+			amount:   50000,
+			userId:   "user",
+			want:     []refundShare{{cash, 50000}},
+		},
+		{
+			name:     "cash on delivery split with store credit",
+			payments: []model.RefundablePayment{credit, cash},
+			userId:   "user",
+			want:     []refundShare{{credit, 20000}, {cash, 80000}},
+		},
+		{
+			name:     "cash on delivery of a guest order",
+			payments: []model.RefundablePayment{cash},
+			err:      ErrCashRefundNoUser,
+		},
+		{
+			name:     "nothing captured",
+			payments: []model.RefundablePayment{spent},
+			userId:   "user",
+			err:      ErrNothingToRefund,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shares, err := split(test.payments, test.amount, test.userId)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("split() error = %v, want %v", err, test.err)
+			}
+			if test.err == nil && !reflect.DeepEqual(shares, test.want) {
+				t.Errorf("split() = %+v, want %+v", shares, test.want)
+			}
+		})
+	}
+
+	_, err := split([]model.RefundablePayment{gateway}, 70000, "user")
+	if !IsRefundExceeds(err) {
+		t.Errorf("split() of more than refundable error = %v, want a RefundExceedsError", err)
+	}
+}
@@ -0,0 +1,94 @@
+package payment
+
+import (
+	"audio_phile/database"
+	"audio_phile/database/dbHelper"
+	"audio_phile/model"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+// EventCaptureUpdated reports the outcome of a capture, carried in the event's status
+const EventCaptureUpdated = "capture.updated"
+
+var ErrUnknownPayment = errors.New("no payment matches the event's intent")
+
+// ReceiveWebhook verifies a webhook, stores it and processes it. Redeliveries of an event that was already
+// processed are recognised by the event id and reported as duplicates without being applied again; together
+// with the provider's timestamp check this keeps a captured request from being replayed.
+func ReceiveWebhook(payload []byte, header http.Header) (model.PaymentWebhookEvent, bool, error) {
+	event, err := Gateway.VerifyWebhook(payload, header)
+	if err != nil {
+		return model.PaymentWebhookEvent{}, false, err
+	}
+	stored, created, err := dbHelper.CreateWebhookEvent(Gateway.Name(), event.Id, event.Type, string(payload))
+	if err != nil {
+		return stored, false, err
+	}
+	if stored.ProcessedAt != nil {
+		return stored, true, nil
+	}
+	return stored, !created, ProcessWebhookEvent(stored.Id, false)
+}
+
+// ProcessWebhookEvent applies a stored event. Processed events are skipped unless force is set, which
+// is safe since applying an event a second time changes nothing. Failures are recorded on the event.
+func ProcessWebhookEvent(id string, force bool) error {
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		stored, err := dbHelper.LockWebhookEvent(tx, id)
+		if err != nil {
+			return err
+		}
+		if stored.ProcessedAt != nil && !force {
+			return nil
+		}
+		if stored.Provider != Gateway.Name() {
+			return fmt.Errorf("event was received from %s, not from %s", stored.Provider, Gateway.Name())
+		}
+		event, err := Gateway.ParseEvent([]byte(stored.Payload))
+		if err != nil {
+			return err
+		}
+		if err := applyEvent(tx, event); err != nil {
+			return err
+		}
+		return dbHelper.MarkWebhookEventProcessed(tx, stored.Id)
+	})
+	if txErr != nil && !errors.Is(txErr, sql.ErrNoRows) {
+		if err := dbHelper.MarkWebhookEventFailed(id, txErr.Error()); err != nil {
+			logrus.Errorf("failed to record webhook event failure with error: %+v", err)
+		}
+	}
+	return txErr
+}
+
+func applyEvent(tx *sqlx.Tx, event WebhookEvent) error {
+	switch event.Type {
+	case EventCaptureUpdated:
+		paymentId, err := dbHelper.GetPaymentIdByIntent(tx, Gateway.Name(), event.IntentId)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUnknownPayment
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := dbHelper.LockPaymentOrderStatus(tx, paymentId); err != nil {
+			return err
+		}
+		payment, err := dbHelper.LockPayment(tx, paymentId)
+		if err != nil {
+			return err
+		}
+		if event.Amount != payment.Amount {
+			return fmt.Errorf("event amount %s does not match payment amount %s", event.Amount, payment.Amount)
+		}
+		_, err = ApplyCapture(tx, paymentId, CaptureResult{IntentId: event.IntentId, Status: event.Status, Reason: event.Reason}, "")
+		return err
+	}
+	// events of other types are kept for reference but need no processing
+	return nil
+}
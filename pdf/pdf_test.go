@@ -0,0 +1,101 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestWidth(t *testing.T) {
+	tests := []struct {
+		name  string
+		font  Font
+		s     string
+		size  float64
+		width float64
+	}{
+		{"courier", Courier, "Tax", 10, 18},
+		{"helvetica", Helvetica, "Tax", 10, 16.67},
+		{"helvetica bold", HelveticaBold, "Tax", 10, 17.23},
+		{"bold", HelveticaBold, "Taxable value", 9, 59.031},
+		{"proportional", Helvetica, "Taxable value", 9, 56.025},
+		{"outside ascii", Helvetica, "é", 10, 5.56},
+		{"empty", HelveticaBold, "", 10, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Width(test.font, test.s, test.size); math.Abs(got-test.width) > 1e-9 {
+				t.Fatalf("expected width %v, got %v", test.width, got)
+			}
+		})
+	}
+}
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		s       string
+		escaped string
+	}{
+		{"plain", "plain"},
+		{"(a) \\ b", "\\(a\\) \\\\ b"},
+		{"line\nbreak", "line break"},
+		{"₹100", "?100"},
+		{"café", "caf\\351"},
+	}
+	for _, test := range tests {
+		if got := escape(test.s); got != test.escaped {
+			t.Errorf("escape(%q): expected %q, got %q", test.s, test.escaped, got)
+		}
+	}
+}
+
+func TestBytesXref(t *testing.T) {
+	for _, pages := range []int{1, 3} {
+		t.Run(fmt.Sprintf("%d pages", pages), func(t *testing.T) {
+			doc := New()
+			for i := 0; i < pages; i++ {
+				if i > 0 {
+					doc.AddPage()
+				}
+				doc.Text(40, 800, Helvetica, 12, fmt.Sprintf("page (%d)", i+1))
+				doc.Line(40, 790, 555, 790, 0.5)
+			}
+			out := doc.Bytes()
+
+			startxref := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(out)
+			if startxref == nil {
+				t.Fatalf("no startxref at the end of the document")
+			}
+			xref, _ := strconv.Atoi(string(startxref[1]))
+			if !bytes.HasPrefix(out[xref:], []byte("xref\n")) {
+				t.Fatalf("startxref %d does not point at the xref table", xref)
+			}
+
+			objects := 2 + len(baseFonts) + 2*pages
+			header := regexp.MustCompile(`^xref\n0 (\d+)\n0000000000 65535 f \n`).FindSubmatch(out[xref:])
+			if header == nil {
+				t.Fatalf("malformed xref table")
+			}
+			if size, _ := strconv.Atoi(string(header[1])); size != objects+1 {
+				t.Fatalf("expected %d xref entries, got %d", objects+1, size)
+			}
+			entries := out[xref+len(header[0]):]
+			for i := 1; i <= objects; i++ {
+				entry := string(entries[(i-1)*20 : i*20])
+				var offset int
+				if _, err := fmt.Sscanf(entry, "%010d 00000 n \n", &offset); err != nil {
+					t.Fatalf("malformed xref entry %q of object %d", entry, i)
+				}
+				if want := fmt.Sprintf("%d 0 obj\n", i); !bytes.HasPrefix(out[offset:], []byte(want)) {
+					t.Errorf("xref offset %d of object %d does not point at its start", offset, i)
+				}
+			}
+			if !bytes.Contains(out, []byte(fmt.Sprintf("/Count %d", pages))) {
+				t.Errorf("expected the page tree to count %d pages", pages)
+			}
+		})
+	}
+}
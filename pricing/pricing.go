@@ -0,0 +1,136 @@
+package pricing
+
+import (
+	"audio_phile/database/dbHelper"
+	"audio_phile/model"
+	"audio_phile/shipping"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"strings"
+	"time"
+)
+
+// Quote prices the live lines of a cart for delivery to region, with shipping to destination when it is known.
+// The cart view and order creation both go through it, so the totals shown to the customer are the totals
+// that get charged.
+func Quote(db sqlx.Queryer, cartId, region string, destination *shipping.Point) (model.PriceBreakdown, error) {
+	lines, err := dbHelper.GetPricingLines(db, cartId)
+	if err != nil {
+		return model.PriceBreakdown{}, err
+	}
+	rates, err := dbHelper.GetTaxRates(db)
+	if err != nil {
+		return model.PriceBreakdown{}, err
+	}
+	userId, couponId, err := dbHelper.GetCartPromotionContext(db, cartId)
+	if err != nil {
+		return model.PriceBreakdown{}, err
+	}
+	candidateCouponId := ""
+	if couponId != nil {
+		candidateCouponId = *couponId
+	}
+	promotions, err := dbHelper.GetCandidatePromotions(db, userId, candidateCouponId)
+	if err != nil {
+		return model.PriceBreakdown{}, err
+	}
+	breakdown := price(lines, rates, promotions, couponId, region, time.Now())
+	if err := quoteShipping(db, &breakdown, lines, destination); err != nil {
+		return model.PriceBreakdown{}, err
+	}
+	return breakdown, nil
+}
+
+// quoteShipping adds shipping the cart to destination to the breakdown. An empty cart or an unknown
+// destination ships nothing, a delivery that cannot be shipped is reported on the breakdown.
+func quoteShipping(db sqlx.Queryer, breakdown *model.PriceBreakdown, lines []model.PricingLine, destination *shipping.Point) error {
+	if destination == nil || len(lines) == 0 {
+		return nil
+	}
+	weightGrams := 0
+	for _, line := range lines {
+		weightGrams += line.WeightGrams * line.Quantity
+	}
+	quote, err := shipping.Quote(db, *destination, weightGrams, breakdown.Subtotal-breakdown.DiscountTotal)
+	if errors.Is(err, shipping.ErrNotShippable) || errors.Is(err, shipping.ErrNoOrigin) {
+		breakdown.ShippingError = err.Error()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if breakdown.FreeShipping {
+		shipping.Waive(&quote, "free shipping promotion")
+	}
+	breakdown.Shipping = &quote
+	breakdown.ShippingTotal = shipping.Charge(quote)
+	breakdown.GrandTotal += breakdown.ShippingTotal
+	return nil
+}
+
+func price(lines []model.PricingLine, rates []model.TaxRate, promotions []model.Promotion, couponId *string, region string, now time.Time) model.PriceBreakdown {
+	breakdown := model.PriceBreakdown{
+		Region:    strings.ToUpper(strings.TrimSpace(region)),
+		Lines:     make([]model.PricedLine, 0, len(lines)),
+		Discounts: make([]model.AppliedDiscount, 0),
+	}
+	for _, line := range lines {
+		priced := model.PricedLine{
+			ProductId: line.ProductId,
+			Name:      line.Name,
+			Category:  line.Category,
+			UnitPrice: model.FromPrice(line.Price),
+			Quantity:  line.Quantity,
+			TaxRate:   rateFor(rates, line.Category, breakdown.Region),
+		}
+		priced.LineTotal = priced.UnitPrice * model.Money(line.Quantity)
+		breakdown.Subtotal += priced.LineTotal
+		breakdown.Lines = append(breakdown.Lines, priced)
+	}
+
+	applyPromotions(&breakdown, promotions, couponId, now)
+
+	for i := range breakdown.Lines {
+		line := &breakdown.Lines[i]
+		// tax is charged on what the customer pays for the line, after discounts
+		line.Tax = applyRate(line.LineTotal-line.Discount, line.TaxRate)
+		line.Total = line.LineTotal - line.Discount + line.Tax
+		breakdown.DiscountTotal += line.Discount
+		breakdown.TaxTotal += line.Tax
+	}
+	breakdown.GrandTotal = breakdown.Subtotal - breakdown.DiscountTotal + breakdown.TaxTotal
+	return breakdown
+}
+
+// rateFor picks the most specific active rate: category and region, then region, then category,
+// then the catch-all rate. Without any matching rate the line is not taxed.
+func rateFor(rates []model.TaxRate, category model.Category, region string) int {
+	best, bestScore := 0, -1
+	for _, rate := range rates {
+		score := 0
+		if rate.Region != nil {
+			if !strings.EqualFold(*rate.Region, region) {
+				continue
+			}
+			score += 2
+		}
+		if rate.Category != nil {
+			if *rate.Category != string(category) {
+				continue
+			}
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = rate.RateBps, score
+		}
+	}
+	return best
+}
+
+// applyRate returns amount * bps / 10000 rounded half up to the nearest minor unit
+func applyRate(amount model.Money, bps int) model.Money {
+	if amount <= 0 || bps == 0 {
+		return 0
+	}
+	return (amount*model.Money(bps) + 5000) / 10000
+}
@@ -0,0 +1,127 @@
+package pricing
+
+import (
+	"audio_phile/model"
+	"testing"
+	"time"
+)
+
+func text(value string) *string {
+	return &value
+}
+
+func TestRateFor(t *testing.T) {
+	tiers := []model.TaxRate{
+		{Name: "standard", RateBps: 1800},
+		{Name: "karnataka", Region: text("KA"), RateBps: 1200},
+		{Name: "headphones", Category: text("headphones"), RateBps: 500},
+		{Name: "headphones in karnataka", Category: text("headphones"), Region: text("KA"), RateBps: 300},
+	}
+	tests := []struct {
+		name     string
+		rates    []model.TaxRate
+		category model.Category
+		region   string
+		bps      int
+	}{
+		{"category and region", tiers, model.CategoryHeadphones, "KA", 300},
+		{"region beats category", tiers, model.CategorySpeakers, "KA", 1200},
+		{"category without region", tiers, model.CategoryHeadphones, "MH", 500},
+		{"catch-all", tiers, model.CategorySpeakers, "MH", 1800},
+		{"no region falls back to category", tiers, model.CategoryHeadphones, "", 500},
+		{"region compared without case", tiers[:2], model.CategorySpeakers, "ka", 1200},
+		{"no matching tier", tiers[1:3], model.CategorySpeakers, "MH", 0},
+		{"empty tier list", nil, model.CategoryHeadphones, "KA", 0},
+		{"zero rate still wins when most specific", []model.TaxRate{
+			{Name: "standard", RateBps: 1800},
+			{Name: "exempt", Category: text("earphones"), RateBps: 0},
+		}, model.CategoryEarphones, "KA", 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := rateFor(test.rates, test.category, test.region); got != test.bps {
+				t.Fatalf("expected %d bps, got %d", test.bps, got)
+			}
+		})
+	}
+}
+
+func TestApplyRate(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount model.Money
+		bps    int
+		tax    model.Money
+	}{
+		{"exact", 399800, 1800, 71964},
+		{"half a paisa rounds up", 125, 1800, 23},
+		{"just under half a paisa rounds down", 1, 4999, 0},
+		{"exactly half a paisa", 1, 5000, 1},
+		{"just over half a paisa", 199, 1800, 36},
+		{"whole amount", 12345, 10000, 12345},
+		{"zero rate", 12345, 0, 0},
+		{"zero amount", 0, 1800, 0},
+		{"fully discounted line", -10, 1800, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := applyRate(test.amount, test.bps); got != test.tax {
+				t.Fatalf("expected %d, got %d", test.tax, got)
+			}
+		})
+	}
+}
+
+func TestPrice(t *testing.T) {
+	rates := []model.TaxRate{
+		{Name: "standard", RateBps: 1800},
+		{Name: "karnataka", Region: text("KA"), RateBps: 1200},
+		{Name: "headphones", Category: text("headphones"), RateBps: 1800},
+	}
+	lines := []model.PricingLine{
+		{ProductId: "p1", Name: "Studio headphones", Category: model.CategoryHeadphones, Price: 1999, Quantity: 2},
+		{ProductId: "p2", Name: "Bookshelf speaker", Category: model.CategorySpeakers, Price: 4999, Quantity: 1},
+	}
+	tenPercent := model.Promotion{Id: "promo", Name: "ten off", Kind: model.PromotionPercentage, Value: 10}
+	now := time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		lines      []model.PricingLine
+		promotions []model.Promotion
+		taxes      []model.Money
+		discount   model.Money
+		tax        model.Money
+		grandTotal model.Money
+	}{
+		{"no promotion", lines, nil, []model.Money{47976, 59988}, 0, 107964, 1007664},
+		// the region rate beats the category rate, tax is charged after the discount and rounded per line:
+		// 359820 * 12% = 43178.4 and 449910 * 12% = 53989.2
+		{"taxed after discount", lines, []model.Promotion{tenPercent}, []model.Money{43178, 53989}, 89970, 97167, 906897},
+		{"empty cart", nil, []model.Promotion{tenPercent}, nil, 0, 0, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			breakdown := price(test.lines, rates, test.promotions, nil, " ka ", now)
+			if breakdown.Region != "KA" {
+				t.Fatalf("expected region KA, got %q", breakdown.Region)
+			}
+			if len(breakdown.Lines) != len(test.taxes) {
+				t.Fatalf("expected %d lines, got %d", len(test.taxes), len(breakdown.Lines))
+			}
+			for i, tax := range test.taxes {
+				line := breakdown.Lines[i]
+				if line.Tax != tax {
+					t.Errorf("line %d: expected tax %d, got %d", i, tax, line.Tax)
+				}
+				if line.Total != line.LineTotal-line.Discount+line.Tax {
+					t.Errorf("line %d: total %d does not add up", i, line.Total)
+				}
+			}
+			if breakdown.DiscountTotal != test.discount || breakdown.TaxTotal != test.tax || breakdown.GrandTotal != test.grandTotal {
+				t.Fatalf("expected discount %d, tax %d, grand total %d, got %d, %d, %d", test.discount, test.tax, test.grandTotal,
+					breakdown.DiscountTotal, breakdown.TaxTotal, breakdown.GrandTotal)
+			}
+		})
+	}
+}
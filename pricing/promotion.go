@@ -0,0 +1,197 @@
+package pricing
+
+import (
+	"audio_phile/database/dbHelper"
+	"audio_phile/model"
+	"audio_phile/shipping"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+var (
+	ErrCouponNotApplicable = errors.New("coupon cannot be applied")
+	ErrNotShippable        = errors.New("order cannot be shipped to this address")
+)
+
+// ineligibility returns why a promotion cannot be used on a cart worth subtotal right now, or "" if it can
+func ineligibility(promotion model.Promotion, subtotal model.Money, now time.Time) string {
+	switch {
+	case promotion.StartsAt != nil && now.Before(*promotion.StartsAt):
+		return "promotion has not started yet"
+	case promotion.EndsAt != nil && !now.Before(*promotion.EndsAt):
+		return "promotion has expired"
+	case promotion.UsageLimit != nil && promotion.Uses >= *promotion.UsageLimit:
+		return "promotion has been fully redeemed"
+	case promotion.PerUserLimit != nil && promotion.UserUses >= *promotion.PerUserLimit:
+		return "promotion was already used the maximum number of times"
+	case subtotal < model.FromPrice(promotion.MinCartValue):
+		return fmt.Sprintf("cart total must be at least %d", promotion.MinCartValue)
+	}
+	return ""
+}
+
+// appliesTo tells whether a line is in the scope of a promotion limited to a category or a product
+func appliesTo(promotion model.Promotion, line model.PricedLine) bool {
+	if promotion.Category != nil && *promotion.Category != line.Category {
+		return false
+	}
+	if promotion.ProductId != nil && *promotion.ProductId != line.ProductId {
+		return false
+	}
+	return true
+}
+
+// applyPromotion adds the promotion's discount to the lines it covers and returns the total discount.
+// Discounts stack in order and never take a line below zero.
+func applyPromotion(promotion model.Promotion, lines []model.PricedLine) model.Money {
+	var total model.Money
+	switch promotion.Kind {
+	case model.PromotionPercentage, model.PromotionCategoryDiscount:
+		percent := promotion.Value
+		if percent > 100 {
+			percent = 100
+		}
+		for i := range lines {
+			if !appliesTo(promotion, lines[i]) {
+				continue
+			}
+			remaining := lines[i].LineTotal - lines[i].Discount
+			discount := (remaining*model.Money(percent) + 50) / 100
+			lines[i].Discount += discount
+			total += discount
+		}
+	case model.PromotionFixedAmount:
+		var eligible model.Money
+		for _, line := range lines {
+			if appliesTo(promotion, line) {
+				eligible += line.LineTotal - line.Discount
+			}
+		}
+		amount := model.FromPrice(promotion.Value)
+		if amount > eligible {
+			amount = eligible
+		}
+		if amount == 0 {
+			return 0
+		}
+		// spread over the lines in proportion to their value so each line is taxed on what it really costs,
+		// the last line takes the rounding remainder
+		last := -1
+		for i := range lines {
+			if !appliesTo(promotion, lines[i]) || lines[i].LineTotal == lines[i].Discount {
+				continue
+			}
+			share := amount * (lines[i].LineTotal - lines[i].Discount) / eligible
+			lines[i].Discount += share
+			total += share
+			last = i
+		}
+		lines[last].Discount += amount - total
+		total = amount
+	case model.PromotionBuyXGetY:
+		group := promotion.BuyQuantity + promotion.GetQuantity
+		if promotion.GetQuantity == 0 || group == 0 {
+			return 0
+		}
+		for i := range lines {
+			if !appliesTo(promotion, lines[i]) {
+				continue
+			}
+			free := lines[i].Quantity / group * promotion.GetQuantity
+			discount := lines[i].UnitPrice * model.Money(free)
+			if remaining := lines[i].LineTotal - lines[i].Discount; discount > remaining {
+				discount = remaining
+			}
+			lines[i].Discount += discount
+			total += discount
+		}
+	}
+	return total
+}
+
+// applyPromotions runs the automatic promotions and then the cart's coupon over the breakdown. An automatic
+// promotion that is not eligible is skipped silently, a coupon that is not records why in CouponError.
+func applyPromotions(breakdown *model.PriceBreakdown, promotions []model.Promotion, couponId *string, now time.Time) {
+	couponFound := false
+	for _, promotion := range promotions {
+		isCoupon := couponId != nil && promotion.Id == *couponId
+		if isCoupon {
+			couponFound = true
+			breakdown.Coupon = promotion.Code
+		}
+		if reason := ineligibility(promotion, breakdown.Subtotal, now); reason != "" {
+			if isCoupon {
+				breakdown.CouponError = reason
+			}
+			continue
+		}
+		amount := applyPromotion(promotion, breakdown.Lines)
+		if promotion.Kind == model.PromotionFreeShipping {
+			breakdown.FreeShipping = true
+		} else if amount == 0 {
+			if isCoupon {
+				breakdown.CouponError = "coupon does not apply to any item in the cart"
+			}
+			continue
+		}
+		breakdown.Discounts = append(breakdown.Discounts, model.AppliedDiscount{
+			PromotionId: promotion.Id,
+			Name:        promotion.Name,
+			Code:        promotion.Code,
+			Kind:        promotion.Kind,
+			Amount:      amount,
+		})
+	}
+	if couponId != nil && !couponFound {
+		breakdown.CouponError = "coupon is no longer available"
+	}
+}
+
+// QuoteForOrder prices a cart that is being turned into an order. The promotions it uses are locked and the
+// cart priced again under the lock, so usage limits hold under concurrent orders. A coupon that no longer
+// applies fails the order instead of silently charging more than the customer was shown, and so does a
+// destination that cannot be shipped to.
+func QuoteForOrder(tx *sqlx.Tx, cartId, region string, destination *shipping.Point) (model.PriceBreakdown, error) {
+	breakdown, err := Quote(tx, cartId, region, destination)
+	if err != nil {
+		return breakdown, err
+	}
+	_, couponId, err := dbHelper.GetCartPromotionContext(tx, cartId)
+	if err != nil {
+		return breakdown, err
+	}
+	promotionIds := make([]string, 0, len(breakdown.Discounts)+1)
+	for _, discount := range breakdown.Discounts {
+		promotionIds = append(promotionIds, discount.PromotionId)
+	}
+	if couponId != nil {
+		promotionIds = append(promotionIds, *couponId)
+	}
+	if len(promotionIds) > 0 {
+		if err := dbHelper.LockPromotions(tx, promotionIds); err != nil {
+			return breakdown, err
+		}
+		if breakdown, err = Quote(tx, cartId, region, destination); err != nil {
+			return breakdown, err
+		}
+	}
+	if breakdown.CouponError != "" {
+		return breakdown, fmt.Errorf("%w: %s", ErrCouponNotApplicable, breakdown.CouponError)
+	}
+	if breakdown.ShippingError != "" {
+		return breakdown, fmt.Errorf("%w: %s", ErrNotShippable, breakdown.ShippingError)
+	}
+	return breakdown, nil
+}
+
+// RecordRedemptions counts every promotion used by an order towards its usage limits
+func RecordRedemptions(tx *sqlx.Tx, breakdown model.PriceBreakdown, userId, orderId string) error {
+	for _, discount := range breakdown.Discounts {
+		if err := dbHelper.CreatePromotionRedemption(tx, discount.PromotionId, userId, orderId, discount.Amount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
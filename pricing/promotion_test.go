@@ -0,0 +1,250 @@
+package pricing
+
+import (
+	"audio_phile/model"
+	"testing"
+	"time"
+)
+
+func pricedLine(productId string, category model.Category, unitPrice model.Money, quantity int) model.PricedLine {
+	return model.PricedLine{
+		ProductId: productId,
+		Category:  category,
+		UnitPrice: unitPrice,
+		Quantity:  quantity,
+		LineTotal: unitPrice * model.Money(quantity),
+	}
+}
+
+func TestApplyPromotion(t *testing.T) {
+	speakers := model.CategorySpeakers
+	tests := []struct {
+		name      string
+		promotion model.Promotion
+		lines     []model.PricedLine
+		discounts []model.Money
+		total     model.Money
+	}{
+		{
+			name:      "percentage rounds each line to the paisa",
+			promotion: model.Promotion{Kind: model.PromotionPercentage, Value: 10},
+			lines:     []model.PricedLine{pricedLine("p1", model.CategoryHeadphones, 1000, 1), pricedLine("p2", model.CategorySpeakers, 2995, 1)},
+			discounts: []model.Money{100, 300},
+			total:     400,
+		},
+		{
+			name:      "percentage is capped at the whole line",
+			promotion: model.Promotion{Kind: model.PromotionPercentage, Value: 150},
+			lines:     []model.PricedLine{pricedLine("p1", model.CategoryHeadphones, 1999, 2)},
+			discounts: []model.Money{3998},
+			total:     3998,
+		},
+		{
+			name:      "percentage of what earlier promotions left",
+			promotion: model.Promotion{Kind: model.PromotionPercentage, Value: 50},
+			lines:     []model.PricedLine{{ProductId: "p1", UnitPrice: 1000, Quantity: 1, LineTotal: 1000, Discount: 400}},
+			discounts: []model.Money{700},
+			total:     300,
+		},
+		{
+			name:      "category discount only touches its category",
+			promotion: model.Promotion{Kind: model.PromotionCategoryDiscount, Value: 20, Category: &speakers},
+			lines:     []model.PricedLine{pricedLine("p1", model.CategoryHeadphones, 1000, 1), pricedLine("p2", model.CategorySpeakers, 5000, 1)},
+			discounts: []model.Money{0, 1000},
+			total:     1000,
+		},
+		{
+			name:      "fixed amount is split in proportion to the lines",
+			promotion: model.Promotion{Kind: model.PromotionFixedAmount, Value: 30},
+			lines:     []model.PricedLine{pricedLine("p1", model.CategoryHeadphones, 1000, 1), pricedLine("p2", model.CategorySpeakers, 2000, 1)},
+			discounts: []model.Money{1000, 2000},
+			total:     3000,
+		},
+		{
+			name:      "fixed amount remainder goes to the last line",
+			promotion: model.Promotion{Kind: model.PromotionFixedAmount, Value: 10},
+			lines: []model.PricedLine{pricedLine("p1", model.CategoryHeadphones, 1000, 1), pricedLine("p2", model.CategorySpeakers, 1000, 1),
+				pricedLine("p3", model.CategoryEarphones, 1000, 1)},
+			discounts: []model.Money{333, 333, 334},
+			total:     1000,
+		},
+		{
+			name:      "fixed amount remainder skips lines out of scope",
+			promotion: model.Promotion{Kind: model.PromotionFixedAmount, Value: 10, Category: &speakers},
+			lines: []model.PricedLine{pricedLine("p1", model.CategorySpeakers, 1000, 1), pricedLine("p2", model.CategorySpeakers, 2000, 1),
+				pricedLine("p3", model.CategoryHeadphones, 1000, 1)},
+			discounts: []model.Money{333, 667, 0},
+			total:     1000,
+		},
+		{
+			name:      "fixed amount is capped at the eligible value",
+			promotion: model.Promotion{Kind: model.PromotionFixedAmount, Value: 50},
+			lines:     []model.PricedLine{pricedLine("p1", model.CategoryHeadphones, 1000, 3)},
+			discounts: []model.Money{3000},
+			total:     3000,
+		},
+		{
+			name:      "fixed amount skips fully discounted lines",
+			promotion: model.Promotion{Kind: model.PromotionFixedAmount, Value: 5},
+			lines: []model.PricedLine{pricedLine("p1", model.CategoryHeadphones, 1000, 1),
+				{ProductId: "p2", UnitPrice: 1000, Quantity: 1, LineTotal: 1000, Discount: 1000}},
+			discounts: []model.Money{500, 1000},
+			total:     500,
+		},
+		{
+			name:      "fixed amount on nothing eligible",
+			promotion: model.Promotion{Kind: model.PromotionFixedAmount, Value: 5, Category: &speakers},
+			lines:     []model.PricedLine{pricedLine("p1", model.CategoryHeadphones, 1000, 1)},
+			discounts: []model.Money{0},
+			total:     0,
+		},
+		{
+			name:      "buy two get one free per full group",
+			promotion: model.Promotion{Kind: model.PromotionBuyXGetY, BuyQuantity: 2, GetQuantity: 1},
+			lines:     []model.PricedLine{pricedLine("p1", model.CategoryEarphones, 500, 7)},
+			discounts: []model.Money{1000},
+			total:     1000,
+		},
+		{
+			name:      "buy x get y without a free item",
+			promotion: model.Promotion{Kind: model.PromotionBuyXGetY, BuyQuantity: 2},
+			lines:     []model.PricedLine{pricedLine("p1", model.CategoryEarphones, 500, 7)},
+			discounts: []model.Money{0},
+			total:     0,
+		},
+		{
+			name:      "product promotion only touches its product",
+			promotion: model.Promotion{Kind: model.PromotionPercentage, Value: 10, ProductId: text("p2")},
+			lines:     []model.PricedLine{pricedLine("p1", model.CategoryHeadphones, 1000, 1), pricedLine("p2", model.CategoryHeadphones, 1000, 1)},
+			discounts: []model.Money{0, 100},
+			total:     100,
+		},
+		{
+			name:      "free shipping discounts no line",
+			promotion: model.Promotion{Kind: model.PromotionFreeShipping},
+			lines:     []model.PricedLine{pricedLine("p1", model.CategoryHeadphones, 1000, 1)},
+			discounts: []model.Money{0},
+			total:     0,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if total := applyPromotion(test.promotion, test.lines); total != test.total {
+				t.Fatalf("expected a discount of %d, got %d", test.total, total)
+			}
+			for i, discount := range test.discounts {
+				if test.lines[i].Discount != discount {
+					t.Errorf("line %d: expected discount %d, got %d", i, discount, test.lines[i].Discount)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyPromotions(t *testing.T) {
+	now := time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC)
+	yesterday := now.Add(-24 * time.Hour)
+	percentage := model.Promotion{Id: "auto", Name: "ten off", Kind: model.PromotionPercentage, Value: 10}
+	fixed := model.Promotion{Id: "coupon", Name: "fifty off", Code: text("FIFTY"), Kind: model.PromotionFixedAmount, Value: 50}
+	expired := model.Promotion{Id: "expired", Name: "old sale", Kind: model.PromotionPercentage, Value: 50, EndsAt: &yesterday}
+	shipping := model.Promotion{Id: "shipping", Name: "free shipping", Kind: model.PromotionFreeShipping}
+	speakers := model.CategorySpeakers
+	speakerCoupon := model.Promotion{Id: "speakers", Code: text("SPEAK"), Kind: model.PromotionCategoryDiscount, Value: 10, Category: &speakers}
+	bigCart := model.Promotion{Id: "big", Code: text("BIG"), Kind: model.PromotionPercentage, Value: 5, MinCartValue: 2000}
+
+	tests := []struct {
+		name         string
+		promotions   []model.Promotion
+		couponId     *string
+		discounts    []model.Money
+		lines        []model.Money
+		couponError  string
+		freeShipping bool
+	}{
+		{
+			name:       "automatic promotion before the coupon",
+			promotions: []model.Promotion{percentage, fixed},
+			couponId:   text("coupon"),
+			// 10% of 40000 and 60000, then 5000 over the remaining 36000 and 54000
+			discounts: []model.Money{10000, 5000},
+			lines:     []model.Money{6000, 9000},
+		},
+		{
+			name:       "coupon before the automatic promotion",
+			promotions: []model.Promotion{fixed, percentage},
+			couponId:   text("coupon"),
+			// 5000 split 2000 and 3000, then 10% of the remaining 38000 and 57000
+			discounts: []model.Money{5000, 9500},
+			lines:     []model.Money{5800, 8700},
+		},
+		{
+			name:       "expired automatic promotion is skipped silently",
+			promotions: []model.Promotion{expired, percentage},
+			discounts:  []model.Money{10000},
+			lines:      []model.Money{4000, 6000},
+		},
+		{
+			name:        "coupon below its minimum cart value",
+			promotions:  []model.Promotion{percentage, bigCart},
+			couponId:    text("big"),
+			discounts:   []model.Money{10000},
+			lines:       []model.Money{4000, 6000},
+			couponError: "cart total must be at least 2000",
+		},
+		{
+			name:        "coupon outside the cart",
+			promotions:  []model.Promotion{speakerCoupon},
+			couponId:    text("speakers"),
+			discounts:   []model.Money{},
+			lines:       []model.Money{0, 0},
+			couponError: "coupon does not apply to any item in the cart",
+		},
+		{
+			name:        "coupon that is gone",
+			promotions:  []model.Promotion{percentage},
+			couponId:    text("deleted"),
+			discounts:   []model.Money{10000},
+			lines:       []model.Money{4000, 6000},
+			couponError: "coupon is no longer available",
+		},
+		{
+			name:         "free shipping",
+			promotions:   []model.Promotion{shipping},
+			discounts:    []model.Money{0},
+			lines:        []model.Money{0, 0},
+			freeShipping: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			breakdown := model.PriceBreakdown{
+				Lines: []model.PricedLine{
+					pricedLine("p1", model.CategoryHeadphones, 20000, 2),
+					pricedLine("p2", model.CategoryHeadphones, 60000, 1),
+				},
+				Discounts: make([]model.AppliedDiscount, 0),
+				Subtotal:  100000,
+			}
+			applyPromotions(&breakdown, test.promotions, test.couponId, now)
+			if len(breakdown.Discounts) != len(test.discounts) {
+				t.Fatalf("expected %d discounts, got %+v", len(test.discounts), breakdown.Discounts)
+			}
+			for i, amount := range test.discounts {
+				if breakdown.Discounts[i].Amount != amount {
+					t.Errorf("discount %d: expected %d, got %d", i, amount, breakdown.Discounts[i].Amount)
+				}
+			}
+			for i, discount := range test.lines {
+				if breakdown.Lines[i].Discount != discount {
+					t.Errorf("line %d: expected discount %d, got %d", i, discount, breakdown.Lines[i].Discount)
+				}
+			}
+			if breakdown.CouponError != test.couponError {
+				t.Errorf("expected coupon error %q, got %q", test.couponError, breakdown.CouponError)
+			}
+			if breakdown.FreeShipping != test.freeShipping {
+				t.Errorf("expected free shipping %v, got %v", test.freeShipping, breakdown.FreeShipping)
+			}
+		})
+	}
+}
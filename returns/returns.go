@@ -0,0 +1,237 @@
+package returns
+
+import (
+	"audio_phile/database"
+	"audio_phile/database/dbHelper"
+	"audio_phile/inventory"
+	"audio_phile/model"
+	"audio_phile/order"
+	"audio_phile/payment"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+// Window is how long after delivery an order can be returned
+var Window = 30 * 24 * time.Hour
+
+var (
+	ErrOrderNotReturnable = errors.New("only delivered orders can be returned")
+	ErrWindowClosed       = errors.New("the return window of the order has closed")
+	ErrDuplicateItem      = errors.New("a product may only be listed once per return")
+)
+
+// ItemError is returned when a return asks for a product the order does not contain or for more units
+// than are left to return
+type ItemError struct {
+	ProductId  string
+	Requested  int
+	Returnable int
+}
+
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("product %s: requested %d but only %d can be returned", e.ProductId, e.Requested, e.Returnable)
+}
+
+// StatusError is returned when a return is asked to do something its status does not allow
+type StatusError struct {
+	Status model.ReturnStatus
+	Action string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("cannot %s a return that is %s", e.Action, e.Status)
+}
+
+// IsItemError reports whether err is an ItemError
+func IsItemError(err error) bool {
+	var itemErr *ItemError
+	return errors.As(err, &itemErr)
+}
+
+// IsRejected reports whether err was caused by the status of a return
+func IsRejected(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr)
+}
+
+// Request opens a return for lines of a delivered order of the user. Each product may be returned up to the
+// units ordered less those claimed by earlier returns that were not rejected, and is worth the matching share
+// of the line's total.
+func Request(orderId, userId string, req model.ReturnRequest) (string, error) {
+	var returnId string
+	txErr := database.Tx(func(tx *sqlx.Tx) error {
+		placed, err := dbHelper.GetOrder(tx, orderId, userId)
+		if err != nil {
+			return err
+		}
+		status, err := dbHelper.LockOrderStatus(tx, placed.Id)
+		if err != nil {
+			return err
+		}
+		if status != model.OrderStatusDelivered {
+			return ErrOrderNotReturnable
+		}
+		if placed.DeliveredAt != nil && time.Since(*placed.DeliveredAt) > Window {
+			return ErrWindowClosed
+		}
+		items, err := dbHelper.GetReturnableItems(tx, placed.Id)
+		if err != nil {
+			return err
+		}
+		returnable := make(map[string]model.ReturnableItem, len(items))
+		for _, item := range items {
+			returnable[item.ProductId] = item
+		}
+
+		returnId, err = dbHelper.CreateReturn(tx, placed.Id, userId, req.Comment)
+		if err != nil {
+			return err
+		}
+		seen := make(map[string]bool, len(req.Items))
+		for _, requested := range req.Items {
+			if seen[requested.ProductId] {
+				return ErrDuplicateItem
+			}
+			seen[requested.ProductId] = true
+			line, ok := returnable[requested.ProductId]
+			if left := line.Quantity - line.Returned; !ok || requested.Quantity > left {
+				return &ItemError{ProductId: requested.ProductId, Requested: requested.Quantity, Returnable: left}
+			}
+			amount := model.Money(int64(line.Total) * int64(requested.Quantity) / int64(line.Quantity))
+			if err := dbHelper.CreateReturnItem(tx, returnId, requested, amount); err != nil {
+				return err
+			}
+		}
+		return order.RecordEvent(tx, placed.Id, userId, "return "+returnId+" requested")
+	})
+	return returnId, txErr
+}
+
+// Detail returns a return with its items and refunds. With a userId only that user's return is returned.
+func Detail(db sqlx.Queryer, returnId, userId string) (model.ReturnDetail, error) {
+	rma, err := dbHelper.GetReturn(db, returnId, userId)
+	if err != nil {
+		return model.ReturnDetail{}, err
+	}
+	items, err := dbHelper.GetReturnItems(db, rma.Id)
+	if err != nil {
+		return model.ReturnDetail{}, err
+	}
+	refunds, err := dbHelper.GetReturnRefunds(db, rma.Id)
+	if err != nil {
+		return model.ReturnDetail{}, err
+	}
+	return model.ReturnDetail{Return: rma, Items: items, Refunds: refunds}, nil
+}
+
+// Approve accepts a requested return, the customer can then ship the items back
+func Approve(returnId, actorId, note string) error {
+	return resolve(returnId, model.ReturnStatusApproved, actorId, note)
+}
+
+// Reject turns down a requested return, its units can be claimed again by another return
+func Reject(returnId, actorId, note string) error {
+	return resolve(returnId, model.ReturnStatusRejected, actorId, note)
+}
+
+func resolve(returnId string, status model.ReturnStatus, actorId, note string) error {
+	return database.Tx(func(tx *sqlx.Tx) error {
+		rma, err := dbHelper.LockReturn(tx, returnId, "")
+		if err != nil {
+			return err
+		}
+		if rma.Status != model.ReturnStatusRequested {
+			return &StatusError{Status: rma.Status, Action: "resolve"}
+		}
+		if err := dbHelper.ResolveReturn(tx, rma.Id, status, actorId, note); err != nil {
+			return err
+		}
+		return order.RecordEvent(tx, rma.OrderId, actorId, withNote("return "+rma.Id+" "+string(status), note))
+	})
+}
+
+// Ship records the shipment the customer sends an approved return back with
+func Ship(returnId, userId string, tracking model.Tracking) error {
+	return database.Tx(func(tx *sqlx.Tx) error {
+		rma, err := dbHelper.LockReturn(tx, returnId, userId)
+		if err != nil {
+			return err
+		}
+		if rma.Status != model.ReturnStatusApproved && rma.Status != model.ReturnStatusInTransit {
+			return &StatusError{Status: rma.Status, Action: "ship"}
+		}
+		if err := dbHelper.UpdateReturnShipment(tx, rma.Id, tracking); err != nil {
+			return err
+		}
+		return order.RecordEvent(tx, rma.OrderId, userId, "return "+rma.Id+" shipped with "+tracking.Carrier+" "+tracking.TrackingNumber)
+	})
+}
+
+// Receive records that the returned items arrived. When they can be sold again they are restocked
+// through the inventory, into the warehouses they were sold from.
+func Receive(returnId, actorId string, req model.ReturnReceiptRequest) error {
+	return database.Tx(func(tx *sqlx.Tx) error {
+		rma, err := dbHelper.LockReturn(tx, returnId, "")
+		if err != nil {
+			return err
+		}
+		if rma.Status != model.ReturnStatusApproved && rma.Status != model.ReturnStatusInTransit {
+			return &StatusError{Status: rma.Status, Action: "receive"}
+		}
+		if req.Restock {
+			items, err := dbHelper.GetReturnItems(tx, rma.Id)
+			if err != nil {
+				return err
+			}
+			for _, item := range items {
+				if _, err := inventory.RestockOrderItem(tx, rma.OrderId, item.ProductId, item.Quantity, actorId, "return "+rma.Id); err != nil {
+					return err
+				}
+			}
+		}
+		if err := dbHelper.MarkReturnReceived(tx, rma.Id, req.Restock); err != nil {
+			return err
+		}
+		return order.RecordEvent(tx, rma.OrderId, actorId, withNote("return "+rma.Id+" received", req.Note))
+	})
+}
+
+// Refund gives the money for a received return back the way it was paid, cash on delivery as store credit, all the returned
+// items are worth when req.Amount is zero or a part of it for a partial refund. The return moves to refunded
+// once the refunds covering the amount went through; when some of them failed it stays received and asking
+// again refunds only what is missing.
+func Refund(returnId, actorId string, req model.RefundRequest) ([]model.Refund, error) {
+	detail, err := Detail(database.Audiophile, returnId, "")
+	if err != nil {
+		return nil, err
+	}
+	if detail.Status != model.ReturnStatusReceived {
+		return nil, &StatusError{Status: detail.Status, Action: "refund"}
+	}
+	var worth model.Money
+	for _, item := range detail.Items {
+		worth += item.Amount
+	}
+	amount := req.Amount
+	if amount == 0 {
+		amount = worth
+	}
+	if amount > worth {
+		return nil, &payment.RefundExceedsError{Requested: amount, Refundable: worth}
+	}
+	if amount == 0 {
+		return nil, payment.ErrNothingToRefund
+	}
+
+	reason := withNote("return "+detail.Id, req.Reason)
+	return payment.Refund(detail.OrderId, amount, detail.Id, actorId, reason)
+}
+
+func withNote(message, note string) string {
+	if note == "" {
+		return message
+	}
+	return message + ": " + note
+}
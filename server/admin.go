@@ -10,7 +10,88 @@ func AdminRoute(r chi.Router) {
 		admin.Route("/product", func(product chi.Router) {
 			product.Post("/", handler.CreateProduct)
 			product.Get("/", handler.GetAllProduct)
+			product.Post("/import", handler.ImportProducts)
+			product.Get("/export", handler.ExportProducts)
 			product.Get("/{id}", handler.GetProductById)
+			product.Get("/{id}/review", handler.GetProductReviews)
+			product.Get("/{id}/stock", handler.GetProductStock)
+			product.Put("/{id}/threshold", handler.SetReorderThreshold)
+			product.Put("/{id}/weight", handler.SetProductWeight)
+		})
+		admin.Route("/warehouse", func(warehouse chi.Router) {
+			warehouse.Post("/", handler.CreateWarehouse)
+			warehouse.Get("/", handler.GetWarehouses)
+			warehouse.Get("/{id}/stock", handler.GetWarehouseStock)
+		})
+		admin.Route("/inventory", func(stock chi.Router) {
+			stock.Post("/movement", handler.RecordStockMovement)
+			stock.Get("/movement", handler.GetStockMovements)
+			stock.Get("/alerts", handler.GetStockAlerts)
+		})
+		admin.Route("/tax-rate", func(taxRate chi.Router) {
+			taxRate.Post("/", handler.CreateTaxRate)
+			taxRate.Get("/", handler.GetTaxRates)
+			taxRate.Delete("/{id}", handler.DeleteTaxRate)
+		})
+		admin.Route("/shipping-rate", func(shippingRate chi.Router) {
+			shippingRate.Post("/", handler.CreateShippingRate)
+			shippingRate.Get("/", handler.GetShippingRates)
+			shippingRate.Delete("/{id}", handler.DeleteShippingRate)
+		})
+		admin.Route("/delivery-zone", func(zone chi.Router) {
+			zone.Post("/", handler.CreateDeliveryZone)
+			zone.Get("/", handler.GetDeliveryZones)
+			zone.Get("/{id}", handler.GetDeliveryZone)
+			zone.Delete("/{id}", handler.DeleteDeliveryZone)
+		})
+		admin.Route("/promotion", func(promotion chi.Router) {
+			promotion.Post("/", handler.CreatePromotion)
+			promotion.Get("/", handler.GetPromotions)
+			promotion.Get("/{id}", handler.GetPromotionById)
+			promotion.Put("/{id}", handler.UpdatePromotion)
+			promotion.Delete("/{id}", handler.DeletePromotion)
+		})
+		admin.Route("/order", func(order chi.Router) {
+			order.Get("/", handler.GetOrders)
+			order.Get("/export", handler.ExportOrders)
+			order.Post("/status", handler.BulkUpdateOrderStatus)
+			order.Get("/{id}", handler.GetOrderById)
+			order.Post("/{id}/status", handler.UpdateOrderStatus)
+			order.Post("/{id}/notes", handler.AddOrderNote)
+			order.Get("/{id}/history", handler.GetOrderStatusHistory)
+			order.Post("/{id}/refund", handler.RefundOrder)
+			order.Get("/{id}/invoice", handler.GetInvoice)
+		})
+		admin.Route("/returns", func(rma chi.Router) {
+			rma.Get("/", handler.GetReturns)
+			rma.Get("/{id}", handler.GetReturnById)
+			rma.Post("/{id}/approve", handler.ApproveReturn)
+			rma.Post("/{id}/reject", handler.RejectReturn)
+			rma.Post("/{id}/receive", handler.ReceiveReturn)
+			rma.Post("/{id}/refund", handler.RefundReturn)
+		})
+		admin.Route("/credit", func(credit chi.Router) {
+			credit.Get("/{userId}", handler.GetUserCredit)
+			credit.Post("/{userId}", handler.AdjustUserCredit)
+		})
+		admin.Route("/gift-card", func(giftCard chi.Router) {
+			giftCard.Post("/", handler.IssueGiftCard)
+			giftCard.Get("/", handler.GetGiftCards)
+			giftCard.Get("/{id}", handler.GetGiftCard)
+			giftCard.Post("/{id}/adjust", handler.AdjustGiftCard)
+			giftCard.Delete("/{id}", handler.DisableGiftCard)
+		})
+		admin.Route("/payment", func(payment chi.Router) {
+			payment.Get("/webhooks", handler.GetWebhookEvents)
+			payment.Post("/webhooks/{id}/reprocess", handler.ReprocessWebhookEvent)
+			payment.Get("/cod", handler.GetCodPayments)
+			payment.Post("/cod/reconcile", handler.ReconcileCodPayments)
+		})
+		admin.Get("/jobs", handler.GetJobRuns)
+		admin.Route("/review", func(review chi.Router) {
+			review.Get("/", handler.GetReviewsForModeration)
+			review.Post("/{id}/approve", handler.ApproveReview)
+			review.Post("/{id}/hide", handler.HideReview)
 		})
 		admin.Route("/user", func(user chi.Router) {
 			user.Get("/", handler.GetAllUser)
@@ -24,6 +24,19 @@ func SetupRoutes() *Server {
 	routes.Route("/api", func(api chi.Router) {
 		api.Post("/register", handler.CreateUser)
 		api.Post("/login", handler.Login)
+		api.Route("/cart", func(guestCart chi.Router) {
+			guestCart.Post("/", handler.CreateGuestCart)
+			guestCart.Group(func(withToken chi.Router) {
+				withToken.Use(middleware.GuestCartMiddleware)
+				withToken.Get("/", handler.GetGuestCart)
+				withToken.Put("/items/{productId}", handler.SetGuestCartItem)
+				withToken.Delete("/items/{productId}", handler.RemoveGuestCartItem)
+			})
+		})
+		api.Get("/wishlist/shared/{token}", handler.GetSharedWishlist)
+		api.Post("/payment/webhook", handler.PaymentWebhook)
+		api.Post("/shipping/quote", handler.QuoteShipping)
+		api.Post("/shipping/serviceable", handler.CheckServiceability)
 		api.Route("/admin", func(admin chi.Router) {
 			admin.Use(middleware.AuthMiddleware)
 			admin.Use(middleware.AdminMiddleware)
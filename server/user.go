@@ -2,33 +2,66 @@ package server
 
 import (
 	"audio_phile/database/handler"
+	"audio_phile/middleware"
 	"github.com/go-chi/chi/v5"
+	"net/http"
 )
 
+// createOrder places the order of the cart in the URL, tests swap it for a handler that needs no database
+var createOrder http.HandlerFunc = handler.CreateOrder
+
 func UserRoute(r chi.Router) {
 	r.Group(func(user chi.Router) {
 		user.Route("/product", func(product chi.Router) {
 			product.Get("/", handler.GetAllProduct)
+			product.Get("/subscriptions", handler.GetRestockSubscriptions)
 			product.Get("/{id}", handler.GetProductById)
+			product.Get("/{id}/review", handler.GetProductReviews)
+			product.Post("/{id}/review", handler.CreateReview)
+			product.Post("/{id}/subscribe", handler.SubscribeRestock)
+			product.Delete("/{id}/subscribe", handler.UnsubscribeRestock)
 		})
 		user.Route("/address", func(address chi.Router) {
 			address.Post("/", handler.CreatedAddress)
 		})
 		user.Route("/cart", func(cartProduct chi.Router) {
-			cartProduct.Post("/{id}/{quantity}", handler.CreateProductToCart)
 			cartProduct.Get("/", handler.GetCartWithProductById)
-			cartProduct.Route("/add", func(addQuantity chi.Router) {
-				addQuantity.Post("/{cartId}/{productId}", handler.AddProductQuantityInCart)
-			})
-			cartProduct.Route("/remove", func(removeQuantity chi.Router) {
-				removeQuantity.Post("/{cartId}/{productId}", handler.RemoveProductQuantityInCart)
-			})
-			cartProduct.Route("/delete", func(deleteProduct chi.Router) {
-				deleteProduct.Delete("/{cartId}/{productId}", handler.DeleteProductFromCart)
+			cartProduct.Post("/checkout", handler.CheckoutCart)
+			cartProduct.Put("/coupon", handler.ApplyCoupon)
+			cartProduct.Delete("/coupon", handler.RemoveCoupon)
+			cartProduct.Route("/items", func(item chi.Router) {
+				item.Put("/{productId}", handler.SetCartItem)
+				item.Delete("/{productId}", handler.RemoveCartItem)
+				item.Post("/{productId}/save-for-later", handler.SaveCartItemForLater)
 			})
 		})
+		user.Route("/wishlist", func(list chi.Router) {
+			list.Post("/", handler.CreateWishlist)
+			list.Get("/", handler.GetWishlists)
+			list.Get("/{id}", handler.GetWishlist)
+			list.Delete("/{id}", handler.DeleteWishlist)
+			list.Post("/{id}/share", handler.ShareWishlist)
+			list.Delete("/{id}/share", handler.UnshareWishlist)
+			list.Put("/{id}/items/{productId}", handler.SetWishlistItem)
+			list.Delete("/{id}/items/{productId}", handler.RemoveWishlistItem)
+			list.Post("/{id}/items/{productId}/move-to-cart", handler.MoveWishlistItemToCart)
+		})
+		user.Route("/credit", func(credit chi.Router) {
+			credit.Get("/", handler.GetMyCredit)
+			credit.Post("/redeem", handler.RedeemGiftCard)
+		})
+		user.Route("/returns", func(rma chi.Router) {
+			rma.Get("/", handler.GetMyReturns)
+			rma.Get("/{id}", handler.GetMyReturn)
+			rma.Put("/{id}/shipment", handler.ShipReturn)
+		})
 		user.Route("/order", func(order chi.Router) {
-			order.Post("/{cartId}", handler.CreateOrder)
+			order.Get("/", handler.GetMyOrders)
+			order.Get("/{id}", handler.GetMyOrder)
+			order.Post("/{id}/pay", handler.PayOrder)
+			order.Get("/{id}/invoice", handler.GetMyInvoice)
+			order.Post("/{id}/returns", handler.RequestReturn)
+			order.With(middleware.ActiveCartMiddleware).Post("/{cartId}", createOrder)
 		})
 	})
 }
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"audio_phile/middleware"
+	"audio_phile/model"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const (
+	aliceId     = "7a1c1f3e-0000-4000-8000-000000000001"
+	bobId       = "7a1c1f3e-0000-4000-8000-000000000002"
+	carolId     = "7a1c1f3e-0000-4000-8000-000000000003"
+	aliceCartId = "c0ffee00-0000-4000-8000-00000000000a"
+	bobCartId   = "c0ffee00-0000-4000-8000-00000000000b"
+)
+
+// stubRoutes sets up the server routes with active carts for alice and bob and none for carol, and an
+// order handler that only echoes the cart the middleware let through
+func stubRoutes(t *testing.T) *Server {
+	t.Helper()
+	activeCarts := map[string]string{aliceId: aliceCartId, bobId: bobCartId}
+	originalLookup, originalHandler := middleware.ActiveCartId, createOrder
+	middleware.ActiveCartId = func(userId string) (string, bool, error) {
+		cartId, exist := activeCarts[userId]
+		return cartId, exist, nil
+	}
+	createOrder = func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Cart-Id", middleware.CartIdFromContext(r))
+		w.WriteHeader(http.StatusCreated)
+	}
+	t.Cleanup(func() {
+		middleware.ActiveCartId, createOrder = originalLookup, originalHandler
+	})
+	return SetupRoutes()
+}
+
+func serve(t *testing.T, srv *Server, method, path, userId string) *httptest.ResponseRecorder {
+	t.Helper()
+	token, err := middleware.GenerateJWT(userId, model.RoleUser)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	request := httptest.NewRequest(method, path, nil)
+	request.Header.Set("Authorization", token)
+	recorder := httptest.NewRecorder()
+	srv.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestCreateOrderRoute(t *testing.T) {
+	srv := stubRoutes(t)
+	tests := []struct {
+		name   string
+		path   string
+		userId string
+		status int
+	}{
+		{"owner orders the cart", "/api/user/order/" + aliceCartId, aliceId, http.StatusCreated},
+		{"other user's cart", "/api/user/order/" + bobCartId, aliceId, http.StatusNotFound},
+		{"other user orders the cart", "/api/user/order/" + aliceCartId, bobId, http.StatusNotFound},
+		{"user without a cart orders another's", "/api/user/order/" + aliceCartId, carolId, http.StatusNotFound},
+		{"invalid cart id", "/api/user/order/not-a-uuid", aliceId, http.StatusNotFound},
+		{"cart id differing in case", "/api/user/order/C0FFEE00-0000-4000-8000-00000000000A", aliceId, http.StatusNotFound},
+		{"missing cart id", "/api/user/order/", aliceId, http.StatusMethodNotAllowed},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := serve(t, srv, http.MethodPost, test.path, test.userId)
+			if recorder.Code != test.status {
+				t.Fatalf("expected status %d, got %d", test.status, recorder.Code)
+			}
+			if test.status == http.StatusCreated && recorder.Header().Get("X-Cart-Id") != aliceCartId {
+				t.Fatalf("expected the handler to see cart %s, got %q", aliceCartId, recorder.Header().Get("X-Cart-Id"))
+			}
+		})
+	}
+}
+
+func TestCreateOrderRouteLookupFailure(t *testing.T) {
+	srv := stubRoutes(t)
+	middleware.ActiveCartId = func(string) (string, bool, error) {
+		return "", false, errors.New("connection refused")
+	}
+	recorder := serve(t, srv, http.MethodPost, "/api/user/order/"+aliceCartId, aliceId)
+	if recorder.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status %d, got %d", http.StatusInternalServerError, recorder.Code)
+	}
+}
@@ -0,0 +1,136 @@
+package shipping
+
+import (
+	"audio_phile/database/dbHelper"
+	"audio_phile/model"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"math"
+	"os"
+	"strconv"
+)
+
+var (
+	ErrNoOrigin     = errors.New("no warehouse to ship from")
+	ErrNotShippable = errors.New("no shipping rate covers this delivery")
+)
+
+// FreeAbove waives shipping for orders worth at least this much after discounts, zero never waives it
+var FreeAbove model.Money
+
+// FromEnv reads the free shipping threshold, in whole currency units, from SHIPPING_FREE_ABOVE
+func FromEnv() error {
+	if value := os.Getenv("SHIPPING_FREE_ABOVE"); value != "" {
+		amount, err := strconv.Atoi(value)
+		if err != nil || amount < 0 {
+			return fmt.Errorf("invalid SHIPPING_FREE_ABOVE %q", value)
+		}
+		FreeAbove = model.FromPrice(amount)
+	}
+	return nil
+}
+
+// Point is a location on earth in degrees
+type Point struct {
+	Lat  float64
+	Long float64
+}
+
+// AddressPoint is where an address is, or nil for the empty address of a user without any
+func AddressPoint(address model.AddressModel) *Point {
+	if address.Id == "" {
+		return nil
+	}
+	return &Point{Lat: address.Lat, Long: address.Long}
+}
+
+// Quote prices shipping a parcel of weightGrams worth orderValue to destination. It ships from the nearest
+// warehouse and charges the most specific rate whose distance and weight bands cover the delivery. Without
+// any rate configured shipping is free.
+func Quote(db sqlx.Queryer, destination Point, weightGrams int, orderValue model.Money) (model.ShippingQuote, error) {
+	quote := model.ShippingQuote{WeightGrams: weightGrams}
+	rates, err := dbHelper.GetShippingRates(db)
+	if err != nil || len(rates) == 0 {
+		return quote, err
+	}
+	origins, err := dbHelper.GetShippingOrigins(db)
+	if err != nil {
+		return quote, err
+	}
+	if len(origins) == 0 {
+		return quote, ErrNoOrigin
+	}
+	distance := math.MaxFloat64
+	for _, origin := range origins {
+		if d := DistanceKm(Point{Lat: origin.Lat, Long: origin.Long}, destination); d < distance {
+			quote.WarehouseId, quote.Warehouse, distance = origin.Id, origin.Name, d
+		}
+	}
+	// reported to the tenth of a km, rates are matched on the exact distance
+	quote.DistanceKm = math.Round(distance*10) / 10
+
+	rate, found := rateFor(rates, distance, weightGrams)
+	if !found {
+		return quote, ErrNotShippable
+	}
+	quote.RateId, quote.Rate = rate.Id, rate.Name
+	quote.Fee = fee(rate, weightGrams)
+	waiveAbove(&quote, orderValue)
+	return quote, nil
+}
+
+// waiveAbove makes a quote free when the order is worth at least FreeAbove
+func waiveAbove(quote *model.ShippingQuote, orderValue model.Money) {
+	if FreeAbove > 0 && orderValue >= FreeAbove {
+		Waive(quote, fmt.Sprintf("orders of %s or more ship free", FreeAbove))
+	}
+}
+
+// Waive makes a quote free for reason, unless it already is
+func Waive(quote *model.ShippingQuote, reason string) {
+	if quote.Free {
+		return
+	}
+	quote.Free = true
+	quote.FreeReason = reason
+}
+
+// Charge is what the customer pays for a quote
+func Charge(quote model.ShippingQuote) model.Money {
+	if quote.Free {
+		return 0
+	}
+	return quote.Fee
+}
+
+// rateFor picks the first rate covering the delivery, rates come most specific first
+func rateFor(rates []model.ShippingRate, distanceKm float64, weightGrams int) (model.ShippingRate, bool) {
+	for _, rate := range rates {
+		if distanceKm < rate.MinDistanceKm || (rate.MaxDistanceKm != nil && distanceKm >= *rate.MaxDistanceKm) {
+			continue
+		}
+		if weightGrams < rate.MinWeightGrams || (rate.MaxWeightGrams != nil && weightGrams >= *rate.MaxWeightGrams) {
+			continue
+		}
+		return rate, true
+	}
+	return model.ShippingRate{}, false
+}
+
+// fee is the base fee plus the per kg fee for every started kg
+func fee(rate model.ShippingRate, weightGrams int) model.Money {
+	kg := (weightGrams + 999) / 1000
+	return rate.BaseFee + rate.PerKgFee*model.Money(kg)
+}
+
+const earthRadiusKm = 6371.0
+
+// DistanceKm is the great circle distance between two points
+func DistanceKm(from, to Point) float64 {
+	lat1, lat2 := from.Lat*math.Pi/180, to.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLong := (to.Long - from.Long) * math.Pi / 180
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLong/2)*math.Sin(dLong/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
@@ -0,0 +1,146 @@
+package shipping
+
+import (
+	"audio_phile/model"
+	"math"
+	"testing"
+)
+
+func TestDistanceKm(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to Point
+		km       float64
+	}{
+		{"same point", Point{Lat: 12.9716, Long: 77.5946}, Point{Lat: 12.9716, Long: 77.5946}, 0},
+		{"one degree of latitude", Point{}, Point{Lat: 1}, 111.195},
+		{"one degree of longitude on the equator", Point{}, Point{Long: 1}, 111.195},
+		{"across the antimeridian", Point{Long: 179.5}, Point{Long: -179.5}, 111.195},
+		{"antipodes", Point{}, Point{Long: 180}, 20015.087},
+		{"pole to pole", Point{Lat: 90}, Point{Lat: -90}, 20015.087},
+		{"london to paris", Point{Lat: 51.5074, Long: -0.1278}, Point{Lat: 48.8566, Long: 2.3522}, 343.556},
+		{"bengaluru to chennai", Point{Lat: 12.9716, Long: 77.5946}, Point{Lat: 13.0827, Long: 80.2707}, 290.172},
+		{"delhi to mumbai", Point{Lat: 28.6139, Long: 77.2090}, Point{Lat: 19.0760, Long: 72.8777}, 1148.095},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			km := DistanceKm(test.from, test.to)
+			if math.Abs(km-test.km) > 0.001 {
+				t.Fatalf("expected %.3f km, got %.3f", test.km, km)
+			}
+			if back := DistanceKm(test.to, test.from); math.Abs(back-km) > 1e-9 {
+				t.Fatalf("distance is not symmetric: %f and %f", km, back)
+			}
+		})
+	}
+}
+
+func TestRateFor(t *testing.T) {
+	ten, fifty := 10.0, 50.0
+	oneKg, fiveKg := 1000, 5000
+	rates := []model.ShippingRate{
+		// most specific first, the way GetShippingRates orders them
+		{Id: "local-light", MaxDistanceKm: &ten, MaxWeightGrams: &oneKg},
+		{Id: "local", MaxDistanceKm: &ten},
+		{Id: "regional-medium", MinDistanceKm: 10, MaxDistanceKm: &fifty, MinWeightGrams: 1000, MaxWeightGrams: &fiveKg},
+		{Id: "regional", MinDistanceKm: 10, MaxDistanceKm: &fifty},
+		{Id: "national", MinDistanceKm: 50},
+	}
+	tests := []struct {
+		name        string
+		rates       []model.ShippingRate
+		distanceKm  float64
+		weightGrams int
+		rateId      string
+	}{
+		{"nothing", rates, 0, 0, "local-light"},
+		{"just below the weight band end", rates, 5, 999, "local-light"},
+		{"weight band end is exclusive", rates, 5, 1000, "local"},
+		{"just below the distance band end", rates, 9.999, 500, "local-light"},
+		{"distance band end is exclusive", rates, 10, 500, "regional"},
+		{"weight band start is inclusive", rates, 20, 1000, "regional-medium"},
+		{"just below the weight band start", rates, 20, 999, "regional"},
+		{"heavier than the band", rates, 20, 5000, "regional"},
+		{"open ended distance", rates, 2500, 40000, "national"},
+		{"no rate covers it", rates[:2], 20, 500, ""},
+		{"no rates", nil, 5, 500, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rate, found := rateFor(test.rates, test.distanceKm, test.weightGrams)
+			if found != (test.rateId != "") || rate.Id != test.rateId {
+				t.Fatalf("expected rate %q, got %q (found %v)", test.rateId, rate.Id, found)
+			}
+		})
+	}
+}
+
+func TestFee(t *testing.T) {
+	rate := model.ShippingRate{BaseFee: 4000, PerKgFee: 1500}
+	tests := []struct {
+		name        string
+		weightGrams int
+		fee         model.Money
+	}{
+		{"weightless", 0, 4000},
+		{"one gram starts a kg", 1, 5500},
+		{"exactly one kg", 1000, 5500},
+		{"just over one kg", 1001, 7000},
+		{"two and a half kg", 2500, 8500},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := fee(rate, test.weightGrams); got != test.fee {
+				t.Fatalf("expected %s, got %s", test.fee, got)
+			}
+		})
+	}
+	if got := fee(model.ShippingRate{BaseFee: 4000}, 7300); got != 4000 {
+		t.Fatalf("expected a flat fee of 40.00, got %s", got)
+	}
+}
+
+func TestWaiveAbove(t *testing.T) {
+	defer func(threshold model.Money) { FreeAbove = threshold }(FreeAbove)
+	tests := []struct {
+		name       string
+		threshold  model.Money
+		orderValue model.Money
+		free       bool
+	}{
+		{"below the threshold", 99900, 99899, false},
+		{"at the threshold", 99900, 99900, true},
+		{"above the threshold", 99900, 150000, true},
+		{"no threshold", 0, 150000, false},
+		{"no threshold and nothing ordered", 0, 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			FreeAbove = test.threshold
+			quote := model.ShippingQuote{Fee: 5500}
+			waiveAbove(&quote, test.orderValue)
+			if quote.Free != test.free {
+				t.Fatalf("expected free %v, got %v", test.free, quote.Free)
+			}
+			if test.free && quote.FreeReason != "orders of 999.00 or more ship free" {
+				t.Fatalf("unexpected reason %q", quote.FreeReason)
+			}
+			charge := model.Money(5500)
+			if test.free {
+				charge = 0
+			}
+			if Charge(quote) != charge {
+				t.Fatalf("expected a charge of %s, got %s", charge, Charge(quote))
+			}
+		})
+	}
+}
+
+func TestWaiveKeepsFirstReason(t *testing.T) {
+	quote := model.ShippingQuote{Fee: 5500}
+	Waive(&quote, "free shipping coupon")
+	Waive(&quote, "orders of 999.00 or more ship free")
+	if !quote.Free || quote.FreeReason != "free shipping coupon" {
+		t.Fatalf("expected the coupon to stay the reason, got %+v", quote)
+	}
+}
@@ -0,0 +1,167 @@
+package shipping
+
+import (
+	"audio_phile/database/dbHelper"
+	"audio_phile/model"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"math"
+)
+
+var ErrNotServiceable = errors.New("we do not deliver to this address")
+
+// the lookups Serviceable makes, variables so that the matching can be tested without a database
+var (
+	hasZones = func(db sqlx.Queryer) (bool, error) {
+		return dbHelper.HasDeliveryZones(db)
+	}
+	postalCodeZone = func(db sqlx.Queryer, postalCode string) (model.DeliveryZone, error) {
+		return dbHelper.FindPostalCodeZone(db, postalCode)
+	}
+	liveZones = func(db sqlx.Queryer) ([]model.DeliveryZone, error) {
+		return Zones(db, "")
+	}
+)
+
+// boundaryEpsilon is how far off an edge, in degrees, a point may be and still be on it
+const boundaryEpsilon = 1e-9
+
+// CreateZone stores a delivery zone with its polygon and postal codes
+func CreateZone(tx *sqlx.Tx, zone model.DeliveryZoneRequest) (string, error) {
+	zoneId, err := dbHelper.CreateDeliveryZone(tx, zone.Name)
+	if err != nil {
+		return "", err
+	}
+	for i, point := range zone.Polygon {
+		if err := dbHelper.CreateDeliveryZonePoint(tx, zoneId, i, point); err != nil {
+			return "", err
+		}
+	}
+	for _, postalCode := range zone.PostalCodes {
+		if err := dbHelper.CreateDeliveryZonePostalCode(tx, zoneId, postalCode); err != nil {
+			return "", err
+		}
+	}
+	return zoneId, nil
+}
+
+// Zones returns the live delivery zones, or just the one with zoneId when it is set
+func Zones(db sqlx.Queryer, zoneId string) ([]model.DeliveryZone, error) {
+	var zones []model.DeliveryZone
+	if zoneId == "" {
+		list, err := dbHelper.GetDeliveryZones(db)
+		if err != nil {
+			return nil, err
+		}
+		zones = list
+	} else {
+		zone, err := dbHelper.GetDeliveryZone(db, zoneId)
+		if err != nil {
+			return nil, err
+		}
+		zones = []model.DeliveryZone{zone}
+	}
+	points, err := dbHelper.GetDeliveryZonePoints(db, zoneId)
+	if err != nil {
+		return nil, err
+	}
+	postalCodes, err := dbHelper.GetDeliveryZonePostalCodes(db, zoneId)
+	if err != nil {
+		return nil, err
+	}
+	byId := make(map[string]*model.DeliveryZone, len(zones))
+	for i := range zones {
+		zones[i].Polygon = make([]model.GeoPoint, 0)
+		zones[i].PostalCodes = make([]string, 0)
+		byId[zones[i].Id] = &zones[i]
+	}
+	for _, point := range points {
+		if zone, ok := byId[point.ZoneId]; ok {
+			zone.Polygon = append(zone.Polygon, point.GeoPoint)
+		}
+	}
+	for _, postalCode := range postalCodes {
+		if zone, ok := byId[postalCode.ZoneId]; ok {
+			zone.PostalCodes = append(zone.PostalCodes, postalCode.PostalCode)
+		}
+	}
+	return zones, nil
+}
+
+// Serviceable tells whether we deliver to a location, a postal code or either; a nil location or an empty
+// postal code is not checked. The postal code is matched first, then the zone polygons containing the
+// location. As long as no zone is set up every address is served.
+func Serviceable(db sqlx.Queryer, location *Point, postalCode string) (model.Serviceability, error) {
+	exist, err := hasZones(db)
+	if err != nil {
+		return model.Serviceability{}, err
+	}
+	if !exist {
+		return model.Serviceability{Serviceable: true}, nil
+	}
+	if postalCode != "" {
+		zone, err := postalCodeZone(db, postalCode)
+		if err == nil {
+			return model.Serviceability{Serviceable: true, ZoneId: zone.Id, Zone: zone.Name}, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return model.Serviceability{}, err
+		}
+	}
+	if location != nil {
+		zones, err := liveZones(db)
+		if err != nil {
+			return model.Serviceability{}, err
+		}
+		for _, zone := range zones {
+			if contains(zone.Polygon, *location) {
+				return model.Serviceability{Serviceable: true, ZoneId: zone.Id, Zone: zone.Name}, nil
+			}
+		}
+	}
+	return model.Serviceability{}, nil
+}
+
+// CheckAddress fails with ErrNotServiceable when we do not deliver to an address
+func CheckAddress(db sqlx.Queryer, address model.AddressModel) error {
+	result, err := Serviceable(db, &Point{Lat: address.Lat, Long: address.Long}, address.PostalCode)
+	if err != nil {
+		return err
+	}
+	if !result.Serviceable {
+		return ErrNotServiceable
+	}
+	return nil
+}
+
+// contains casts a ray from the point towards increasing longitude and counts the polygon edges it crosses,
+// an odd count is inside. A point on an edge or a vertex is inside, so an address on the border of a zone is
+// served. Zones are small enough for latitude and longitude to be treated as flat.
+func contains(polygon []model.GeoPoint, point Point) bool {
+	if len(polygon) < 3 {
+		return false
+	}
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if onEdge(a, b, point) {
+			return true
+		}
+		if (a.Lat > point.Lat) != (b.Lat > point.Lat) &&
+			point.Long < (b.Long-a.Long)*(point.Lat-a.Lat)/(b.Lat-a.Lat)+a.Long {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// onEdge tells whether the point lies on the segment from a to b
+func onEdge(a, b model.GeoPoint, point Point) bool {
+	cross := (b.Long-a.Long)*(point.Lat-a.Lat) - (b.Lat-a.Lat)*(point.Long-a.Long)
+	if math.Abs(cross) > boundaryEpsilon {
+		return false
+	}
+	return point.Lat >= math.Min(a.Lat, b.Lat)-boundaryEpsilon && point.Lat <= math.Max(a.Lat, b.Lat)+boundaryEpsilon &&
+		point.Long >= math.Min(a.Long, b.Long)-boundaryEpsilon && point.Long <= math.Max(a.Long, b.Long)+boundaryEpsilon
+}
@@ -0,0 +1,143 @@
+package shipping
+
+import (
+	"audio_phile/model"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"testing"
+)
+
+var (
+	square = []model.GeoPoint{{Lat: 0, Long: 0}, {Lat: 0, Long: 10}, {Lat: 10, Long: 10}, {Lat: 10, Long: 0}}
+	// u is concave: a base from lat 0 to 3 with two arms up to lat 10, the notch between them is outside
+	u = []model.GeoPoint{
+		{Lat: 0, Long: 0}, {Lat: 0, Long: 10}, {Lat: 10, Long: 10}, {Lat: 10, Long: 7},
+		{Lat: 3, Long: 7}, {Lat: 3, Long: 3}, {Lat: 10, Long: 3}, {Lat: 10, Long: 0},
+	}
+)
+
+func TestContains(t *testing.T) {
+	tests := []struct {
+		name    string
+		polygon []model.GeoPoint
+		point   Point
+		inside  bool
+	}{
+		{"inside", square, Point{Lat: 5, Long: 5}, true},
+		{"outside", square, Point{Lat: 5, Long: 15}, false},
+		{"outside towards the ray", square, Point{Lat: 5, Long: -5}, false},
+		{"outside level with a vertex", square, Point{Lat: 10, Long: -5}, false},
+		{"on an edge", square, Point{Lat: 0, Long: 5}, true},
+		{"on a vertical edge", square, Point{Lat: 5, Long: 10}, true},
+		{"on a vertex", square, Point{Lat: 10, Long: 10}, true},
+		{"on the first vertex", square, Point{Lat: 0, Long: 0}, true},
+		{"just outside an edge", square, Point{Lat: 5, Long: 10.0001}, false},
+		{"concave base", u, Point{Lat: 1, Long: 5}, true},
+		{"concave arm", u, Point{Lat: 6, Long: 1.5}, true},
+		{"concave far arm", u, Point{Lat: 6, Long: 8.5}, true},
+		{"concave notch", u, Point{Lat: 6, Long: 5}, false},
+		{"concave notch floor", u, Point{Lat: 3, Long: 5}, true},
+		{"concave ray along the notch floor", u, Point{Lat: 3, Long: 1}, true},
+		{"concave ray through the notch", u, Point{Lat: 8, Long: 1}, true},
+		{"not a polygon", square[:2], Point{Lat: 0, Long: 5}, false},
+		{"no polygon", nil, Point{Lat: 5, Long: 5}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := contains(test.polygon, test.point); got != test.inside {
+				t.Fatalf("expected inside %v, got %v", test.inside, got)
+			}
+		})
+	}
+}
+
+// zoneLookups replaces the database lookups of Serviceable for one test
+func zoneLookups(t *testing.T, zones []model.DeliveryZone, postalCodes map[string]string, err error) {
+	t.Helper()
+	originalHas, originalPostal, originalLive := hasZones, postalCodeZone, liveZones
+	t.Cleanup(func() { hasZones, postalCodeZone, liveZones = originalHas, originalPostal, originalLive })
+	hasZones = func(sqlx.Queryer) (bool, error) {
+		return len(zones) > 0, err
+	}
+	postalCodeZone = func(_ sqlx.Queryer, postalCode string) (model.DeliveryZone, error) {
+		for _, zone := range zones {
+			if postalCodes[postalCode] == zone.Id {
+				return zone, nil
+			}
+		}
+		return model.DeliveryZone{}, sql.ErrNoRows
+	}
+	liveZones = func(sqlx.Queryer) ([]model.DeliveryZone, error) {
+		return zones, nil
+	}
+}
+
+func TestServiceable(t *testing.T) {
+	zones := []model.DeliveryZone{
+		{Id: "z1", Name: "Square", Polygon: square},
+		{Id: "z2", Name: "Postal only", Polygon: []model.GeoPoint{}},
+	}
+	postalCodes := map[string]string{"560001": "z1", "600001": "z2"}
+	inside, outside, border := &Point{Lat: 5, Long: 5}, &Point{Lat: 50, Long: 50}, &Point{Lat: 10, Long: 3}
+
+	tests := []struct {
+		name       string
+		zones      []model.DeliveryZone
+		location   *Point
+		postalCode string
+		zoneId     string
+	}{
+		{"no zones serve everything", nil, outside, "999999", ""},
+		{"location inside", zones, inside, "", "z1"},
+		{"location outside", zones, outside, "", "-"},
+		{"location on the border", zones, border, "", "z1"},
+		{"postal code only", zones, nil, "600001", "z2"},
+		{"postal code beats the location", zones, inside, "600001", "z2"},
+		{"postal code of an outside location", zones, outside, "560001", "z1"},
+		{"unknown postal code falls back to the location", zones, inside, "999999", "z1"},
+		{"unknown postal code outside", zones, outside, "999999", "-"},
+		{"unknown postal code only", zones, nil, "999999", "-"},
+		{"nothing to check", zones, nil, "", "-"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			zoneLookups(t, test.zones, postalCodes, nil)
+			result, err := Serviceable(nil, test.location, test.postalCode)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if serviceable := test.zoneId != "-"; result.Serviceable != serviceable {
+				t.Fatalf("expected serviceable %v, got %+v", serviceable, result)
+			}
+			if test.zoneId != "-" && result.ZoneId != test.zoneId {
+				t.Fatalf("expected zone %q, got %q", test.zoneId, result.ZoneId)
+			}
+		})
+	}
+}
+
+func TestServiceableLookupFails(t *testing.T) {
+	failure := errors.New("connection refused")
+	zoneLookups(t, nil, nil, failure)
+	if _, err := Serviceable(nil, &Point{}, ""); !errors.Is(err, failure) {
+		t.Fatalf("expected the lookup error, got %v", err)
+	}
+	postalCodeZone = func(sqlx.Queryer, string) (model.DeliveryZone, error) {
+		return model.DeliveryZone{}, failure
+	}
+	hasZones = func(sqlx.Queryer) (bool, error) { return true, nil }
+	if _, err := Serviceable(nil, nil, "560001"); !errors.Is(err, failure) {
+		t.Fatalf("expected the postal code lookup error, got %v", err)
+	}
+}
+
+func TestCheckAddress(t *testing.T) {
+	zoneLookups(t, []model.DeliveryZone{{Id: "z1", Polygon: square}}, nil, nil)
+	if err := CheckAddress(nil, model.AddressModel{Lat: 5, Long: 5}); err != nil {
+		t.Fatalf("expected the address to be served, got %v", err)
+	}
+	if err := CheckAddress(nil, model.AddressModel{Lat: 50, Long: 50}); !errors.Is(err, ErrNotServiceable) {
+		t.Fatalf("expected ErrNotServiceable, got %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strings"
+)
+
+// LoadEnvFile sets the KEY=VALUE pairs of an env file that are not set in the environment already, so the
+// real environment always wins. Blank lines and lines starting with # are skipped; a missing file is not an
+// error.
+func LoadEnvFile(path string) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !found || key == "" {
+			continue
+		}
+		if _, set := os.LookupEnv(key); set {
+			continue
+		}
+		if err := os.Setenv(key, strings.Trim(value, `"`)); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,132 @@
+package wishlist
+
+import (
+	"audio_phile/cart"
+	"audio_phile/database/dbHelper"
+	"audio_phile/model"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"github.com/jmoiron/sqlx"
+)
+
+// SavedForLater is the list cart lines go to when saved for later without naming a list
+const SavedForLater = "Saved for later"
+
+var (
+	ErrWishlistNotFound = errors.New("wishlist not found")
+	ErrItemNotFound     = errors.New("item not found")
+)
+
+// Owned checks that the list belongs to the user
+func Owned(db sqlx.Queryer, userId, wishlistId string) (model.Wishlist, error) {
+	wishlist, err := dbHelper.GetWishlist(db, userId, wishlistId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return wishlist, ErrWishlistNotFound
+	}
+	return wishlist, err
+}
+
+// Detail returns a list with its items
+func Detail(db sqlx.Queryer, wishlist model.Wishlist) (model.WishlistDetail, error) {
+	items, err := dbHelper.GetWishlistItems(db, wishlist.Id)
+	return model.WishlistDetail{Wishlist: wishlist, Items: items}, err
+}
+
+// Share gives the list a public read-only token, keeping the existing one if it is already shared
+func Share(tx *sqlx.Tx, wishlist model.Wishlist) (string, error) {
+	if wishlist.ShareToken != nil {
+		return *wishlist.ShareToken, nil
+	}
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(raw)
+	return token, dbHelper.SetWishlistShareToken(tx, wishlist.Id, &token)
+}
+
+// MoveToCart adds the saved quantity of a product to the user's active cart and takes it off the list.
+// The cart stock check applies, so nothing moves when the cart cannot take the quantity.
+func MoveToCart(tx *sqlx.Tx, userId, wishlistId, productId string) error {
+	if _, err := Owned(tx, userId, wishlistId); err != nil {
+		return err
+	}
+	quantity, err := dbHelper.GetWishlistItemQuantity(tx, wishlistId, productId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrItemNotFound
+	}
+	if err != nil {
+		return err
+	}
+	cartId, _, err := cart.ActiveCartId(tx, userId, true)
+	if err != nil {
+		return err
+	}
+	inCart, err := cartQuantity(tx, cartId, productId)
+	if err != nil {
+		return err
+	}
+	if err := cart.SetQuantity(tx, cartId, productId, inCart+quantity); err != nil {
+		return err
+	}
+	_, err = dbHelper.DeleteWishlistItem(tx, wishlistId, productId)
+	return err
+}
+
+// SaveForLater moves a cart line to a list, to the Saved for later list when wishlistId is empty. Saving a
+// product the list already holds adds the cart quantity to it.
+func SaveForLater(tx *sqlx.Tx, userId, wishlistId, productId string) (string, error) {
+	cartId, exist, err := cart.ActiveCartId(tx, userId, false)
+	if err != nil {
+		return "", err
+	}
+	quantity := 0
+	if exist {
+		if quantity, err = cartQuantity(tx, cartId, productId); err != nil {
+			return "", err
+		}
+	}
+	if quantity == 0 {
+		return "", ErrItemNotFound
+	}
+
+	if wishlistId == "" {
+		// ActiveCartId locked the user, so the list cannot be created twice concurrently
+		var found bool
+		wishlistId, found, err = dbHelper.GetWishlistIdByName(tx, userId, SavedForLater)
+		if err != nil {
+			return "", err
+		}
+		if !found {
+			if wishlistId, err = dbHelper.CreateWishlist(tx, userId, SavedForLater); err != nil {
+				return "", err
+			}
+		}
+	} else if _, err := Owned(tx, userId, wishlistId); err != nil {
+		return "", err
+	}
+
+	saved, err := dbHelper.AddWishlistItem(tx, wishlistId, productId, quantity)
+	if err != nil {
+		return "", err
+	}
+	if !saved {
+		return "", cart.ErrProductNotFound
+	}
+	return wishlistId, cart.SetQuantity(tx, cartId, productId, 0)
+}
+
+func cartQuantity(db sqlx.Queryer, cartId, productId string) (int, error) {
+	lines, err := dbHelper.GetCartLines(db, cartId)
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range lines {
+		if line.ProductId == productId {
+			return line.Quantity, nil
+		}
+	}
+	return 0, nil
+}